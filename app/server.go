@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -23,10 +24,21 @@ func main() {
 
 	fmt.Printf("Server starting as %s on port %s\n", serverConfig.Role, serverConfig.Port)
 
-	kvStore := store.New()
+	dbs := store.NewDatabases(serverConfig.Databases)
 
 	rdbFile := rdb.New(serverConfig)
-	rdbFile.Inject(kvStore)
+	rdbFile.Inject(dbs)
+
+	// replicas wait for the DELs and HDELs of their master, like redis
+	if serverConfig.Role == config.RoleMaster {
+		go dbs.ActiveExpireCycle(serverConfig.Hz, serverConfig.ActiveExpireEffort, func(db *store.Store, keys []string) {
+			command.PropagateDeletes(keys, db, serverConfig)
+		})
+
+		go dbs.ReclaimExpiredHashFields(func(db *store.Store, hashes []store.ReclaimedFields) {
+			command.PropagateReclaimedFields(hashes, db, serverConfig)
+		})
+	}
 
 	l, err := net.Listen("tcp", "0.0.0.0:"+serverConfig.Port)
 	if err != nil {
@@ -37,7 +49,7 @@ func main() {
 
 	// handle replication stuff
 	if serverConfig.Role == config.RoleSlave {
-		go replication.ConnectToMaster(serverConfig, kvStore)
+		go replication.ConnectToMaster(serverConfig, dbs)
 	} else {
 		go replication.HandleReplicaWrite(serverConfig)
 	}
@@ -49,27 +61,48 @@ func main() {
 			log.Fatal("Error accepting connection: ", err.Error())
 		}
 
-		go handleClient(conn, kvStore, serverConfig)
+		go handleClient(conn, dbs, serverConfig)
 	}
 
 }
 
-func handleClient(conn net.Conn, kvStore *store.Store, serverConfig *config.ServerConfig) {
+func handleClient(conn net.Conn, dbs *store.Databases, serverConfig *config.ServerConfig) {
 	defer conn.Close()
 
-	reader := bufio.NewReader(conn)
+	// cancelled as soon as the client disconnects, even while a blocking command is waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	for {
+	messages := make(chan parser.Message)
 
-		message, err := parser.Deserialize(reader)
+	go func() {
+		defer cancel()
+		defer close(messages)
 
-		if err != nil {
-			if !errors.Is(err, io.EOF) {
-				fmt.Println("Error parsing commands: ", err.Error())
+		reader := bufio.NewReader(conn)
+
+		for {
+			message, err := parser.Deserialize(reader)
+
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					fmt.Println("Error parsing commands: ", err.Error())
+				}
+				fmt.Println("Connection closed")
+				return
+			}
+
+			select {
+			case messages <- message:
+			case <-ctx.Done():
+				return
 			}
-			fmt.Println("Connection closed")
-			break
 		}
+	}()
+
+	client := &command.Client{}
+
+	for message := range messages {
 
 		fmt.Println("Commands: ", message.Commands)
 
@@ -77,7 +110,7 @@ func handleClient(conn net.Conn, kvStore *store.Store, serverConfig *config.Serv
 			continue
 		}
 
-		response := command.Handler(message.Commands, conn, kvStore, serverConfig)
+		response := command.Handler(ctx, message.Commands, conn, dbs, client, serverConfig)
 
 		conn.Write(response)
 
@@ -0,0 +1,362 @@
+package command
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+const (
+	SETBIT   = "SETBIT"
+	GETBIT   = "GETBIT"
+	BITCOUNT = "BITCOUNT"
+	BITPOS   = "BITPOS"
+	BITOP    = "BITOP"
+
+	BITFIELD    = "BITFIELD"
+	BITFIELD_RO = "BITFIELD_RO"
+)
+
+// bitmaps are strings, so offsets are limited to the 512MB a string may hold
+const maxBitOffset = 512*1024*1024*8 - 1
+
+// SETBIT key offset value
+func handleSetBitCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	offset, err := parseBitOffset(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if cmds[3] != "0" && cmds[3] != "1" {
+		return parser.SerializeSimpleError("ERR bit is not an integer or out of range")
+	}
+
+	previous, err := kvStore.SetBit(cmds[1], offset, cmds[3] == "1")
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(previous)
+}
+
+// GETBIT key offset
+func handleGetBitCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	offset, err := parseBitOffset(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	bit, err := kvStore.GetBit(cmds[1], offset)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(bit)
+}
+
+// BITCOUNT key [start end [BYTE | BIT]]
+func handleBitCountCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	if len(cmds) == 3 || len(cmds) > 5 {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	start, end, unitBit := 0, -1, false
+
+	if len(cmds) > 2 {
+		var err error
+		start, end, unitBit, err = parseBitRange(cmds[2], cmds[3], cmds[4:])
+
+		if err != nil {
+			return parser.SerializeSimpleError(err.Error())
+		}
+	}
+
+	count, err := kvStore.BitCount(cmds[1], start, end, unitBit)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(count)
+}
+
+// BITPOS key bit [start [end [BYTE | BIT]]]
+func handleBitPosCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	if len(cmds) > 6 {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	bit, err := strconv.Atoi(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	if bit != 0 && bit != 1 {
+		return parser.SerializeSimpleError("ERR The bit argument must be 1 or 0.")
+	}
+
+	start, end, unitBit := 0, -1, false
+	endGiven := len(cmds) > 4
+
+	switch {
+	case endGiven:
+		start, end, unitBit, err = parseBitRange(cmds[3], cmds[4], cmds[5:])
+	case len(cmds) == 4:
+		start, err = strconv.Atoi(cmds[3])
+
+		if err != nil {
+			err = errors.New(errNotInteger)
+		}
+	}
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	pos, err := kvStore.BitPos(cmds[1], bit, start, end, endGiven, unitBit)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(pos)
+}
+
+// BITOP AND | OR | XOR | NOT destkey key [key ...]
+func handleBitOpCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	op := strings.ToUpper(cmds[1])
+
+	switch op {
+	case datatypes.BitOpAnd, datatypes.BitOpOr, datatypes.BitOpXor:
+	case datatypes.BitOpNot:
+		if len(cmds) != 4 {
+			return parser.SerializeSimpleError("ERR BITOP NOT must be called with a single source key.")
+		}
+	default:
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	length, err := kvStore.BitOp(op, cmds[2], cmds[3:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(length)
+}
+
+// BITFIELD key [GET encoding offset | [OVERFLOW WRAP | SAT | FAIL] SET encoding offset value | INCRBY encoding offset increment ...]
+// BITFIELD_RO key [GET encoding offset ...]
+func handleBitfieldCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	ops, err := parseBitfieldOps(cmds[2:], strings.ToUpper(cmds[0]) == BITFIELD_RO)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	results, ok, err := kvStore.Bitfield(cmds[1], ops)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	replies := make([][]byte, len(results))
+	writes := false
+
+	for i, result := range results {
+		if ok[i] {
+			replies[i] = parser.SerializeInteger(int(result))
+		} else {
+			replies[i] = parser.SerializeNullBulkString()
+		}
+
+		if ops[i].Kind != datatypes.BitfieldGet {
+			writes = true
+		}
+	}
+
+	if writes {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeRawArray(replies)
+}
+
+func parseBitOffset(offset string) (int, error) {
+	value, err := strconv.ParseInt(offset, 10, 64)
+
+	if err != nil || value < 0 || value > maxBitOffset {
+		return 0, errors.New("ERR bit offset is not an integer or out of range")
+	}
+
+	return int(value), nil
+}
+
+// parses start end [BYTE | BIT] of BITCOUNT and BITPOS
+func parseBitRange(start, end string, unit []string) (int, int, bool, error) {
+	s, err1 := strconv.Atoi(start)
+	e, err2 := strconv.Atoi(end)
+
+	if err1 != nil || err2 != nil {
+		return 0, 0, false, errors.New(errNotInteger)
+	}
+
+	if len(unit) == 0 {
+		return s, e, false, nil
+	}
+
+	switch strings.ToUpper(unit[0]) {
+	case "BYTE":
+		return s, e, false, nil
+	case "BIT":
+		return s, e, true, nil
+	default:
+		return 0, 0, false, errors.New(errSyntax)
+	}
+}
+
+// parses the subcommands of BITFIELD, readOnly only accepts GET (and OVERFLOW) like BITFIELD_RO
+func parseBitfieldOps(args []string, readOnly bool) ([]datatypes.BitfieldOp, error) {
+	ops := []datatypes.BitfieldOp{}
+	overflow := datatypes.BitfieldWrap
+
+	for i := 0; i < len(args); i++ {
+		var op datatypes.BitfieldOp
+		var argc int
+
+		switch strings.ToUpper(args[i]) {
+		case "GET":
+			op.Kind, argc = datatypes.BitfieldGet, 2
+		case "SET":
+			op.Kind, argc = datatypes.BitfieldSet, 3
+		case "INCRBY":
+			op.Kind, argc = datatypes.BitfieldIncrBy, 3
+		case "OVERFLOW":
+			if i+1 == len(args) {
+				return nil, errors.New(errSyntax)
+			}
+
+			switch strings.ToUpper(args[i+1]) {
+			case "WRAP":
+				overflow = datatypes.BitfieldWrap
+			case "SAT":
+				overflow = datatypes.BitfieldSat
+			case "FAIL":
+				overflow = datatypes.BitfieldFail
+			default:
+				return nil, errors.New("ERR Invalid OVERFLOW type specified")
+			}
+
+			i++
+			continue
+		default:
+			return nil, errors.New(errSyntax)
+		}
+
+		if i+argc >= len(args) {
+			return nil, errors.New(errSyntax)
+		}
+
+		if readOnly && op.Kind != datatypes.BitfieldGet {
+			return nil, errors.New("ERR BITFIELD_RO only supports the GET subcommand")
+		}
+
+		var err error
+		op.Signed, op.Bits, err = parseBitfieldType(args[i+1])
+
+		if err != nil {
+			return nil, err
+		}
+
+		op.Offset, err = parseBitfieldOffset(args[i+2], op.Bits)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Kind != datatypes.BitfieldGet {
+			op.Value, err = strconv.ParseInt(args[i+3], 10, 64)
+
+			if err != nil {
+				return nil, errors.New(errNotInteger)
+			}
+		}
+
+		op.Overflow = overflow
+		ops = append(ops, op)
+		i += argc
+	}
+
+	return ops, nil
+}
+
+// parses encodings such as i5 or u8, signed integers may use up to 64 bits and unsigned ones 63
+func parseBitfieldType(encoding string) (bool, int, error) {
+	invalid := errors.New("ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+
+	if len(encoding) < 2 || (encoding[0] != 'i' && encoding[0] != 'u') {
+		return false, 0, invalid
+	}
+
+	signed := encoding[0] == 'i'
+	bits, err := strconv.Atoi(encoding[1:])
+
+	if err != nil || bits < 1 || (signed && bits > 64) || (!signed && bits > 63) {
+		return false, 0, invalid
+	}
+
+	return signed, bits, nil
+}
+
+// parses a bit offset, or with a # prefix an offset counted in fields of bits bits
+func parseBitfieldOffset(offset string, bits int) (int, error) {
+	if !strings.HasPrefix(offset, "#") {
+		return parseBitOffset(offset)
+	}
+
+	index, err := parseBitOffset(offset[1:])
+
+	if err != nil || index > maxBitOffset/bits {
+		return 0, errors.New("ERR bit offset is not an integer or out of range")
+	}
+
+	return index * bits, nil
+}
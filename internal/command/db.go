@@ -0,0 +1,133 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const (
+	SELECT   = "SELECT"
+	MOVE     = "MOVE"
+	SWAPDB   = "SWAPDB"
+	FLUSHDB  = "FLUSHDB"
+	FLUSHALL = "FLUSHALL"
+	DBSIZE   = "DBSIZE"
+)
+
+// SELECT index
+func handleSelectCommand(cmds []string, dbs *store.Databases, client *Client) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	index, err := strconv.Atoi(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	if _, err := dbs.Get(index); err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	client.DB = index
+
+	return parser.SerializeSimpleString(OK)
+}
+
+// MOVE key db
+func handleMoveCommand(cmds []string, kvStore *store.Store, dbs *store.Databases, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	index, err := strconv.Atoi(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	dst, err := dbs.Get(index)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if dst == kvStore {
+		return parser.SerializeSimpleError("ERR source and destination objects are the same")
+	}
+
+	if !dbs.Move(cmds[1], kvStore, dst) {
+		return parser.SerializeInteger(0)
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(1)
+}
+
+// SWAPDB index1 index2
+func handleSwapDBCommand(cmds []string, kvStore *store.Store, dbs *store.Databases, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	first, err := strconv.Atoi(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError("ERR invalid first DB index")
+	}
+
+	second, err := strconv.Atoi(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError("ERR invalid second DB index")
+	}
+
+	if err := dbs.Swap(first, second); err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeSimpleString(OK)
+}
+
+// FLUSHDB [ASYNC | SYNC] and FLUSHALL [ASYNC | SYNC]
+func handleFlushCommand(cmds []string, kvStore *store.Store, dbs *store.Databases, cfg *config.ServerConfig) []byte {
+	if len(cmds) > 2 {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	// the old keyspace is left to the collector either way, so both modes behave like ASYNC
+	if len(cmds) == 2 {
+		mode := strings.ToUpper(cmds[1])
+
+		if mode != "ASYNC" && mode != "SYNC" {
+			return parser.SerializeSimpleError(errSyntax)
+		}
+	}
+
+	if strings.ToUpper(cmds[0]) == FLUSHALL {
+		dbs.FlushAll()
+	} else {
+		kvStore.FlushDB()
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeSimpleString(OK)
+}
+
+// DBSIZE
+func handleDBSizeCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 1 {
+		return wrongArgsError(cmds[0])
+	}
+
+	return parser.SerializeInteger(kvStore.DBSize())
+}
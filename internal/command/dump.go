@@ -0,0 +1,150 @@
+package command
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/rdb"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const (
+	DUMP    = "DUMP"
+	RESTORE = "RESTORE"
+)
+
+// DUMP key
+func handleDumpCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	var payload []byte
+
+	_, exists := kvStore.View(cmds[1], func(value store.Data, _ time.Time) {
+		payload = rdb.Dump(value)
+	})
+
+	if !exists {
+		return parser.SerializeNullBulkString()
+	}
+
+	return parser.SerializeBulkString(string(payload))
+}
+
+// RESTORE key ttl serialized-value [REPLACE] [ABSTTL] [IDLETIME seconds] [FREQ frequency]
+func handleRestoreCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	replace, absTTL := false, false
+	idle, freq := time.Duration(-1), -1
+
+	for i := 4; i < len(cmds); i++ {
+		switch strings.ToUpper(cmds[i]) {
+		case "REPLACE":
+			replace = true
+		case "ABSTTL":
+			absTTL = true
+		case "IDLETIME":
+			// IDLETIME and FREQ set the clocks of different policies, only one makes sense
+			if i+1 >= len(cmds) || freq >= 0 {
+				return parser.SerializeSimpleError(errSyntax)
+			}
+
+			i++
+			n, err := strconv.ParseInt(cmds[i], 10, 64)
+
+			if err != nil {
+				return parser.SerializeSimpleError(errNotInteger)
+			}
+
+			if n < 0 {
+				return parser.SerializeSimpleError("ERR Invalid IDLETIME value, must be >= 0")
+			}
+
+			idle = time.Duration(n) * time.Second
+		case "FREQ":
+			if i+1 >= len(cmds) || idle >= 0 {
+				return parser.SerializeSimpleError(errSyntax)
+			}
+
+			i++
+			n, err := strconv.Atoi(cmds[i])
+
+			if err != nil {
+				return parser.SerializeSimpleError(errNotInteger)
+			}
+
+			if n < 0 || n > 255 {
+				return parser.SerializeSimpleError("ERR Invalid FREQ value, must be >= 0 and <= 255")
+			}
+
+			freq = n
+		default:
+			return parser.SerializeSimpleError(errSyntax)
+		}
+	}
+
+	ttl, err := strconv.ParseInt(cmds[2], 10, 64)
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	if ttl < 0 {
+		return parser.SerializeSimpleError("ERR Invalid TTL value, must be >= 0")
+	}
+
+	var expiry time.Time
+
+	if ttl > 0 && absTTL {
+		expiry = time.UnixMilli(ttl)
+	} else if ttl > 0 {
+		now := time.Now().UnixMilli()
+
+		if ttl > math.MaxInt64-now {
+			return parser.SerializeSimpleError("ERR invalid expire time in 'restore' command")
+		}
+
+		expiry = time.UnixMilli(now + ttl)
+	}
+
+	if !replace && kvStore.Exists([]string{cmds[1]}) > 0 {
+		return parser.SerializeSimpleError(store.ErrBusyKey.Error())
+	}
+
+	value, err := rdb.ParseDump([]byte(cmds[3]))
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	result, err := kvStore.Restore(cmds[1], value, expiry, replace, idle, freq)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	switch result {
+	case store.Restored:
+		// replicas get the absolute expiry, and whatever they hold at key is replaced
+		replicated := []string{RESTORE, cmds[1], "0", cmds[3], "REPLACE"}
+
+		if !expiry.IsZero() {
+			replicated[2] = strconv.FormatInt(expiry.UnixMilli(), 10)
+			replicated = append(replicated, "ABSTTL")
+		}
+
+		propagate(replicated, kvStore, cfg)
+	case store.RestoreDeleted:
+		propagate([]string{DEL, cmds[1]}, kvStore, cfg)
+	}
+
+	return parser.SerializeSimpleString("OK")
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDumpRestore(t *testing.T) {
+	s := newTestServer()
+
+	s.expect(t, "$3\r\n1-1\r\n", "XADD", "s", "1-1", "f", "v")
+	s.expect(t, "$3\r\n2-0\r\n", "XADD", "s", "2-0", "g", "w")
+	s.expect(t, ":2\r\n", "HSET", "h", "f", "v", "g", "w")
+	s.expect(t, "*1\r\n:1\r\n", "HEXPIRE", "h", "100", "FIELDS", "1", "f")
+
+	for _, key := range []string{"s", "h"} {
+		payload := s.run("DUMP", key)
+
+		if payload[0] != '$' {
+			t.Fatalf("DUMP %s: %q", key, payload)
+		}
+
+		// the bulk string without its length and terminator
+		payload = payload[strings.Index(payload, "\r\n")+2 : len(payload)-2]
+
+		s.expect(t, "+OK\r\n", "RESTORE", key+"-copy", "0", payload)
+	}
+
+	s.expect(t, "*2\r\n*2\r\n$3\r\n1-1\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n*2\r\n$3\r\n2-0\r\n*2\r\n$1\r\ng\r\n$1\r\nw\r\n", "XRANGE", "s-copy", "-", "+")
+	s.expect(t, "*2\r\n:100\r\n:-1\r\n", "HTTL", "h-copy", "FIELDS", "2", "f", "g")
+}
+
+func TestRestoreTTLOverflow(t *testing.T) {
+	s := newTestServer()
+
+	s.expect(t, "+OK\r\n", "SET", "k", "v")
+
+	payload := s.run("DUMP", "k")
+	payload = payload[strings.Index(payload, "\r\n")+2 : len(payload)-2]
+
+	s.expect(t, "-ERR invalid expire time in 'restore' command\r\n", "RESTORE", "r", "9223372036854775807", payload)
+	s.expect(t, ":0\r\n", "EXISTS", "r")
+}
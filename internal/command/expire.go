@@ -0,0 +1,156 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const (
+	EXPIRE      = "EXPIRE"
+	PEXPIRE     = "PEXPIRE"
+	EXPIREAT    = "EXPIREAT"
+	PEXPIREAT   = "PEXPIREAT"
+	TTL         = "TTL"
+	PTTL        = "PTTL"
+	EXPIRETIME  = "EXPIRETIME"
+	PEXPIRETIME = "PEXPIRETIME"
+	PERSIST     = "PERSIST"
+)
+
+// EXPIRE key seconds [NX | XX | GT | LT], and PEXPIRE, EXPIREAT and PEXPIREAT
+func handleExpireCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	command := strings.ToUpper(cmds[0])
+	conditions := make([]string, 0, len(cmds)-3)
+	given := map[string]bool{}
+
+	for _, arg := range cmds[3:] {
+		condition := strings.ToUpper(arg)
+
+		switch condition {
+		case store.ExpireNX, store.ExpireXX, store.ExpireGT, store.ExpireLT:
+			conditions = append(conditions, condition)
+			given[condition] = true
+		default:
+			return parser.SerializeSimpleError(fmt.Sprintf("ERR Unsupported option %s", arg))
+		}
+	}
+
+	if given[store.ExpireNX] && (given[store.ExpireXX] || given[store.ExpireGT] || given[store.ExpireLT]) {
+		return parser.SerializeSimpleError("ERR NX and XX, GT or LT options at the same time are not compatible")
+	}
+
+	if given[store.ExpireGT] && given[store.ExpireLT] {
+		return parser.SerializeSimpleError("ERR GT and LT options at the same time are not compatible")
+	}
+
+	n, err := strconv.ParseInt(cmds[2], 10, 64)
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	ms, ok := expireTimeMillis(command, n)
+
+	if !ok {
+		return parser.SerializeSimpleError(fmt.Sprintf("ERR invalid expire time in '%s' command", strings.ToLower(command)))
+	}
+
+	switch kvStore.Expire(cmds[1], time.UnixMilli(ms), conditions) {
+	case store.ExpireSet:
+		propagate([]string{PEXPIREAT, cmds[1], strconv.FormatInt(ms, 10)}, kvStore, cfg)
+	case store.ExpireDeleted:
+		propagate([]string{DEL, cmds[1]}, kvStore, cfg)
+	default:
+		return parser.SerializeInteger(0)
+	}
+
+	return parser.SerializeInteger(1)
+}
+
+// converts the argument of the EXPIRE commands to an absolute unix time in
+// milliseconds, ok is false when it overflows
+func expireTimeMillis(command string, n int64) (int64, bool) {
+	ms := n
+
+	if command == EXPIRE || command == EXPIREAT {
+		if n > math.MaxInt64/1000 || n < math.MinInt64/1000 {
+			return 0, false
+		}
+
+		ms = n * 1000
+	}
+
+	if command == EXPIRE || command == PEXPIRE {
+		now := time.Now().UnixMilli()
+
+		// negative times expire the key at once, but must not wrap around either
+		if ms > math.MaxInt64-now || ms < math.MinInt64+now {
+			return 0, false
+		}
+
+		ms += now
+	}
+
+	return ms, true
+}
+
+// TTL key, PTTL key, EXPIRETIME key and PEXPIRETIME key
+func handleTTLCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	command := strings.ToUpper(cmds[0])
+	expiry, exists := kvStore.GetExpiry(cmds[1])
+
+	if !exists {
+		return parser.SerializeInteger(-2)
+	}
+
+	if expiry.IsZero() {
+		return parser.SerializeInteger(-1)
+	}
+
+	ms := expiry.UnixMilli()
+
+	if command == TTL || command == PTTL {
+		ms -= time.Now().UnixMilli()
+
+		if ms < 0 {
+			ms = 0
+		}
+	}
+
+	if command == TTL || command == EXPIRETIME {
+		// rounded to the closest second
+		return parser.SerializeInteger(int((ms + 500) / 1000))
+	}
+
+	return parser.SerializeInteger(int(ms))
+}
+
+// PERSIST key
+func handlePersistCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	if !kvStore.Persist(cmds[1]) {
+		return parser.SerializeInteger(0)
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(1)
+}
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpireOverflow(t *testing.T) {
+	s := newTestServer()
+
+	s.expect(t, "+OK\r\n", "SET", "k", "v")
+
+	tests := [][]string{
+		{"PEXPIRE", "k", "-9223372036854775808"},
+		{"PEXPIRE", "k", "9223372036854775807"},
+		{"EXPIRE", "k", "-9223372036854775"},
+		{"EXPIRE", "k", "9223372036854775"},
+	}
+
+	for _, cmds := range tests {
+		s.expect(t, "-ERR invalid expire time in '"+strings.ToLower(cmds[0])+"' command\r\n", cmds...)
+	}
+
+	s.expect(t, ":-1\r\n", "TTL", "k")
+
+	// a negative time that does not overflow still deletes the key
+	s.expect(t, ":1\r\n", "PEXPIRE", "k", "-1000")
+	s.expect(t, ":0\r\n", "EXISTS", "k")
+}
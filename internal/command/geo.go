@@ -0,0 +1,453 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+const (
+	GEOADD         = "GEOADD"
+	GEOPOS         = "GEOPOS"
+	GEODIST        = "GEODIST"
+	GEOHASH        = "GEOHASH"
+	GEOSEARCH      = "GEOSEARCH"
+	GEOSEARCHSTORE = "GEOSEARCHSTORE"
+)
+
+// meters per unit accepted by the geo commands
+var geoUnits = map[string]float64{
+	"m":  1,
+	"km": 1000,
+	"ft": 0.3048,
+	"mi": 1609.34,
+}
+
+// GEOADD key [NX | XX] [CH] longitude latitude member [longitude latitude member ...]
+func handleGeoAddCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 5 {
+		return wrongArgsError(cmds[0])
+	}
+
+	var options datatypes.ZAddOptions
+	var ch bool
+
+	i := 2
+
+flags:
+	for ; i < len(cmds); i++ {
+		switch strings.ToUpper(cmds[i]) {
+		case "NX":
+			options.NX = true
+		case "XX":
+			options.XX = true
+		case "CH":
+			ch = true
+		default:
+			break flags
+		}
+	}
+
+	triples := cmds[i:]
+
+	if len(triples) == 0 || len(triples)%3 != 0 || (options.NX && options.XX) {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	members := make([]datatypes.ScoredMember, 0, len(triples)/3)
+
+	// replicas receive the equivalent ZADD
+	zadd := append([]string{ZADD}, cmds[1:i]...)
+
+	for j := 0; j < len(triples); j += 3 {
+		longitude, latitude, err := parseLonLat(triples[j], triples[j+1])
+
+		if err != nil {
+			return parser.SerializeSimpleError(err.Error())
+		}
+
+		score := datatypes.GeoScore(longitude, latitude)
+		members = append(members, datatypes.ScoredMember{Member: triples[j+2], Score: score})
+		zadd = append(zadd, datatypes.FormatScore(score), triples[j+2])
+	}
+
+	added, updated, err := kvStore.ZAdd(cmds[1], members, options)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(zadd, kvStore, cfg)
+
+	if ch {
+		return parser.SerializeInteger(added + updated)
+	}
+
+	return parser.SerializeInteger(added)
+}
+
+// GEOPOS key [member [member ...]]
+func handleGeoPosCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	scores, found, err := kvStore.ZMScore(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	result := make([][]byte, len(scores))
+
+	for i, score := range scores {
+		if !found[i] {
+			result[i] = parser.SerializeNullArray()
+			continue
+		}
+
+		longitude, latitude := datatypes.GeoDecode(score)
+		result[i] = parser.SerializeArray([]string{formatCoordinate(longitude), formatCoordinate(latitude)})
+	}
+
+	return parser.SerializeRawArray(result)
+}
+
+// GEODIST key member1 member2 [M | KM | FT | MI]
+func handleGeoDistCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	if len(cmds) > 5 {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	conversion := 1.0
+
+	if len(cmds) == 5 {
+		var err error
+
+		if conversion, err = parseGeoUnit(cmds[4]); err != nil {
+			return parser.SerializeSimpleError(err.Error())
+		}
+	}
+
+	scores, found, err := kvStore.ZMScore(cmds[1], cmds[2:4])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !found[0] || !found[1] {
+		return parser.SerializeNullBulkString()
+	}
+
+	lon1, lat1 := datatypes.GeoDecode(scores[0])
+	lon2, lat2 := datatypes.GeoDecode(scores[1])
+
+	return parser.SerializeBulkString(formatDistance(datatypes.GeoDistance(lon1, lat1, lon2, lat2) / conversion))
+}
+
+// GEOHASH key [member [member ...]]
+func handleGeoHashCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	scores, found, err := kvStore.ZMScore(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	result := make([][]byte, len(scores))
+
+	for i, score := range scores {
+		if !found[i] {
+			result[i] = parser.SerializeNullBulkString()
+			continue
+		}
+
+		result[i] = parser.SerializeBulkString(datatypes.GeohashString(score))
+	}
+
+	return parser.SerializeRawArray(result)
+}
+
+// GEOSEARCH key <FROMMEMBER member | FROMLONLAT longitude latitude>
+// <BYRADIUS radius unit | BYBOX width height unit> [ASC | DESC] [COUNT count [ANY]]
+// [WITHCOORD] [WITHDIST] [WITHHASH]
+func handleGeoSearchCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 7 {
+		return wrongArgsError(cmds[0])
+	}
+
+	query, with, err := parseGeoSearchArgs(cmds[0], cmds[2:], false)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	points, err := kvStore.GeoSearch(cmds[1], query)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	result := make([][]byte, len(points))
+
+	for i, point := range points {
+		if !with.coord && !with.dist && !with.hash {
+			result[i] = parser.SerializeBulkString(point.Member)
+			continue
+		}
+
+		fields := [][]byte{parser.SerializeBulkString(point.Member)}
+
+		if with.dist {
+			fields = append(fields, parser.SerializeBulkString(formatDistance(point.Distance/query.Shape.Conversion)))
+		}
+
+		if with.hash {
+			fields = append(fields, parser.SerializeInteger(int(point.Score)))
+		}
+
+		if with.coord {
+			fields = append(fields, parser.SerializeArray([]string{formatCoordinate(point.Longitude), formatCoordinate(point.Latitude)}))
+		}
+
+		result[i] = parser.SerializeRawArray(fields)
+	}
+
+	return parser.SerializeRawArray(result)
+}
+
+// GEOSEARCHSTORE destination source <FROMMEMBER member | FROMLONLAT longitude latitude>
+// <BYRADIUS radius unit | BYBOX width height unit> [ASC | DESC] [COUNT count [ANY]] [STOREDIST]
+func handleGeoSearchStoreCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 8 {
+		return wrongArgsError(cmds[0])
+	}
+
+	query, with, err := parseGeoSearchArgs(cmds[0], cmds[3:], true)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	length, err := kvStore.GeoSearchStore(cmds[1], cmds[2], query, with.storeDist)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(length)
+}
+
+// the optional replies of GEOSEARCH, and STOREDIST of GEOSEARCHSTORE
+type geoSearchWith struct {
+	coord     bool
+	dist      bool
+	hash      bool
+	storeDist bool
+}
+
+// parses the options of GEOSEARCH and GEOSEARCHSTORE following the key(s)
+func parseGeoSearchArgs(command string, args []string, storing bool) (store.GeoQuery, geoSearchWith, error) {
+	var query store.GeoQuery
+	var with geoSearchWith
+	var fromLonLat, byRadius, byBox bool
+	var err error
+
+	errSyntaxError := errors.New(errSyntax)
+
+	for i := 0; i < len(args); i++ {
+		remaining := len(args) - i - 1
+
+		switch arg := strings.ToUpper(args[i]); {
+		case arg == "WITHCOORD":
+			with.coord = true
+		case arg == "WITHDIST":
+			with.dist = true
+		case arg == "WITHHASH":
+			with.hash = true
+		case arg == "STOREDIST" && storing:
+			with.storeDist = true
+		case arg == "ANY":
+			query.Any = true
+		case arg == "ASC":
+			query.Sort = store.SortAsc
+		case arg == "DESC":
+			query.Sort = store.SortDesc
+		case arg == "COUNT" && remaining >= 1:
+			count, err := strconv.Atoi(args[i+1])
+
+			if err != nil {
+				return query, with, errors.New(errNotInteger)
+			}
+
+			if count <= 0 {
+				return query, with, errors.New("ERR COUNT must be > 0")
+			}
+
+			query.Count = count
+			i++
+		case arg == "FROMMEMBER" && remaining >= 1:
+			if query.FromMember || fromLonLat {
+				return query, with, errSyntaxError
+			}
+
+			query.FromMember = true
+			query.Member = args[i+1]
+			i++
+		case arg == "FROMLONLAT" && remaining >= 2:
+			if query.FromMember || fromLonLat {
+				return query, with, errSyntaxError
+			}
+
+			query.Shape.Longitude, query.Shape.Latitude, err = parseLonLat(args[i+1], args[i+2])
+
+			if err != nil {
+				return query, with, err
+			}
+
+			fromLonLat = true
+			i += 2
+		case arg == "BYRADIUS" && remaining >= 2:
+			if byRadius || byBox {
+				return query, with, errSyntaxError
+			}
+
+			query.Shape.Radius, err = parseGeoDistance(args[i+1], "radius")
+
+			if err == nil {
+				query.Shape.Conversion, err = parseGeoUnit(args[i+2])
+			}
+
+			if err != nil {
+				return query, with, err
+			}
+
+			byRadius = true
+			i += 2
+		case arg == "BYBOX" && remaining >= 3:
+			if byRadius || byBox {
+				return query, with, errSyntaxError
+			}
+
+			query.Shape.Width, err = parseGeoDistance(args[i+1], "width")
+
+			if err == nil {
+				query.Shape.Height, err = parseGeoDistance(args[i+2], "height")
+			}
+
+			if err == nil {
+				query.Shape.Conversion, err = parseGeoUnit(args[i+3])
+			}
+
+			if err != nil {
+				return query, with, err
+			}
+
+			query.Shape.ByBox = true
+			byBox = true
+			i += 3
+		default:
+			return query, with, errSyntaxError
+		}
+	}
+
+	if storing && (with.coord || with.dist || with.hash) {
+		return query, with, errors.New("ERR GEOSEARCHSTORE is not compatible with WITHDIST, WITHHASH and WITHCOORD options")
+	}
+
+	if !query.FromMember && !fromLonLat {
+		return query, with, fmt.Errorf("ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for %s", strings.ToLower(command))
+	}
+
+	if !byRadius && !byBox {
+		return query, with, fmt.Errorf("ERR exactly one of BYRADIUS and BYBOX can be specified for %s", strings.ToLower(command))
+	}
+
+	if query.Any && query.Count == 0 {
+		return query, with, errors.New("ERR the ANY argument requires COUNT argument")
+	}
+
+	// the closest members are returned when COUNT is given without an order
+	if query.Count > 0 && query.Sort == 0 && !query.Any {
+		query.Sort = store.SortAsc
+	}
+
+	return query, with, nil
+}
+
+func parseLonLat(lon, lat string) (float64, float64, error) {
+	longitude, err := datatypes.ParseScore(lon)
+
+	if err != nil {
+		return 0, 0, errors.New(errNotFloat)
+	}
+
+	latitude, err := datatypes.ParseScore(lat)
+
+	if err != nil {
+		return 0, 0, errors.New(errNotFloat)
+	}
+
+	if !datatypes.GeoValid(longitude, latitude) {
+		return 0, 0, fmt.Errorf("ERR invalid longitude,latitude pair %f,%f", longitude, latitude)
+	}
+
+	return longitude, latitude, nil
+}
+
+// parses a radius, width or height
+func parseGeoDistance(distance, name string) (float64, error) {
+	value, err := datatypes.ParseScore(distance)
+
+	if err != nil {
+		return 0, fmt.Errorf("ERR need numeric %s", name)
+	}
+
+	if value < 0 {
+		if name == "radius" {
+			return 0, errors.New("ERR radius cannot be negative")
+		}
+
+		return 0, errors.New("ERR height or width cannot be negative")
+	}
+
+	return value, nil
+}
+
+// returns the number of meters in unit
+func parseGeoUnit(unit string) (float64, error) {
+	conversion, ok := geoUnits[strings.ToLower(unit)]
+
+	if !ok {
+		return 0, errors.New("ERR unsupported unit provided. please use M, KM, FT, MI")
+	}
+
+	return conversion, nil
+}
+
+func formatDistance(distance float64) string {
+	return strconv.FormatFloat(distance, 'f', 4, 64)
+}
+
+// formats coordinates with 17 decimals like redis, without the trailing zeros
+func formatCoordinate(coordinate float64) string {
+	formatted := strconv.FormatFloat(coordinate, 'f', 17, 64)
+	formatted = strings.TrimRight(formatted, "0")
+
+	return strings.TrimSuffix(formatted, ".")
+}
@@ -37,17 +37,100 @@ const (
 	XADD       = "XADD"
 	XRANGE     = "XRANGE"
 	XREAD      = "XREAD"
+	SAVE       = "SAVE"
 )
 
-func Handler(cmds []string, conn net.Conn, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+const (
+	errSyntax     = "ERR syntax error"
+	errNotInteger = "ERR value is not an integer or out of range"
+	errNotFloat   = "ERR value is not a valid float"
+)
+
+// the state of a connection
+type Client struct {
+	DB int // the database selected with SELECT
+}
+
+// ctx is cancelled once the client goes away, so blocking commands can stop waiting
+func Handler(ctx context.Context, cmds []string, conn net.Conn, dbs *store.Databases, client *Client, cfg *config.ServerConfig) []byte {
 
 	var response []byte
 
+	kvStore, err := dbs.Get(client.DB)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if err := performEvictions(strings.ToUpper(cmds[0]), dbs, cfg); err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
 	switch strings.ToUpper(cmds[0]) {
 	case GET:
 		response = handleGetCommand(cmds, kvStore)
 	case SET:
 		response = handleSetCommand(cmds, kvStore, cfg)
+	case GETEX:
+		response = handleGetExCommand(cmds, kvStore, cfg)
+	case GETDEL:
+		response = handleGetDelCommand(cmds, kvStore, cfg)
+	case GETSET:
+		response = handleGetSetCommand(cmds, kvStore, cfg)
+	case SETNX:
+		response = handleSetNXCommand(cmds, kvStore, cfg)
+	case SETEX, PSETEX:
+		response = handleSetExCommand(cmds, kvStore, cfg)
+	case MSET, MSETNX:
+		response = handleMSetCommand(cmds, kvStore, cfg)
+	case MGET:
+		response = handleMGetCommand(cmds, kvStore)
+	case APPEND:
+		response = handleAppendCommand(cmds, kvStore, cfg)
+	case STRLEN:
+		response = handleStrLenCommand(cmds, kvStore)
+	case GETRANGE, SUBSTR:
+		response = handleGetRangeCommand(cmds, kvStore)
+	case SETRANGE:
+		response = handleSetRangeCommand(cmds, kvStore, cfg)
+	case LCS:
+		response = handleLCSCommand(cmds, kvStore)
+	case SETBIT:
+		response = handleSetBitCommand(cmds, kvStore, cfg)
+	case GETBIT:
+		response = handleGetBitCommand(cmds, kvStore)
+	case BITCOUNT:
+		response = handleBitCountCommand(cmds, kvStore)
+	case BITPOS:
+		response = handleBitPosCommand(cmds, kvStore)
+	case BITOP:
+		response = handleBitOpCommand(cmds, kvStore, cfg)
+	case BITFIELD, BITFIELD_RO:
+		response = handleBitfieldCommand(cmds, kvStore, cfg)
+	case PFADD:
+		response = handlePFAddCommand(cmds, kvStore, cfg)
+	case PFCOUNT:
+		response = handlePFCountCommand(cmds, kvStore)
+	case PFMERGE:
+		response = handlePFMergeCommand(cmds, kvStore, cfg)
+	case PFDEBUG:
+		response = handlePFDebugCommand(cmds, kvStore, cfg)
+	case GEOADD:
+		response = handleGeoAddCommand(cmds, kvStore, cfg)
+	case GEOPOS:
+		response = handleGeoPosCommand(cmds, kvStore)
+	case GEODIST:
+		response = handleGeoDistCommand(cmds, kvStore)
+	case GEOHASH:
+		response = handleGeoHashCommand(cmds, kvStore)
+	case GEOSEARCH:
+		response = handleGeoSearchCommand(cmds, kvStore)
+	case GEOSEARCHSTORE:
+		response = handleGeoSearchStoreCommand(cmds, kvStore, cfg)
+	case INCR, DECR, INCRBY, DECRBY:
+		response = handleIncrCommand(cmds, kvStore, cfg)
+	case INCRBYFLOAT:
+		response = handleIncrByFloatCommand(cmds, kvStore, cfg)
 	case PING:
 		response = parser.SerializeSimpleString("PONG")
 	case ECHO:
@@ -57,7 +140,7 @@ func Handler(cmds []string, conn net.Conn, kvStore *store.Store, cfg *config.Ser
 			response = parser.SerializeBulkString(cmds[1])
 		}
 	case INFO:
-		response = handleInfoCommand(cfg)
+		response = handleInfoCommand(cmds, dbs, cfg)
 	case REPLCONF:
 		response = handleRelpConfCommand(cmds, conn, cfg)
 	case PSYNC:
@@ -70,12 +153,170 @@ func Handler(cmds []string, conn net.Conn, kvStore *store.Store, cfg *config.Ser
 		response = handleXRangeCommand(cmds, kvStore)
 	case XREAD:
 		response = handleXReadCommand(cmds, kvStore)
+	case DEL, UNLINK:
+		response = handleDelCommand(cmds, kvStore, cfg)
+	case EXISTS:
+		response = handleExistsCommand(cmds, kvStore)
+	case RENAME, RENAMENX:
+		response = handleRenameCommand(cmds, kvStore, cfg)
+	case COPY:
+		response = handleCopyCommand(cmds, kvStore, dbs, cfg)
+	case TOUCH:
+		response = handleTouchCommand(cmds, kvStore)
+	case RANDOMKEY:
+		response = handleRandomKeyCommand(cmds, kvStore)
+	case EXPIRE, PEXPIRE, EXPIREAT, PEXPIREAT:
+		response = handleExpireCommand(cmds, kvStore, cfg)
+	case TTL, PTTL, EXPIRETIME, PEXPIRETIME:
+		response = handleTTLCommand(cmds, kvStore)
+	case PERSIST:
+		response = handlePersistCommand(cmds, kvStore, cfg)
 	case KEYS:
 		response = handleKeysCommand(cmds, kvStore)
+	case MEMORY:
+		response = handleMemoryCommand(cmds, kvStore, dbs)
+	case OBJECT:
+		response = handleObjectCommand(cmds, kvStore, cfg)
+	case DUMP:
+		response = handleDumpCommand(cmds, kvStore)
+	case RESTORE:
+		response = handleRestoreCommand(cmds, kvStore, cfg)
+	case MIGRATE:
+		response = handleMigrateCommand(cmds, kvStore, cfg)
+	case SAVE:
+		response = handleSaveCommand(cmds, dbs, cfg)
+	case SELECT:
+		response = handleSelectCommand(cmds, dbs, client)
+	case MOVE:
+		response = handleMoveCommand(cmds, kvStore, dbs, cfg)
+	case SWAPDB:
+		response = handleSwapDBCommand(cmds, kvStore, dbs, cfg)
+	case FLUSHDB, FLUSHALL:
+		response = handleFlushCommand(cmds, kvStore, dbs, cfg)
+	case DBSIZE:
+		response = handleDBSizeCommand(cmds, kvStore)
+	case SCAN:
+		response = handleScanCommand(cmds, kvStore)
 	case TYPE:
 		response = handleTypeCommand(cmds, kvStore)
 	case CONFIG:
 		response = handleConfigCommand(cmds, cfg)
+	case LPUSH, RPUSH, LPUSHX, RPUSHX:
+		response = handlePushCommand(cmds, kvStore, cfg)
+	case LPOP, RPOP:
+		response = handlePopCommand(cmds, kvStore, cfg)
+	case LRANGE:
+		response = handleLRangeCommand(cmds, kvStore)
+	case LLEN:
+		response = handleLLenCommand(cmds, kvStore)
+	case LINDEX:
+		response = handleLIndexCommand(cmds, kvStore)
+	case LSET:
+		response = handleLSetCommand(cmds, kvStore, cfg)
+	case LINSERT:
+		response = handleLInsertCommand(cmds, kvStore, cfg)
+	case LREM:
+		response = handleLRemCommand(cmds, kvStore, cfg)
+	case LTRIM:
+		response = handleLTrimCommand(cmds, kvStore, cfg)
+	case LPOS:
+		response = handleLPosCommand(cmds, kvStore)
+	case LMOVE:
+		response = handleLMoveCommand(cmds, kvStore, cfg)
+	case LMPOP, BLMPOP:
+		response = handleMPopCommand(ctx, cmds, kvStore, cfg)
+	case BLPOP, BRPOP:
+		response = handleBPopCommand(ctx, cmds, kvStore, cfg)
+	case BLMOVE:
+		response = handleBLMoveCommand(ctx, cmds, kvStore, cfg)
+	case HSET:
+		response = handleHSetCommand(cmds, kvStore, cfg)
+	case HSETNX:
+		response = handleHSetNXCommand(cmds, kvStore, cfg)
+	case HGET:
+		response = handleHGetCommand(cmds, kvStore)
+	case HMGET:
+		response = handleHMGetCommand(cmds, kvStore)
+	case HDEL:
+		response = handleHDelCommand(cmds, kvStore, cfg)
+	case HEXISTS:
+		response = handleHExistsCommand(cmds, kvStore)
+	case HLEN:
+		response = handleHLenCommand(cmds, kvStore)
+	case HKEYS, HVALS, HGETALL:
+		response = handleHGetAllCommand(cmds, kvStore)
+	case HINCRBY:
+		response = handleHIncrByCommand(cmds, kvStore, cfg)
+	case HINCRBYFLOAT:
+		response = handleHIncrByFloatCommand(cmds, kvStore, cfg)
+	case HSTRLEN:
+		response = handleHStrLenCommand(cmds, kvStore)
+	case HRANDFIELD:
+		response = handleHRandFieldCommand(cmds, kvStore)
+	case HSCAN:
+		response = handleHScanCommand(cmds, kvStore)
+	case HEXPIRE, HPEXPIRE, HEXPIREAT, HPEXPIREAT:
+		response = handleHExpireCommand(cmds, kvStore, cfg)
+	case HTTL, HPTTL:
+		response = handleHTTLCommand(cmds, kvStore)
+	case HPERSIST:
+		response = handleHPersistCommand(cmds, kvStore, cfg)
+	case SADD:
+		response = handleSAddCommand(cmds, kvStore, cfg)
+	case SREM:
+		response = handleSRemCommand(cmds, kvStore, cfg)
+	case SISMEMBER, SMISMEMBER:
+		response = handleSIsMemberCommand(cmds, kvStore)
+	case SMEMBERS:
+		response = handleSMembersCommand(cmds, kvStore)
+	case SCARD:
+		response = handleSCardCommand(cmds, kvStore)
+	case SPOP:
+		response = handleSPopCommand(cmds, kvStore, cfg)
+	case SRANDMEMBER:
+		response = handleSRandMemberCommand(cmds, kvStore)
+	case SMOVE:
+		response = handleSMoveCommand(cmds, kvStore, cfg)
+	case SINTER, SUNION, SDIFF:
+		response = handleSetOperationCommand(cmds, kvStore)
+	case SINTERSTORE, SUNIONSTORE, SDIFFSTORE:
+		response = handleSetOperationStoreCommand(cmds, kvStore, cfg)
+	case SINTERCARD:
+		response = handleSInterCardCommand(cmds, kvStore)
+	case SSCAN:
+		response = handleSScanCommand(cmds, kvStore)
+	case ZADD:
+		response = handleZAddCommand(cmds, kvStore, cfg)
+	case ZREM:
+		response = handleZRemCommand(cmds, kvStore, cfg)
+	case ZSCORE, ZMSCORE:
+		response = handleZScoreCommand(cmds, kvStore)
+	case ZINCRBY:
+		response = handleZIncrByCommand(cmds, kvStore, cfg)
+	case ZCARD:
+		response = handleZCardCommand(cmds, kvStore)
+	case ZCOUNT:
+		response = handleZCountCommand(cmds, kvStore)
+	case ZRANK, ZREVRANK:
+		response = handleZRankCommand(cmds, kvStore)
+	case ZRANGE:
+		response = handleZRangeCommand(cmds, kvStore)
+	case ZRANGESTORE:
+		response = handleZRangeStoreCommand(cmds, kvStore, cfg)
+	case ZPOPMIN, ZPOPMAX:
+		response = handleZPopCommand(cmds, kvStore, cfg)
+	case ZSCAN:
+		response = handleZScanCommand(cmds, kvStore)
+	case ZUNION, ZINTER, ZDIFF:
+		response = handleZSetOperationCommand(cmds, kvStore)
+	case ZUNIONSTORE, ZINTERSTORE, ZDIFFSTORE:
+		response = handleZSetOperationStoreCommand(cmds, kvStore, cfg)
+	case ZINTERCARD:
+		response = handleZInterCardCommand(cmds, kvStore)
+	case ZMPOP, BZMPOP:
+		response = handleZMPopCommand(ctx, cmds, kvStore, cfg)
+	case BZPOPMIN, BZPOPMAX:
+		response = handleBZPopCommand(ctx, cmds, kvStore, cfg)
 	default:
 		response = parser.SerializeSimpleError(fmt.Sprintf("ERR unknown command '%s'", cmds[0]))
 	}
@@ -83,46 +324,58 @@ func Handler(cmds []string, conn net.Conn, kvStore *store.Store, cfg *config.Ser
 	return response
 }
 
-func handleGetCommand(cmds []string, kvStore *store.Store) []byte {
-	if len(cmds) != 2 {
-		return parser.SerializeSimpleError("ERR wrong number of arguments for 'get' command")
+// forwards a write command run against db to the connected replicas, selecting
+// db first when the previous command propagated ran against another database
+func propagate(cmds []string, db *store.Store, cfg *config.ServerConfig) {
+	if cfg.Role != config.RoleMaster {
+		return
 	}
 
-	value := kvStore.Get(cmds[1])
-	return parser.SerializeBulkString(value)
+	cfg.Lock()
+	defer cfg.Unlock()
 
+	if cfg.ReplicationDB != db.Index() {
+		cfg.ReplicaWriteQueue <- []string{SELECT, strconv.Itoa(db.Index())}
+		cfg.ReplicationDB = db.Index()
+	}
+
+	cfg.ReplicaWriteQueue <- cmds
 }
 
-func handleSetCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
-	if len(cmds) != 3 && len(cmds) != 5 {
-		fmt.Println("Wrong args set", cmds)
-		return parser.SerializeSimpleError("ERR wrong number of arguments for 'set' command")
+// replicates keys deleted by the server itself, like the expired ones, as a DEL each
+func PropagateDeletes(keys []string, db *store.Store, cfg *config.ServerConfig) {
+	for _, key := range keys {
+		propagate([]string{DEL, key}, db, cfg)
 	}
+}
 
-	var expiry time.Time
-
-	if len(cmds) == 5 {
-		if strings.ToUpper(cmds[3]) != PX {
-			return parser.SerializeSimpleError("ERR syntax error")
+// replicates the hash fields the server reclaimed as they expired, as a HDEL
+// each, or as a DEL when they were the last ones of their hash
+func PropagateReclaimedFields(hashes []store.ReclaimedFields, db *store.Store, cfg *config.ServerConfig) {
+	for _, hash := range hashes {
+		if hash.Deleted {
+			propagate([]string{DEL, hash.Key}, db, cfg)
+			continue
 		}
 
-		expiresIn, err := time.ParseDuration(cmds[4] + "ms")
-
-		if err != nil {
-			return parser.SerializeSimpleError("ERR invalid expire time in set")
+		for _, field := range hash.Fields {
+			propagate([]string{HDEL, hash.Key, field}, db, cfg)
 		}
-
-		expiry = time.Now().Add(expiresIn)
 	}
+}
 
-	kvStore.Set(cmds[1], cmds[2], expiry)
+func serializeIntegers(values []int) []byte {
+	result := make([][]byte, len(values))
 
-	if cfg.Role == config.RoleMaster {
-		cfg.ReplicaWriteQueue <- cmds
+	for i, value := range values {
+		result[i] = parser.SerializeInteger(value)
 	}
 
-	return parser.SerializeSimpleString("OK")
+	return parser.SerializeRawArray(result)
+}
 
+func wrongArgsError(command string) []byte {
+	return parser.SerializeSimpleError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(command)))
 }
 
 func handleWaitCommand(cmds []string, cfg *config.ServerConfig) []byte {
@@ -350,15 +603,13 @@ func handleXReadCommand(cmds []string, kvStore *store.Store) []byte {
 	}
 
 	if len(result) == 0 {
-		return parser.SerializeBulkString("")
+		return parser.SerializeNullBulkString()
 	}
 
 	return []byte(fmt.Sprintf("*%d\r\n%s", len(result), strings.Join(result, "")))
 }
 
 func handleKeysCommand(cmds []string, kvStore *store.Store) []byte {
-	fmt.Println("keys command", cmds)
-
 	if len(cmds) != 2 {
 		return parser.SerializeSimpleError("Err wrong number of arguments for 'keys' command")
 	}
@@ -432,30 +683,93 @@ func handleRelpConfCommand(cmds []string, conn net.Conn, cfg *config.ServerConfi
 	return response
 }
 
+// CONFIG GET parameter [parameter ...] and CONFIG SET parameter value [parameter value ...]
 func handleConfigCommand(cmds []string, cfg *config.ServerConfig) []byte {
-	if len(cmds) != 3 {
+	if len(cmds) < 3 {
 		return parser.SerializeSimpleError("ERR wrong number of arguments for 'config' command")
 	}
 
-	if strings.ToUpper(cmds[1]) != "GET" {
+	switch strings.ToUpper(cmds[1]) {
+	case "GET":
+		pairs := []string{}
+
+		for _, pattern := range cmds[2:] {
+			pairs = append(pairs, cfg.GetParameters(pattern)...)
+		}
+
+		return parser.SerializeArray(pairs)
+	case "SET":
+		if len(cmds)%2 != 0 {
+			return parser.SerializeSimpleError("ERR wrong number of arguments for 'config|set' command")
+		}
+
+		for i := 2; i < len(cmds); i += 2 {
+			if err := cfg.SetParameter(cmds[i], cmds[i+1]); err != nil {
+				return parser.SerializeSimpleError(err.Error())
+			}
+		}
+
+		return parser.SerializeSimpleString(OK)
+	default:
 		return parser.SerializeSimpleError("ERR unsupported subcommand for 'config' command")
 	}
+}
+
+// SAVE
+func handleSaveCommand(cmds []string, dbs *store.Databases, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 1 {
+		return wrongArgsError(cmds[0])
+	}
 
-	switch strings.ToUpper(cmds[2]) {
-	case "DIR":
-		return parser.SerializeArray([]string{"dir", cfg.RDBDir})
-	case "DBFILENAME":
-		return parser.SerializeArray([]string{"dbfilename", cfg.RDBFileName})
-	default:
-		return parser.SerializeSimpleError("ERR unsupported CONFIG parameter")
+	if err := rdb.Save(cfg, dbs); err != nil {
+		return parser.SerializeSimpleError("ERR " + err.Error())
 	}
+
+	return parser.SerializeSimpleString(OK)
 }
 
-func handleInfoCommand(cfg *config.ServerConfig) []byte {
+// INFO [section ...], every section when none is given
+func handleInfoCommand(cmds []string, dbs *store.Databases, cfg *config.ServerConfig) []byte {
+	sections := map[string]bool{}
+
+	for _, section := range cmds[1:] {
+		sections[strings.ToLower(section)] = true
+	}
+
+	all := len(sections) == 0 || sections["all"] || sections["everything"] || sections["default"]
+
 	sb := strings.Builder{}
-	sb.WriteString("# Replication \n")
-	sb.WriteString("role:" + cfg.Role + "\n")
-	sb.WriteString("master_replid:" + cfg.MasterReplid + "\n")
-	sb.WriteString(fmt.Sprintf("master_repl_offset:%d", cfg.MasterReplOffset) + "\n")
+
+	if all || sections["replication"] {
+		sb.WriteString("# Replication \n")
+		sb.WriteString("role:" + cfg.Role + "\n")
+		sb.WriteString("master_replid:" + cfg.MasterReplid + "\n")
+		sb.WriteString(fmt.Sprintf("master_repl_offset:%d", cfg.MasterReplOffset) + "\n")
+	}
+
+	if all || sections["memory"] {
+		report := newMemoryReport(dbs)
+		maxMemory, policy, _ := cfg.MaxMemorySettings()
+
+		sb.WriteString("# Memory\n")
+		sb.WriteString(fmt.Sprintf("used_memory:%d\n", report.used))
+		sb.WriteString("used_memory_human:" + bytesToHuman(report.used) + "\n")
+		sb.WriteString(fmt.Sprintf("used_memory_rss:%d\n", report.runtime.Sys))
+		sb.WriteString("used_memory_rss_human:" + bytesToHuman(int64(report.runtime.Sys)) + "\n")
+		sb.WriteString(fmt.Sprintf("used_memory_peak:%d\n", report.peak))
+		sb.WriteString("used_memory_peak_human:" + bytesToHuman(report.peak) + "\n")
+		sb.WriteString(fmt.Sprintf("used_memory_overhead:%d\n", report.overhead))
+		sb.WriteString(fmt.Sprintf("used_memory_dataset:%d\n", report.used-report.overhead))
+		sb.WriteString(fmt.Sprintf("maxmemory:%d\n", maxMemory))
+		sb.WriteString("maxmemory_human:" + bytesToHuman(maxMemory) + "\n")
+		sb.WriteString("maxmemory_policy:" + policy + "\n")
+		sb.WriteString(fmt.Sprintf("mem_fragmentation_ratio:%.2f\n", report.fragmentation()))
+	}
+
+	if all || sections["stats"] {
+		sb.WriteString("# Stats\n")
+		sb.WriteString(fmt.Sprintf("evicted_keys:%d\n", dbs.EvictedKeys()))
+	}
+
 	return parser.SerializeBulkString(sb.String())
 }
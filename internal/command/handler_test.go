@@ -0,0 +1,48 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// a master with no replicas, what the commands need to run in tests
+type testServer struct {
+	dbs    *store.Databases
+	client *Client
+	cfg    *config.ServerConfig
+}
+
+// the encoding thresholds are global, so every server starts from the defaults
+func newTestServer() *testServer {
+	datatypes.SetEncodingThresholds(datatypes.DefaultEncodingThresholds)
+
+	return &testServer{
+		dbs:    store.NewDatabases(16),
+		client: &Client{},
+		cfg: &config.ServerConfig{
+			Role:               config.RoleMaster,
+			ReplicaWriteQueue:  make(chan []string, 1024),
+			ReplicationDB:      -1,
+			Databases:          16,
+			EncodingThresholds: datatypes.DefaultEncodingThresholds,
+		},
+	}
+}
+
+// runs the command and returns its reply
+func (s *testServer) run(cmds ...string) string {
+	return string(Handler(context.Background(), cmds, nil, s.dbs, s.client, s.cfg))
+}
+
+// runs the command and fails the test when its reply is not want
+func (s *testServer) expect(t *testing.T, want string, cmds ...string) {
+	t.Helper()
+
+	if got := s.run(cmds...); got != want {
+		t.Errorf("%q: got %q, want %q", cmds, got, want)
+	}
+}
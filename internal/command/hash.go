@@ -0,0 +1,477 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const (
+	HSET         = "HSET"
+	HGET         = "HGET"
+	HMGET        = "HMGET"
+	HDEL         = "HDEL"
+	HEXISTS      = "HEXISTS"
+	HLEN         = "HLEN"
+	HKEYS        = "HKEYS"
+	HVALS        = "HVALS"
+	HGETALL      = "HGETALL"
+	HINCRBY      = "HINCRBY"
+	HINCRBYFLOAT = "HINCRBYFLOAT"
+	HSETNX       = "HSETNX"
+	HSTRLEN      = "HSTRLEN"
+	HRANDFIELD   = "HRANDFIELD"
+	HSCAN        = "HSCAN"
+	HEXPIRE      = "HEXPIRE"
+	HPEXPIRE     = "HPEXPIRE"
+	HEXPIREAT    = "HEXPIREAT"
+	HPEXPIREAT   = "HPEXPIREAT"
+	HTTL         = "HTTL"
+	HPTTL        = "HPTTL"
+	HPERSIST     = "HPERSIST"
+)
+
+func handleHSetCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 4 || len(cmds)%2 != 0 {
+		return wrongArgsError(cmds[0])
+	}
+
+	added, err := kvStore.HSet(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(added)
+}
+
+func handleHSetNXCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	set, err := kvStore.HSetNX(cmds[1], cmds[2], cmds[3])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !set {
+		return parser.SerializeInteger(0)
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(1)
+}
+
+func handleHGetCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	value, ok, err := kvStore.HGet(cmds[1], cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	return parser.SerializeBulkString(value)
+}
+
+func handleHMGetCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	values, found, err := kvStore.HMGet(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	result := make([][]byte, len(values))
+
+	for i, value := range values {
+		if found[i] {
+			result[i] = parser.SerializeBulkString(value)
+		} else {
+			result[i] = parser.SerializeNullBulkString()
+		}
+	}
+
+	return parser.SerializeRawArray(result)
+}
+
+func handleHDelCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	deleted, err := kvStore.HDel(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if deleted > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeInteger(deleted)
+}
+
+func handleHExistsCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	_, ok, err := kvStore.HGet(cmds[1], cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !ok {
+		return parser.SerializeInteger(0)
+	}
+
+	return parser.SerializeInteger(1)
+}
+
+func handleHLenCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	length, err := kvStore.HLen(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+// HKEYS, HVALS and HGETALL only differ in what they return for each field
+func handleHGetAllCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	var values []string
+	var err error
+
+	switch strings.ToUpper(cmds[0]) {
+	case HKEYS:
+		values, err = kvStore.HKeys(cmds[1])
+	case HVALS:
+		values, err = kvStore.HVals(cmds[1])
+	default:
+		values, err = kvStore.HGetAll(cmds[1])
+	}
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeArray(values)
+}
+
+func handleHIncrByCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	increment, err := strconv.ParseInt(cmds[3], 10, 64)
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	value, err := kvStore.HIncrBy(cmds[1], cmds[2], increment)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(int(value))
+}
+
+func handleHIncrByFloatCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	increment, err := strconv.ParseFloat(cmds[3], 64)
+
+	if err != nil || math.IsNaN(increment) || math.IsInf(increment, 0) {
+		return parser.SerializeSimpleError(errNotFloat)
+	}
+
+	value, err := kvStore.HIncrByFloat(cmds[1], cmds[2], increment)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	// float formatting may differ between instances, so replicas get the final value
+	propagate([]string{HSET, cmds[1], cmds[2], value}, kvStore, cfg)
+
+	return parser.SerializeBulkString(value)
+}
+
+func handleHStrLenCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	length, err := kvStore.HStrLen(cmds[1], cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+// HRANDFIELD key [count [WITHVALUES]]
+func handleHRandFieldCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 || len(cmds) > 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	count := 1
+	withValues := false
+
+	if len(cmds) >= 3 {
+		var err error
+		count, err = strconv.Atoi(cmds[2])
+
+		if err != nil {
+			return parser.SerializeSimpleError(errNotInteger)
+		}
+
+		// like redis, so the fields and values replied can be counted
+		if count < -math.MaxInt64/2 {
+			return parser.SerializeSimpleError("ERR value is out of range")
+		}
+	}
+
+	if len(cmds) == 4 {
+		if strings.ToUpper(cmds[3]) != "WITHVALUES" {
+			return parser.SerializeSimpleError(errSyntax)
+		}
+
+		withValues = true
+	}
+
+	values, err := kvStore.HRandField(cmds[1], count, withValues)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	// without count a single field is returned
+	if len(cmds) == 2 {
+		if len(values) == 0 {
+			return parser.SerializeNullBulkString()
+		}
+
+		return parser.SerializeBulkString(values[0])
+	}
+
+	return parser.SerializeArray(values)
+}
+
+// HSCAN key cursor [MATCH pattern] [COUNT count]
+func handleHScanCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	cursor, pattern, count, err := parseScanArgs(cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	next, values, err := kvStore.HScan(cmds[1], cursor, count, pattern)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return serializeScanReply(next, values)
+}
+
+// HEXPIRE key seconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+// HPEXPIRE, HEXPIREAT and HPEXPIREAT only differ in how the time is given
+func handleHExpireCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 6 {
+		return wrongArgsError(cmds[0])
+	}
+
+	name := strings.ToUpper(cmds[0])
+	timeArg, err := strconv.ParseInt(cmds[2], 10, 64)
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	invalidTime := parser.SerializeSimpleError(fmt.Sprintf("ERR invalid expire time in '%s' command", strings.ToLower(name)))
+
+	if timeArg < 0 {
+		return invalidTime
+	}
+
+	var expiry time.Time
+
+	switch name {
+	case HEXPIRE:
+		if timeArg > math.MaxInt64/int64(time.Second) {
+			return invalidTime
+		}
+		expiry = time.Now().Add(time.Duration(timeArg) * time.Second)
+	case HPEXPIRE:
+		if timeArg > math.MaxInt64/int64(time.Millisecond) {
+			return invalidTime
+		}
+		expiry = time.Now().Add(time.Duration(timeArg) * time.Millisecond)
+	case HEXPIREAT:
+		expiry = time.Unix(timeArg, 0)
+	default:
+		expiry = time.UnixMilli(timeArg)
+	}
+
+	args := cmds[3:]
+	condition := store.ExpireAlways
+
+	switch strings.ToUpper(args[0]) {
+	case store.ExpireNX, store.ExpireXX, store.ExpireGT, store.ExpireLT:
+		condition = strings.ToUpper(args[0])
+		args = args[1:]
+	}
+
+	fields, err := parseHashFields(args)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	codes, err := kvStore.HExpire(cmds[1], fields, expiry, condition)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	// relative times would drift on replicas, so they get the absolute expiry of the updated fields
+	updated := []string{}
+
+	for i, code := range codes {
+		if code == store.HashFieldExpirySet || code == store.HashFieldDeleted {
+			updated = append(updated, fields[i])
+		}
+	}
+
+	if len(updated) > 0 {
+		replicated := []string{HPEXPIREAT, cmds[1], strconv.FormatInt(expiry.UnixMilli(), 10), "FIELDS", strconv.Itoa(len(updated))}
+		propagate(append(replicated, updated...), kvStore, cfg)
+	}
+
+	return serializeIntegers(codes)
+}
+
+// HTTL key FIELDS numfields field [field ...]
+func handleHTTLCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 5 {
+		return wrongArgsError(cmds[0])
+	}
+
+	fields, err := parseHashFields(cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	ttls, err := kvStore.HTTL(cmds[1], fields)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	inSeconds := strings.ToUpper(cmds[0]) == HTTL
+	result := make([]int, len(ttls))
+
+	for i, ttl := range ttls {
+		if ttl >= 0 && inSeconds {
+			ttl = (ttl + 500) / 1000
+		}
+
+		result[i] = int(ttl)
+	}
+
+	return serializeIntegers(result)
+}
+
+// HPERSIST key FIELDS numfields field [field ...]
+func handleHPersistCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 5 {
+		return wrongArgsError(cmds[0])
+	}
+
+	fields, err := parseHashFields(cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	codes, err := kvStore.HPersist(cmds[1], fields)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	for _, code := range codes {
+		if code == store.HashFieldPersisted {
+			propagate(cmds, kvStore, cfg)
+			break
+		}
+	}
+
+	return serializeIntegers(codes)
+}
+
+// parses FIELDS numfields field [field ...]
+func parseHashFields(args []string) ([]string, error) {
+	if len(args) < 2 || strings.ToUpper(args[0]) != "FIELDS" {
+		return nil, errors.New("ERR Mandatory argument FIELDS is missing or not at the right position")
+	}
+
+	numFields, err := strconv.Atoi(args[1])
+
+	if err != nil || numFields <= 0 {
+		return nil, errors.New("ERR Parameter `numFields` should be greater than 0")
+	}
+
+	if numFields != len(args)-2 {
+		return nil, errors.New("ERR The `numfields` parameter must match the number of arguments")
+	}
+
+	return args[2:], nil
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+func TestHRandFieldCountRange(t *testing.T) {
+	s := newTestServer()
+
+	s.expect(t, ":1\r\n", "HSET", "h", "f", "v")
+	s.expect(t, "*2\r\n$1\r\nf\r\n$1\r\nf\r\n", "HRANDFIELD", "h", "-2")
+	s.expect(t, "*4\r\n$1\r\nf\r\n$1\r\nv\r\n$1\r\nf\r\n$1\r\nv\r\n", "HRANDFIELD", "h", "-2", "WITHVALUES")
+
+	for _, count := range []string{"-9223372036854775808", "-4611686018427387904"} {
+		s.expect(t, "-ERR value is out of range\r\n", "HRANDFIELD", "h", count)
+		s.expect(t, "-ERR value is out of range\r\n", "HRANDFIELD", "h", count, "WITHVALUES")
+	}
+}
+
+func TestPropagateReclaimedFields(t *testing.T) {
+	s := newTestServer()
+
+	s.expect(t, ":2\r\n", "HSET", "h", "a", "1", "b", "2")
+	s.expect(t, "*1\r\n:1\r\n", "HPEXPIRE", "h", "1", "FIELDS", "1", "a")
+	s.expect(t, ":1\r\n", "HSET", "last", "c", "3")
+	s.expect(t, "*1\r\n:1\r\n", "HPEXPIRE", "last", "1", "FIELDS", "1", "c")
+
+	for len(s.cfg.ReplicaWriteQueue) > 0 {
+		<-s.cfg.ReplicaWriteQueue
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	go s.dbs.ReclaimExpiredHashFields(func(db *store.Store, hashes []store.ReclaimedFields) {
+		PropagateReclaimedFields(hashes, db, s.cfg)
+	})
+
+	propagated := map[string]bool{}
+
+	for len(propagated) < 2 {
+		select {
+		case cmds := <-s.cfg.ReplicaWriteQueue:
+			propagated[strings.Join(cmds, " ")] = true
+		case <-time.After(time.Second):
+			t.Fatalf("got %v propagated, want two commands", propagated)
+		}
+	}
+
+	if want := map[string]bool{"HDEL h a": true, "DEL last": true}; !reflect.DeepEqual(propagated, want) {
+		t.Errorf("got %v propagated, want %v", propagated, want)
+	}
+}
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+const (
+	PFADD   = "PFADD"
+	PFCOUNT = "PFCOUNT"
+	PFMERGE = "PFMERGE"
+	PFDEBUG = "PFDEBUG"
+
+	GETREG   = "GETREG"
+	DECODE   = "DECODE"
+	ENCODING = "ENCODING"
+	TODENSE  = "TODENSE"
+)
+
+// PFADD key [element [element ...]]
+func handlePFAddCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	updated, err := kvStore.PFAdd(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !updated {
+		return parser.SerializeInteger(0)
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(1)
+}
+
+// PFCOUNT key [key ...]
+func handlePFCountCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	count, err := kvStore.PFCount(cmds[1:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(int(count))
+}
+
+// PFMERGE destkey [sourcekey [sourcekey ...]]
+func handlePFMergeCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	if err := kvStore.PFMerge(cmds[1], cmds[2:]); err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeSimpleString("OK")
+}
+
+// PFDEBUG GETREG|DECODE|ENCODING|TODENSE key
+func handlePFDebugCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	var response []byte
+
+	subcommand := strings.ToUpper(cmds[1])
+
+	switch subcommand {
+	case GETREG, DECODE, ENCODING, TODENSE:
+	default:
+		return parser.SerializeSimpleError(fmt.Sprintf("ERR Unknown PFDEBUG subcommand '%s'", cmds[1]))
+	}
+
+	err := kvStore.PFDebug(cmds[2], func(hll *datatypes.String) error {
+		switch subcommand {
+		case GETREG:
+			registers, err := hll.HLLRegisters()
+
+			if err != nil {
+				return err
+			}
+
+			response = serializeIntegers(registers)
+		case DECODE:
+			decoded, err := hll.HLLDecode()
+
+			if err != nil {
+				return err
+			}
+
+			response = parser.SerializeSimpleString(decoded)
+		case ENCODING:
+			response = parser.SerializeSimpleString(hll.HLLEncoding())
+		case TODENSE:
+			converted, err := hll.HLLToDense()
+
+			if err != nil {
+				return err
+			}
+
+			response = parser.SerializeInteger(0)
+
+			if converted {
+				response = parser.SerializeInteger(1)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	// GETREG and TODENSE change the encoding, the replicas follow along
+	if subcommand == GETREG || subcommand == TODENSE {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return response
+}
@@ -0,0 +1,188 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const (
+	DEL       = "DEL"
+	UNLINK    = "UNLINK"
+	EXISTS    = "EXISTS"
+	RENAME    = "RENAME"
+	RENAMENX  = "RENAMENX"
+	COPY      = "COPY"
+	TOUCH     = "TOUCH"
+	RANDOMKEY = "RANDOMKEY"
+	SCAN      = "SCAN"
+)
+
+// DEL key [key ...] and UNLINK key [key ...]. UNLINK is DEL here, as the
+// collector already frees the deleted values off the request path.
+func handleDelCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	deleted := kvStore.Del(cmds[1:])
+
+	if deleted > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeInteger(deleted)
+}
+
+// EXISTS key [key ...]
+func handleExistsCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	return parser.SerializeInteger(kvStore.Exists(cmds[1:]))
+}
+
+// RENAME key newkey and RENAMENX key newkey
+func handleRenameCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	nx := strings.ToUpper(cmds[0]) == RENAMENX
+
+	renamed, err := kvStore.Rename(cmds[1], cmds[2], nx)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if renamed {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	if !nx {
+		return parser.SerializeSimpleString("OK")
+	}
+
+	if renamed {
+		return parser.SerializeInteger(1)
+	}
+
+	return parser.SerializeInteger(0)
+}
+
+// COPY source destination [DB destination-db] [REPLACE]
+func handleCopyCommand(cmds []string, kvStore *store.Store, dbs *store.Databases, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	dst := kvStore
+	replace := false
+
+	for i := 3; i < len(cmds); i++ {
+		switch strings.ToUpper(cmds[i]) {
+		case "REPLACE":
+			replace = true
+		case "DB":
+			if i+1 >= len(cmds) {
+				return parser.SerializeSimpleError(errSyntax)
+			}
+
+			i++
+			index, err := strconv.Atoi(cmds[i])
+
+			if err != nil {
+				return parser.SerializeSimpleError(errNotInteger)
+			}
+
+			db, err := dbs.Get(index)
+
+			if err != nil {
+				return parser.SerializeSimpleError(err.Error())
+			}
+
+			dst = db
+		default:
+			return parser.SerializeSimpleError(errSyntax)
+		}
+	}
+
+	copied, err := kvStore.Copy(cmds[1], dst, cmds[2], replace)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !copied {
+		return parser.SerializeInteger(0)
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(1)
+}
+
+// TOUCH key [key ...]
+func handleTouchCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	return parser.SerializeInteger(kvStore.Touch(cmds[1:]))
+}
+
+// RANDOMKEY
+func handleRandomKeyCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 1 {
+		return wrongArgsError(cmds[0])
+	}
+
+	key, ok := kvStore.RandomKey()
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	return parser.SerializeBulkString(key)
+}
+
+// SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+func handleScanCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	// TYPE is only valid for SCAN, the other options are shared with HSCAN and friends
+	args := []string{cmds[1]}
+	dataType := ""
+
+	for i := 2; i < len(cmds); i += 2 {
+		if strings.ToUpper(cmds[i]) == "TYPE" && i+1 < len(cmds) {
+			dataType = strings.ToLower(cmds[i+1])
+			continue
+		}
+
+		end := i + 2
+
+		if end > len(cmds) {
+			end = len(cmds)
+		}
+
+		args = append(args, cmds[i:end]...)
+	}
+
+	cursor, pattern, count, err := parseScanArgs(args)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	next, keys := kvStore.Scan(cursor, count, pattern, dataType)
+
+	return serializeScanReply(next, keys)
+}
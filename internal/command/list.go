@@ -0,0 +1,539 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const (
+	LPUSH   = "LPUSH"
+	RPUSH   = "RPUSH"
+	LPUSHX  = "LPUSHX"
+	RPUSHX  = "RPUSHX"
+	LPOP    = "LPOP"
+	RPOP    = "RPOP"
+	LRANGE  = "LRANGE"
+	LLEN    = "LLEN"
+	LINDEX  = "LINDEX"
+	LSET    = "LSET"
+	LINSERT = "LINSERT"
+	LREM    = "LREM"
+	LTRIM   = "LTRIM"
+	LPOS    = "LPOS"
+	LMOVE   = "LMOVE"
+	LMPOP   = "LMPOP"
+	BLPOP   = "BLPOP"
+	BRPOP   = "BRPOP"
+	BLMOVE  = "BLMOVE"
+	BLMPOP  = "BLMPOP"
+	LEFT    = "LEFT"
+	RIGHT   = "RIGHT"
+)
+
+func handlePushCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	name := strings.ToUpper(cmds[0])
+	left := name == LPUSH || name == LPUSHX
+	onlyIfExists := name == LPUSHX || name == RPUSHX
+
+	length, err := kvStore.Push(cmds[1], cmds[2:], left, onlyIfExists)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if length > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+func handlePopCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 2 && len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	count := 1
+
+	if len(cmds) == 3 {
+		var err error
+		count, err = strconv.Atoi(cmds[2])
+
+		if err != nil || count < 0 {
+			return parser.SerializeSimpleError("ERR value is out of range, must be positive")
+		}
+	}
+
+	values, err := kvStore.Pop(cmds[1], strings.ToUpper(cmds[0]) == LPOP, count)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if len(values) > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	// without the count argument a single bulk string is returned
+	if len(cmds) == 2 {
+		if len(values) == 0 {
+			return parser.SerializeNullBulkString()
+		}
+
+		return parser.SerializeBulkString(values[0])
+	}
+
+	if values == nil {
+		return parser.SerializeNullArray()
+	}
+
+	return parser.SerializeArray(values)
+}
+
+func handleLRangeCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	start, err1 := strconv.Atoi(cmds[2])
+	stop, err2 := strconv.Atoi(cmds[3])
+
+	if err1 != nil || err2 != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	values, err := kvStore.LRange(cmds[1], start, stop)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeArray(values)
+}
+
+func handleLLenCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	length, err := kvStore.LLen(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+func handleLIndexCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	index, err := strconv.Atoi(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	value, ok, err := kvStore.LIndex(cmds[1], index)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	return parser.SerializeBulkString(value)
+}
+
+func handleLSetCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	index, err := strconv.Atoi(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	err = kvStore.LSet(cmds[1], index, cmds[3])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeSimpleString(OK)
+}
+
+func handleLInsertCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 5 {
+		return wrongArgsError(cmds[0])
+	}
+
+	var before bool
+
+	switch strings.ToUpper(cmds[2]) {
+	case "BEFORE":
+		before = true
+	case "AFTER":
+		before = false
+	default:
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	length, err := kvStore.LInsert(cmds[1], before, cmds[3], cmds[4])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if length > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+func handleLRemCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	count, err := strconv.Atoi(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	removed, err := kvStore.LRem(cmds[1], count, cmds[3])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if removed > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeInteger(removed)
+}
+
+func handleLTrimCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	start, err1 := strconv.Atoi(cmds[2])
+	stop, err2 := strconv.Atoi(cmds[3])
+
+	if err1 != nil || err2 != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	err := kvStore.LTrim(cmds[1], start, stop)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeSimpleString(OK)
+}
+
+// LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+func handleLPosCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 || len(cmds)%2 != 1 {
+		return wrongArgsError(cmds[0])
+	}
+
+	rank, count, maxLen := 1, -1, 0
+
+	for i := 3; i < len(cmds); i += 2 {
+		value, err := strconv.Atoi(cmds[i+1])
+
+		if err != nil {
+			return parser.SerializeSimpleError(errNotInteger)
+		}
+
+		switch strings.ToUpper(cmds[i]) {
+		case "RANK":
+			if value == 0 {
+				return parser.SerializeSimpleError("ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+			}
+			rank = value
+		case "COUNT":
+			if value < 0 {
+				return parser.SerializeSimpleError("ERR COUNT can't be negative")
+			}
+			count = value
+		case "MAXLEN":
+			if value < 0 {
+				return parser.SerializeSimpleError("ERR MAXLEN can't be negative")
+			}
+			maxLen = value
+		default:
+			return parser.SerializeSimpleError(errSyntax)
+		}
+	}
+
+	limit := count
+	if count == -1 {
+		limit = 1
+	}
+
+	positions, err := kvStore.LPos(cmds[1], cmds[2], rank, limit, maxLen)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	// without COUNT the reply is a single position or null
+	if count == -1 {
+		if len(positions) == 0 {
+			return parser.SerializeNullBulkString()
+		}
+
+		return parser.SerializeInteger(positions[0])
+	}
+
+	return serializeIntegers(positions)
+}
+
+func handleLMoveCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 5 {
+		return wrongArgsError(cmds[0])
+	}
+
+	fromLeft, ok1 := parseListSide(cmds[3])
+	toLeft, ok2 := parseListSide(cmds[4])
+
+	if !ok1 || !ok2 {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	value, ok, err := kvStore.LMove(cmds[1], cmds[2], fromLeft, toLeft)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeBulkString(value)
+}
+
+// BLPOP key [key ...] timeout
+func handleBPopCommand(ctx context.Context, cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	ctx, cancel, err := blockingContext(ctx, cmds[len(cmds)-1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	defer cancel()
+
+	left := strings.ToUpper(cmds[0]) == BLPOP
+
+	result, err := kvStore.BPop(ctx, cmds[1:len(cmds)-1], left, 1)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if result == nil {
+		return parser.SerializeNullArray()
+	}
+
+	// replicas must not block, so they get the pop that actually happened
+	if left {
+		propagate([]string{LPOP, result[0]}, kvStore, cfg)
+	} else {
+		propagate([]string{RPOP, result[0]}, kvStore, cfg)
+	}
+
+	return parser.SerializeArray(result)
+}
+
+// BLMOVE source destination LEFT|RIGHT LEFT|RIGHT timeout
+func handleBLMoveCommand(ctx context.Context, cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 6 {
+		return wrongArgsError(cmds[0])
+	}
+
+	fromLeft, ok1 := parseListSide(cmds[3])
+	toLeft, ok2 := parseListSide(cmds[4])
+
+	if !ok1 || !ok2 {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	ctx, cancel, err := blockingContext(ctx, cmds[5])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	defer cancel()
+
+	value, ok, err := kvStore.BLMove(ctx, cmds[1], cmds[2], fromLeft, toLeft)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	propagate(append([]string{LMOVE}, cmds[1:5]...), kvStore, cfg)
+
+	return parser.SerializeBulkString(value)
+}
+
+// LMPOP numkeys key [key ...] LEFT|RIGHT [COUNT count]
+// BLMPOP timeout numkeys key [key ...] LEFT|RIGHT [COUNT count]
+func handleMPopCommand(ctx context.Context, cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	blocking := strings.ToUpper(cmds[0]) == BLMPOP
+	args := cmds[1:]
+
+	if blocking {
+		if len(args) == 0 {
+			return wrongArgsError(cmds[0])
+		}
+		args = args[1:]
+	}
+
+	if len(args) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	numKeys, err := strconv.Atoi(args[0])
+
+	if err != nil || numKeys <= 0 {
+		return parser.SerializeSimpleError("ERR numkeys should be greater than 0")
+	}
+
+	if numKeys > len(args)-2 {
+		return parser.SerializeSimpleError("ERR Number of keys can't be greater than number of args")
+	}
+
+	keys := args[1 : numKeys+1]
+	left, ok := parseListSide(args[numKeys+1])
+
+	if !ok {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	count := 1
+	options := args[numKeys+2:]
+
+	if len(options) != 0 {
+		if len(options) != 2 || strings.ToUpper(options[0]) != "COUNT" {
+			return parser.SerializeSimpleError(errSyntax)
+		}
+
+		count, err = strconv.Atoi(options[1])
+
+		if err != nil || count <= 0 {
+			return parser.SerializeSimpleError("ERR count should be greater than 0")
+		}
+	}
+
+	var result []string
+
+	if blocking {
+		var cancel context.CancelFunc
+		ctx, cancel, err = blockingContext(ctx, cmds[1])
+
+		if err != nil {
+			return parser.SerializeSimpleError(err.Error())
+		}
+
+		defer cancel()
+
+		result, err = kvStore.BPop(ctx, keys, left, count)
+	} else {
+		result, err = kvStore.MPop(keys, left, count)
+	}
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if result == nil {
+		return parser.SerializeNullArray()
+	}
+
+	popCommand := RPOP
+	if left {
+		popCommand = LPOP
+	}
+
+	propagate([]string{popCommand, result[0], strconv.Itoa(len(result) - 1)}, kvStore, cfg)
+
+	return parser.SerializeRawArray([][]byte{
+		parser.SerializeBulkString(result[0]),
+		parser.SerializeArray(result[1:]),
+	})
+}
+
+// derives the context a blocking command waits on from its timeout in seconds,
+// a timeout of 0 blocks until the client goes away
+func blockingContext(ctx context.Context, timeout string) (context.Context, context.CancelFunc, error) {
+	seconds, err := strconv.ParseFloat(timeout, 64)
+
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return nil, nil, errors.New("ERR timeout is not a float or out of range")
+	}
+
+	if seconds < 0 {
+		return nil, nil, errors.New("ERR timeout is negative")
+	}
+
+	if seconds == 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(seconds*float64(time.Second)))
+	return ctx, cancel, nil
+}
+
+// returns true for LEFT, false for RIGHT and ok = false for anything else
+func parseListSide(side string) (left bool, ok bool) {
+	switch strings.ToUpper(side) {
+	case LEFT:
+		return true, true
+	case RIGHT:
+		return false, true
+	default:
+		return false, false
+	}
+}
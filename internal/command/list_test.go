@@ -0,0 +1,31 @@
+package command
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLInsert(t *testing.T) {
+	tests := []struct {
+		encoding string
+		maxSize  string // list-max-listpack-size
+	}{
+		{"listpack", "-2"},
+		{"quicklist", "2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.encoding, func(t *testing.T) {
+			s := newTestServer()
+
+			s.expect(t, "+OK\r\n", "CONFIG", "SET", "list-max-listpack-size", test.maxSize)
+			s.expect(t, ":3\r\n", "RPUSH", "l", "a", "b", "c")
+			s.expect(t, "$"+strconv.Itoa(len(test.encoding))+"\r\n"+test.encoding+"\r\n", "OBJECT", "ENCODING", "l")
+			s.expect(t, ":4\r\n", "LINSERT", "l", "BEFORE", "b", "x")
+			s.expect(t, ":5\r\n", "LINSERT", "l", "AFTER", "c", "y")
+			s.expect(t, ":-1\r\n", "LINSERT", "l", "AFTER", "z", "y")
+			s.expect(t, ":0\r\n", "LINSERT", "missing", "AFTER", "a", "y")
+			s.expect(t, "*5\r\n$1\r\na\r\n$1\r\nx\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\ny\r\n", "LRANGE", "l", "0", "-1")
+		})
+	}
+}
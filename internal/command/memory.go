@@ -0,0 +1,250 @@
+package command
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const MEMORY = "MEMORY"
+
+// commands that may grow the memory used, refused once maxmemory is reached and
+// nothing more can be evicted, like the denyoom flag in redis
+var denyOOM = map[string]bool{
+	SET: true, SETNX: true, SETEX: true, PSETEX: true, GETSET: true, MSET: true, MSETNX: true,
+	APPEND: true, SETRANGE: true, INCR: true, DECR: true, INCRBY: true, DECRBY: true, INCRBYFLOAT: true,
+	SETBIT: true, BITOP: true, BITFIELD: true,
+	LPUSH: true, RPUSH: true, LPUSHX: true, RPUSHX: true, LINSERT: true, LSET: true, LMOVE: true, BLMOVE: true,
+	HSET: true, HSETNX: true, HINCRBY: true, HINCRBYFLOAT: true,
+	SADD: true, SINTERSTORE: true, SUNIONSTORE: true, SDIFFSTORE: true,
+	ZADD: true, ZINCRBY: true, ZUNIONSTORE: true, ZINTERSTORE: true, ZDIFFSTORE: true, ZRANGESTORE: true,
+	XADD: true, PFADD: true, PFMERGE: true, GEOADD: true, GEOSEARCHSTORE: true, COPY: true, RESTORE: true,
+}
+
+// evicts keys while the used memory is over maxmemory, replicating the evictions
+// as DELs. returns store.ErrOOM when command may grow the memory used and the
+// memory could not be freed. replicas leave eviction to their master.
+func performEvictions(command string, dbs *store.Databases, cfg *config.ServerConfig) error {
+	maxMemory, policy, samples := cfg.MaxMemorySettings()
+
+	if maxMemory == 0 || cfg.Role == config.RoleSlave {
+		return nil
+	}
+
+	evicted, err := dbs.PerformEvictions(maxMemory, policy, samples)
+
+	for _, key := range evicted {
+		propagate([]string{DEL, key.Key}, key.DB, cfg)
+	}
+
+	if err != nil && denyOOM[command] {
+		return err
+	}
+
+	return nil
+}
+
+// the memory figures shared by MEMORY STATS, MEMORY DOCTOR and INFO
+type memoryReport struct {
+	used     int64 // estimated memory used by the keys and values
+	peak     int64
+	overhead int64 // the part of used spent on the key tables
+	keys     int
+	runtime  runtime.MemStats
+}
+
+func newMemoryReport(dbs *store.Databases) *memoryReport {
+	report := &memoryReport{
+		used: dbs.UsedMemory(),
+		peak: dbs.PeakMemory(),
+	}
+
+	for i := 0; i < dbs.Len(); i++ {
+		db, _ := dbs.Get(i)
+		keys, expires := db.MemoryOverhead()
+
+		report.overhead += keys + expires
+		report.keys += db.DBSize()
+	}
+
+	runtime.ReadMemStats(&report.runtime)
+
+	return report
+}
+
+// what the process got from the OS over what the data set needs
+func (r *memoryReport) fragmentation() float64 {
+	if r.used == 0 {
+		return 0
+	}
+
+	return float64(r.runtime.Sys) / float64(r.used)
+}
+
+// MEMORY USAGE key [SAMPLES count], MEMORY STATS, MEMORY DOCTOR and MEMORY HELP
+func handleMemoryCommand(cmds []string, kvStore *store.Store, dbs *store.Databases) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	switch strings.ToUpper(cmds[1]) {
+	case "USAGE":
+		return handleMemoryUsageCommand(cmds, kvStore)
+	case "STATS":
+		if len(cmds) != 2 {
+			return parser.SerializeSimpleError("ERR wrong number of arguments for 'memory|stats' command")
+		}
+
+		return handleMemoryStatsCommand(dbs)
+	case "DOCTOR":
+		if len(cmds) != 2 {
+			return parser.SerializeSimpleError("ERR wrong number of arguments for 'memory|doctor' command")
+		}
+
+		return parser.SerializeBulkString(memoryDoctor(newMemoryReport(dbs)))
+	case "HELP":
+		return parser.SerializeArray([]string{
+			"MEMORY <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"DOCTOR",
+			"    Return memory problems reports.",
+			"STATS",
+			"    Return information about the memory usage of the server.",
+			"USAGE <key> [SAMPLES <count>]",
+			"    Return memory in bytes used by <key> and its value. Nested values are",
+			"    sampled up to <count> times (default: 5, 0 means sample all).",
+			"HELP",
+			"    Print this help.",
+		})
+	default:
+		return parser.SerializeSimpleError(fmt.Sprintf("ERR unknown subcommand '%s'. Try MEMORY HELP.", cmds[1]))
+	}
+}
+
+func handleMemoryUsageCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return parser.SerializeSimpleError("ERR wrong number of arguments for 'memory|usage' command")
+	}
+
+	samples := 5
+
+	for i := 3; i < len(cmds); i += 2 {
+		if strings.ToUpper(cmds[i]) != "SAMPLES" || i+1 >= len(cmds) {
+			return parser.SerializeSimpleError(errSyntax)
+		}
+
+		n, err := strconv.Atoi(cmds[i+1])
+
+		if err != nil || n < 0 {
+			return parser.SerializeSimpleError(errNotInteger)
+		}
+
+		samples = n
+	}
+
+	usage, ok := kvStore.MemoryUsage(cmds[2], samples)
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	return parser.SerializeInteger(usage)
+}
+
+func handleMemoryStatsCommand(dbs *store.Databases) []byte {
+	report := newMemoryReport(dbs)
+	stats := [][]byte{}
+
+	add := func(name string, value []byte) {
+		stats = append(stats, parser.SerializeBulkString(name), value)
+	}
+
+	add("peak.allocated", parser.SerializeInteger(int(report.peak)))
+	add("total.allocated", parser.SerializeInteger(int(report.used)))
+
+	for i := 0; i < dbs.Len(); i++ {
+		db, _ := dbs.Get(i)
+		keys, expires := db.MemoryOverhead()
+
+		if keys == 0 {
+			continue
+		}
+
+		add(fmt.Sprintf("db.%d", i), parser.SerializeRawArray([][]byte{
+			parser.SerializeBulkString("overhead.hashtable.main"), parser.SerializeInteger(int(keys)),
+			parser.SerializeBulkString("overhead.hashtable.expires"), parser.SerializeInteger(int(expires)),
+		}))
+	}
+
+	dataset := report.used - report.overhead
+	bytesPerKey := int64(0)
+
+	if report.keys > 0 {
+		bytesPerKey = report.used / int64(report.keys)
+	}
+
+	add("overhead.total", parser.SerializeInteger(int(report.overhead)))
+	add("keys.count", parser.SerializeInteger(report.keys))
+	add("keys.bytes-per-key", parser.SerializeInteger(int(bytesPerKey)))
+	add("dataset.bytes", parser.SerializeInteger(int(dataset)))
+	add("dataset.percentage", parser.SerializeBulkString(formatPercentage(dataset, report.used)))
+	add("peak.percentage", parser.SerializeBulkString(formatPercentage(report.used, report.peak)))
+	add("allocator.allocated", parser.SerializeInteger(int(report.runtime.HeapAlloc)))
+	add("allocator.active", parser.SerializeInteger(int(report.runtime.HeapInuse)))
+	add("allocator.resident", parser.SerializeInteger(int(report.runtime.Sys)))
+	add("fragmentation", parser.SerializeBulkString(strconv.FormatFloat(report.fragmentation(), 'f', -1, 64)))
+	add("fragmentation.bytes", parser.SerializeInteger(int(int64(report.runtime.Sys)-report.used)))
+
+	return parser.SerializeRawArray(stats)
+}
+
+// the issues redis' MEMORY DOCTOR looks for that apply here
+func memoryDoctor(report *memoryReport) string {
+	// below this there is too little data for the ratios to mean anything
+	if report.used < 5<<20 {
+		return "Hi Sam, this instance is empty or is using very little memory, my issues detector can't be used in these conditions. Please, leave for your mission on Earth and fill it with some data. The new Sam and I will be back to our programming as soon as I finished rebooting."
+	}
+
+	issues := []string{}
+
+	if report.peak*10 > report.used*15 {
+		issues = append(issues, " * Peak memory: In the past this instance used more than 150% the memory that is currently using. The allocator is normally not able to release memory after a peak, so you can expect to see a big fragmentation ratio, however this is actually harmless and is only due to the memory peak, and if the Redis instance Resident Set Size (RSS) is currently bigger than expected, the memory will be used as soon as you fill the Redis instance with more data. If the memory peak was only occasional and you want to try to reclaim memory, please try the MEMORY PURGE command, otherwise the only other option is to shutdown and restart the instance.\n\n")
+	}
+
+	if report.fragmentation() > 1.4 {
+		issues = append(issues, fmt.Sprintf(" * High total RSS: This instance has a memory fragmentation and RSS overhead greater than 1.4 (this means that the Resident Set Size of the Redis process is much larger than the sum of the logical allocations Redis performed). This problem is usually due either to a large peak memory (check if there is a peak memory entry above in the report) or may result from a workload that causes the allocator to fragment memory a lot. The current ratio is %.2f.\n\n", report.fragmentation()))
+	}
+
+	if len(issues) == 0 {
+		return "Hi Sam, I can't find any memory issue in your instance. I can only account for what occurs on this base."
+	}
+
+	return "Sam, I detected a few issues in this Redis instance memory implants:\n\n" + strings.Join(issues, "") + "I'm here to keep you safe, Sam. I want to help you.\n"
+}
+
+// part * 100 / whole
+func formatPercentage(part, whole int64) string {
+	if whole == 0 {
+		return "0"
+	}
+
+	return strconv.FormatFloat(float64(part)*100/float64(whole), 'f', -1, 64)
+}
+
+// formats bytes like 1.50M, as the *_human fields of INFO
+func bytesToHuman(n int64) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%dB", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.2fK", float64(n)/(1<<10))
+	case n < 1<<30:
+		return fmt.Sprintf("%.2fM", float64(n)/(1<<20))
+	default:
+		return fmt.Sprintf("%.2fG", float64(n)/(1<<30))
+	}
+}
@@ -0,0 +1,374 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/rdb"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const MIGRATE = "MIGRATE"
+
+const (
+	// cached connections unused for that long are closed, like MIGRATE_SOCKET_CACHE_TTL in redis
+	migrateConnTTL = 10 * time.Second
+	// the most targets a connection is kept open to, like MIGRATE_SOCKET_CACHE_ITEMS in redis
+	migrateConnCacheSize = 64
+)
+
+// a connection to a MIGRATE target, kept open for the next MIGRATEs to it
+type migrateConn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	db       int // the database selected on the target, -1 before the first SELECT
+	lastUsed time.Time
+}
+
+// the idle connections by target address. a MIGRATE takes the connection out
+// while using it, so two MIGRATEs to the same target never share one.
+var migrateConns = struct {
+	sync.Mutex
+	conns map[string]*migrateConn
+}{conns: make(map[string]*migrateConn)}
+
+// an i/o error on a migrate connection, which can not be used anymore
+type migrateIOError struct {
+	message string
+	cause   error
+}
+
+func (e *migrateIOError) Error() string {
+	return e.message
+}
+
+func (e *migrateIOError) Unwrap() error {
+	return e.cause
+}
+
+// a key to migrate with what RESTORE needs
+type migrateKey struct {
+	key     string
+	ttl     int64 // milliseconds left, 0 when it does not expire
+	payload []byte
+	version uint64 // of the key when it was dumped, see Store.DelIfVersion
+}
+
+// MIGRATE host port key|"" destination-db timeout [COPY] [REPLACE] [AUTH password]
+// [AUTH2 username password] [KEYS key [key ...]]
+func handleMigrateCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 6 {
+		return wrongArgsError(cmds[0])
+	}
+
+	copyKeys, replace := false, false
+	keys := []string{cmds[3]}
+	var auth []string
+
+	for i := 6; i < len(cmds); i++ {
+		switch strings.ToUpper(cmds[i]) {
+		case "COPY":
+			copyKeys = true
+		case "REPLACE":
+			replace = true
+		case "AUTH":
+			if i+1 >= len(cmds) {
+				return parser.SerializeSimpleError(errSyntax)
+			}
+
+			auth = []string{"AUTH", cmds[i+1]}
+			i++
+		case "AUTH2":
+			if i+2 >= len(cmds) {
+				return parser.SerializeSimpleError(errSyntax)
+			}
+
+			auth = []string{"AUTH", cmds[i+1], cmds[i+2]}
+			i += 2
+		case "KEYS":
+			if cmds[3] != "" {
+				return parser.SerializeSimpleError("ERR When using MIGRATE KEYS option, the key argument must be set to the empty string")
+			}
+
+			keys = cmds[i+1:]
+			i = len(cmds)
+		default:
+			return parser.SerializeSimpleError(errSyntax)
+		}
+	}
+
+	db, err := strconv.Atoi(cmds[4])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	timeout, err := strconv.Atoi(cmds[5])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	if timeout <= 0 {
+		timeout = 1000
+	}
+
+	migrating := dumpMigratingKeys(keys, kvStore)
+
+	if len(migrating) == 0 {
+		return parser.SerializeSimpleString("NOKEY")
+	}
+
+	addr := net.JoinHostPort(cmds[1], cmds[2])
+	restored, err := migrate(addr, db, time.Duration(timeout)*time.Millisecond, auth, migrating, replace)
+
+	if !copyKeys && len(restored) > 0 {
+		deleted, modified := deleteMigratedKeys(restored, migrating, kvStore)
+
+		if len(deleted) > 0 {
+			propagate(append([]string{DEL}, deleted...), kvStore, cfg)
+		}
+
+		if err == nil && modified != "" {
+			err = errors.New("ERR Key " + modified + " was modified during MIGRATE and was not deleted")
+		}
+	}
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeSimpleString(OK)
+}
+
+// serializes the keys that exist, along with their time to live
+func dumpMigratingKeys(keys []string, kvStore *store.Store) []migrateKey {
+	migrating := []migrateKey{}
+
+	for _, key := range keys {
+		m := migrateKey{key: key}
+
+		version, exists := kvStore.View(key, func(value store.Data, expiry time.Time) {
+			m.payload = rdb.Dump(value)
+
+			if !expiry.IsZero() {
+				m.ttl = time.Until(expiry).Milliseconds()
+
+				// about to expire, but not yet
+				if m.ttl < 1 {
+					m.ttl = 1
+				}
+			}
+		})
+
+		if !exists {
+			continue
+		}
+
+		m.version = version
+		migrating = append(migrating, m)
+	}
+
+	return migrating
+}
+
+// deletes the restored keys that were not written to since they were dumped.
+// returns the keys deleted and the first one written to, which is left in place
+// as the target has an older value.
+func deleteMigratedKeys(restored []string, migrating []migrateKey, kvStore *store.Store) ([]string, string) {
+	versions := make(map[string]uint64, len(migrating))
+
+	for _, m := range migrating {
+		versions[m.key] = m.version
+	}
+
+	deleted := []string{}
+	modified := ""
+
+	for _, key := range restored {
+		ok, exists := kvStore.DelIfVersion(key, versions[key])
+
+		if ok {
+			deleted = append(deleted, key)
+		} else if exists && modified == "" {
+			modified = key
+		}
+	}
+
+	return deleted, modified
+}
+
+// restores the keys into database db of the target at addr, pipelining AUTH,
+// SELECT when the connection has another database selected, and a RESTORE per
+// key. returns the keys the target restored, which may be only some of them
+// when an error is returned. a connection that failed before any reply is read
+// may just have been closed by the target while cached, so it is retried once.
+func migrate(addr string, db int, timeout time.Duration, auth []string, keys []migrateKey, replace bool) ([]string, error) {
+	var authCommand []byte
+	var restores bytes.Buffer
+
+	if auth != nil {
+		authCommand = parser.SerializeArray(auth)
+	}
+
+	for _, k := range keys {
+		restore := []string{RESTORE, k.key, strconv.FormatInt(k.ttl, 10), string(k.payload)}
+
+		if replace {
+			restore = append(restore, "REPLACE")
+		}
+
+		restores.Write(parser.SerializeArray(restore))
+	}
+
+	for attempt := 0; ; attempt++ {
+		mc, err := takeMigrateConn(addr, timeout)
+
+		if err != nil {
+			return nil, errors.New("IOERR error or timeout connecting to the client")
+		}
+
+		restored, err := mc.restore(db, timeout, authCommand, restores.Bytes(), keys)
+
+		var ioErr *migrateIOError
+
+		if !errors.As(err, &ioErr) {
+			// the target answered every command, so the connection is still in sync
+			releaseMigrateConn(addr, mc)
+			return restored, err
+		}
+
+		mc.conn.Close()
+
+		if attempt > 0 || len(restored) > 0 || errors.Is(err, os.ErrDeadlineExceeded) {
+			return restored, err
+		}
+	}
+}
+
+// sends the AUTH when given, SELECT when needed and the RESTOREs, then reads
+// the replies. returns the keys restored and the first error replied, or a
+// *migrateIOError when the connection broke.
+func (mc *migrateConn) restore(db int, timeout time.Duration, authCommand, restores []byte, keys []migrateKey) ([]string, error) {
+	mc.conn.SetDeadline(time.Now().Add(timeout))
+
+	auth := authCommand != nil
+	selecting := mc.db != db
+
+	var request bytes.Buffer
+
+	request.Write(authCommand)
+
+	if selecting {
+		request.Write(parser.SerializeArray([]string{SELECT, strconv.Itoa(db)}))
+	}
+
+	request.Write(restores)
+
+	if _, err := mc.conn.Write(request.Bytes()); err != nil {
+		return nil, &migrateIOError{"IOERR error or timeout writing to target instance", err}
+	}
+
+	replies := len(keys)
+
+	if auth {
+		replies++
+	}
+
+	if selecting {
+		replies++
+	}
+
+	// every reply is read to keep the connection in sync, the first error is returned
+	restored := []string{}
+	var replyErr error
+
+	for i := 0; i < replies; i++ {
+		reply, err := mc.reader.ReadString('\n')
+
+		if err != nil {
+			return restored, &migrateIOError{"IOERR error or timeout reading to target instance", err}
+		}
+
+		isError := strings.HasPrefix(reply, "-")
+
+		if isError && replyErr == nil {
+			replyErr = errors.New("ERR Target instance replied with error: " + strings.TrimSpace(reply[1:]))
+		}
+
+		switch {
+		case auth && i == 0:
+			continue
+		case selecting && i == replies-len(keys)-1:
+			mc.db = db
+
+			if isError {
+				mc.db = -1
+			}
+		case !isError:
+			restored = append(restored, keys[i-(replies-len(keys))].key)
+		}
+	}
+
+	return restored, replyErr
+}
+
+// takes the cached connection to addr out of the cache, or opens a new one.
+// the connections unused for too long are closed on the way.
+func takeMigrateConn(addr string, timeout time.Duration) (*migrateConn, error) {
+	migrateConns.Lock()
+
+	for a, mc := range migrateConns.conns {
+		if time.Since(mc.lastUsed) > migrateConnTTL {
+			mc.conn.Close()
+			delete(migrateConns.conns, a)
+		}
+	}
+
+	mc, ok := migrateConns.conns[addr]
+	delete(migrateConns.conns, addr)
+
+	migrateConns.Unlock()
+
+	if ok {
+		return mc, nil
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &migrateConn{conn: conn, reader: bufio.NewReader(conn), db: -1}, nil
+}
+
+// puts mc back in the cache for the next MIGRATE to addr
+func releaseMigrateConn(addr string, mc *migrateConn) {
+	mc.lastUsed = time.Now()
+
+	migrateConns.Lock()
+	defer migrateConns.Unlock()
+
+	if old, ok := migrateConns.conns[addr]; ok {
+		old.conn.Close()
+	} else if len(migrateConns.conns) >= migrateConnCacheSize {
+		// like redis, make room by closing a random connection
+		for a, other := range migrateConns.conns {
+			other.conn.Close()
+			delete(migrateConns.conns, a)
+			break
+		}
+	}
+
+	migrateConns.conns[addr] = mc
+}
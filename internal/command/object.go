@@ -0,0 +1,87 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const OBJECT = "OBJECT"
+
+// OBJECT ENCODING|IDLETIME|FREQ|REFCOUNT key and OBJECT HELP. none of them count as an access to key.
+func handleObjectCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	subcommand := strings.ToUpper(cmds[1])
+
+	if subcommand == "HELP" {
+		return parser.SerializeArray([]string{
+			"OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"ENCODING <key>",
+			"    Return the kind of internal representation used in order to store the value",
+			"    associated with a <key>.",
+			"FREQ <key>",
+			"    Return the access frequency index of the <key>. The returned integer is",
+			"    proportional to the logarithm of the recent access frequency of the key.",
+			"IDLETIME <key>",
+			"    Return the idle time of the <key>, that is the approximated number of",
+			"    seconds elapsed since the last access to the key.",
+			"REFCOUNT <key>",
+			"    Return the number of references of the value associated with the specified",
+			"    <key>.",
+			"HELP",
+			"    Print this help.",
+		})
+	}
+
+	switch subcommand {
+	case "ENCODING", "IDLETIME", "FREQ", "REFCOUNT":
+	default:
+		return parser.SerializeSimpleError(fmt.Sprintf("ERR unknown subcommand '%s'. Try OBJECT HELP.", cmds[1]))
+	}
+
+	if len(cmds) != 3 {
+		return parser.SerializeSimpleError(fmt.Sprintf("ERR wrong number of arguments for 'object|%s' command", strings.ToLower(subcommand)))
+	}
+
+	info, ok := kvStore.Object(cmds[2])
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	maxMemory, policy, _ := cfg.MaxMemorySettings()
+	lfu := policy == store.AllKeysLFU || policy == store.VolatileLFU
+
+	switch subcommand {
+	case "ENCODING":
+		return parser.SerializeBulkString(info.Encoding)
+	case "IDLETIME":
+		if lfu {
+			return parser.SerializeSimpleError("ERR An LFU maxmemory policy is selected, idle time not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+		}
+
+		return parser.SerializeInteger(int(info.IdleTime.Seconds()))
+	case "FREQ":
+		if !lfu {
+			return parser.SerializeSimpleError("ERR An LFU maxmemory policy is not selected, access frequency not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+		}
+
+		return parser.SerializeInteger(info.Frequency)
+	default:
+		// redis stops sharing integers once they need their own lru or lfu clock
+		lru := policy == store.AllKeysLRU || policy == store.VolatileLRU
+
+		if info.Shared && (maxMemory == 0 || !(lru || lfu)) {
+			return parser.SerializeInteger(math.MaxInt32)
+		}
+
+		return parser.SerializeInteger(1)
+	}
+}
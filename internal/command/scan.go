@@ -0,0 +1,52 @@
+package command
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+)
+
+// parses cursor [MATCH pattern] [COUNT count], shared by the SCAN family of commands
+func parseScanArgs(args []string) (cursor uint64, pattern string, count int, err error) {
+	cursor, err = strconv.ParseUint(args[0], 10, 64)
+
+	if err != nil {
+		return 0, "", 0, errors.New("ERR invalid cursor")
+	}
+
+	count = 10
+
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return 0, "", 0, errors.New(errSyntax)
+		}
+
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(args[i+1])
+
+			if err != nil {
+				return 0, "", 0, errors.New(errNotInteger)
+			}
+
+			if count < 1 {
+				return 0, "", 0, errors.New(errSyntax)
+			}
+		default:
+			return 0, "", 0, errors.New(errSyntax)
+		}
+	}
+
+	return cursor, pattern, count, nil
+}
+
+func serializeScanReply(cursor uint64, values []string) []byte {
+	return parser.SerializeRawArray([][]byte{
+		parser.SerializeBulkString(strconv.FormatUint(cursor, 10)),
+		parser.SerializeArray(values),
+	})
+}
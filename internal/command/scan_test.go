@@ -0,0 +1,100 @@
+package command
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+)
+
+// runs a command of the SCAN family, returns the cursor and the elements replied
+func (s *testServer) scan(t *testing.T, cmds ...string) (string, []string) {
+	t.Helper()
+
+	reply := s.run(cmds...)
+
+	// the array header, the cursor as a bulk string, then the elements
+	parts := strings.SplitN(reply, "\r\n", 4)
+
+	if len(parts) != 4 || parts[0] != "*2" {
+		t.Fatalf("%q: unexpected reply %q", cmds, reply)
+	}
+
+	message, err := parser.Deserialize(bufio.NewReader(strings.NewReader(parts[3])))
+
+	if err != nil {
+		t.Fatalf("%q: unexpected reply %q", cmds, reply)
+	}
+
+	return parts[2], message.Commands
+}
+
+func TestScanCollections(t *testing.T) {
+	tests := []struct {
+		name    string
+		add     func(member string) []string
+		scan    string
+		perItem int // elements replied per member
+	}{
+		{"hash", func(m string) []string { return []string{"HSET", "key", m, "v"} }, "HSCAN", 2},
+		{"set", func(m string) []string { return []string{"SADD", "key", m} }, "SSCAN", 1},
+		{"sorted set", func(m string) []string { return []string{"ZADD", "key", "1", m} }, "ZSCAN", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name+", compact", func(t *testing.T) {
+			s := newTestServer()
+
+			for i := 0; i < 5; i++ {
+				s.run(test.add(strconv.Itoa(i))...)
+			}
+
+			// like redis, everything at once whatever the count
+			cursor, elements := s.scan(t, test.scan, "key", "0", "COUNT", "1")
+
+			if cursor != "0" || len(elements) != 5*test.perItem {
+				t.Errorf("got cursor %s and %d elements, want 0 and %d", cursor, len(elements), 5*test.perItem)
+			}
+		})
+
+		t.Run(test.name+", hash table", func(t *testing.T) {
+			s := newTestServer()
+			members := 1000
+
+			for i := 0; i < members; i++ {
+				s.run(test.add("member" + strconv.Itoa(i))...)
+			}
+
+			seen := map[string]bool{}
+			cursor, calls := "0", 0
+
+			for {
+				var elements []string
+				cursor, elements = s.scan(t, test.scan, "key", cursor, "COUNT", "10")
+				calls++
+
+				if len(elements) > 100*test.perItem {
+					t.Fatalf("%d elements for a count of 10", len(elements))
+				}
+
+				for i := 0; i < len(elements); i += test.perItem {
+					if seen[elements[i]] {
+						t.Fatalf("%s returned twice", elements[i])
+					}
+
+					seen[elements[i]] = true
+				}
+
+				if cursor == "0" {
+					break
+				}
+			}
+
+			if len(seen) != members || calls < members/100 {
+				t.Errorf("got %d members in %d calls, want %d members in more calls", len(seen), calls, members)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package command
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const (
+	SADD        = "SADD"
+	SREM        = "SREM"
+	SISMEMBER   = "SISMEMBER"
+	SMISMEMBER  = "SMISMEMBER"
+	SMEMBERS    = "SMEMBERS"
+	SCARD       = "SCARD"
+	SPOP        = "SPOP"
+	SRANDMEMBER = "SRANDMEMBER"
+	SMOVE       = "SMOVE"
+	SINTER      = "SINTER"
+	SUNION      = "SUNION"
+	SDIFF       = "SDIFF"
+	SINTERSTORE = "SINTERSTORE"
+	SUNIONSTORE = "SUNIONSTORE"
+	SDIFFSTORE  = "SDIFFSTORE"
+	SINTERCARD  = "SINTERCARD"
+	SSCAN       = "SSCAN"
+)
+
+func handleSAddCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	added, err := kvStore.SAdd(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if added > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeInteger(added)
+}
+
+func handleSRemCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	removed, err := kvStore.SRem(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if removed > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeInteger(removed)
+}
+
+// SISMEMBER key member replies with an integer, SMISMEMBER key member [member ...] with an array
+func handleSIsMemberCommand(cmds []string, kvStore *store.Store) []byte {
+	multi := strings.ToUpper(cmds[0]) == SMISMEMBER
+
+	if (multi && len(cmds) < 3) || (!multi && len(cmds) != 3) {
+		return wrongArgsError(cmds[0])
+	}
+
+	found, err := kvStore.SMIsMember(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	result := make([]int, len(found))
+
+	for i, ok := range found {
+		if ok {
+			result[i] = 1
+		}
+	}
+
+	if !multi {
+		return parser.SerializeInteger(result[0])
+	}
+
+	return serializeIntegers(result)
+}
+
+func handleSMembersCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	members, err := kvStore.SMembers(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeArray(members)
+}
+
+func handleSCardCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	length, err := kvStore.SCard(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+// SPOP key [count]
+func handleSPopCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 2 && len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	count := 1
+
+	if len(cmds) == 3 {
+		var err error
+		count, err = strconv.Atoi(cmds[2])
+
+		if err != nil || count < 0 {
+			return parser.SerializeSimpleError("ERR value is out of range, must be positive")
+		}
+	}
+
+	members, err := kvStore.SPop(cmds[1], count)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	// the popped members are random, so replicas are told exactly which ones went away
+	if len(members) > 0 {
+		propagate(append([]string{SREM, cmds[1]}, members...), kvStore, cfg)
+	}
+
+	if len(cmds) == 2 {
+		if len(members) == 0 {
+			return parser.SerializeNullBulkString()
+		}
+
+		return parser.SerializeBulkString(members[0])
+	}
+
+	return parser.SerializeArray(members)
+}
+
+// SRANDMEMBER key [count]
+func handleSRandMemberCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 && len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	count := 1
+
+	if len(cmds) == 3 {
+		var err error
+		count, err = strconv.Atoi(cmds[2])
+
+		if err != nil {
+			return parser.SerializeSimpleError(errNotInteger)
+		}
+
+		// its opposite, the number of members to return, does not fit
+		if count == math.MinInt {
+			return parser.SerializeSimpleError("ERR value is out of range")
+		}
+	}
+
+	members, err := kvStore.SRandMember(cmds[1], count)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if len(cmds) == 2 {
+		if len(members) == 0 {
+			return parser.SerializeNullBulkString()
+		}
+
+		return parser.SerializeBulkString(members[0])
+	}
+
+	return parser.SerializeArray(members)
+}
+
+func handleSMoveCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	moved, err := kvStore.SMove(cmds[1], cmds[2], cmds[3])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !moved {
+		return parser.SerializeInteger(0)
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(1)
+}
+
+// SINTER, SUNION and SDIFF key [key ...]
+func handleSetOperationCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	op := strings.TrimPrefix(strings.ToUpper(cmds[0]), "S")
+
+	members, err := kvStore.SetOperation(op, cmds[1:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeArray(members)
+}
+
+// SINTERSTORE, SUNIONSTORE and SDIFFSTORE destination key [key ...]
+func handleSetOperationStoreCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	op := strings.TrimSuffix(strings.TrimPrefix(strings.ToUpper(cmds[0]), "S"), "STORE")
+
+	length, err := kvStore.SetOperationStore(op, cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(length)
+}
+
+// SINTERCARD numkeys key [key ...] [LIMIT limit]
+func handleSInterCardCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	keys, limit, errReply := parseInterCardArgs(cmds[1:])
+
+	if errReply != nil {
+		return errReply
+	}
+
+	length, err := kvStore.SInterCard(keys, limit)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+// SSCAN key cursor [MATCH pattern] [COUNT count]
+func handleSScanCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	cursor, pattern, count, err := parseScanArgs(cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	next, members, err := kvStore.SScan(cmds[1], cursor, count, pattern)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return serializeScanReply(next, members)
+}
+
+// parses numkeys key [key ...] [LIMIT limit], returns an error reply when invalid
+func parseInterCardArgs(args []string) ([]string, int, []byte) {
+	numKeys, err := strconv.Atoi(args[0])
+
+	if err != nil || numKeys <= 0 {
+		return nil, 0, parser.SerializeSimpleError("ERR numkeys should be greater than 0")
+	}
+
+	if numKeys > len(args)-1 {
+		return nil, 0, parser.SerializeSimpleError("ERR Number of keys can't be greater than number of args")
+	}
+
+	keys := args[1 : numKeys+1]
+	options := args[numKeys+1:]
+	limit := 0
+
+	if len(options) != 0 {
+		if len(options) != 2 || strings.ToUpper(options[0]) != "LIMIT" {
+			return nil, 0, parser.SerializeSimpleError(errSyntax)
+		}
+
+		limit, err = strconv.Atoi(options[1])
+
+		if err != nil || limit < 0 {
+			return nil, 0, parser.SerializeSimpleError("ERR LIMIT can't be negative")
+		}
+	}
+
+	return keys, limit, nil
+}
@@ -0,0 +1,770 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+const (
+	ZADD        = "ZADD"
+	ZREM        = "ZREM"
+	ZSCORE      = "ZSCORE"
+	ZMSCORE     = "ZMSCORE"
+	ZINCRBY     = "ZINCRBY"
+	ZCARD       = "ZCARD"
+	ZCOUNT      = "ZCOUNT"
+	ZRANK       = "ZRANK"
+	ZREVRANK    = "ZREVRANK"
+	ZRANGE      = "ZRANGE"
+	ZRANGESTORE = "ZRANGESTORE"
+	ZPOPMIN     = "ZPOPMIN"
+	ZPOPMAX     = "ZPOPMAX"
+	ZSCAN       = "ZSCAN"
+	ZUNION      = "ZUNION"
+	ZINTER      = "ZINTER"
+	ZDIFF       = "ZDIFF"
+	ZUNIONSTORE = "ZUNIONSTORE"
+	ZINTERSTORE = "ZINTERSTORE"
+	ZDIFFSTORE  = "ZDIFFSTORE"
+	ZINTERCARD  = "ZINTERCARD"
+	ZMPOP       = "ZMPOP"
+	BZPOPMIN    = "BZPOPMIN"
+	BZPOPMAX    = "BZPOPMAX"
+	BZMPOP      = "BZMPOP"
+	MIN         = "MIN"
+	MAX         = "MAX"
+)
+
+// ZADD key [NX | XX] [GT | LT] [CH] [INCR] score member [score member ...]
+func handleZAddCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	var options datatypes.ZAddOptions
+	var ch bool
+
+	i := 2
+
+flags:
+	for ; i < len(cmds); i++ {
+		switch strings.ToUpper(cmds[i]) {
+		case "NX":
+			options.NX = true
+		case "XX":
+			options.XX = true
+		case "GT":
+			options.GT = true
+		case "LT":
+			options.LT = true
+		case "CH":
+			ch = true
+		case "INCR":
+			options.Incr = true
+		default:
+			break flags
+		}
+	}
+
+	pairs := cmds[i:]
+
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	if options.NX && options.XX {
+		return parser.SerializeSimpleError("ERR XX and NX options at the same time are not compatible")
+	}
+
+	if (options.GT && options.LT) || (options.NX && (options.GT || options.LT)) {
+		return parser.SerializeSimpleError("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+
+	if options.Incr && len(pairs) != 2 {
+		return parser.SerializeSimpleError("ERR INCR option supports a single increment-element pair")
+	}
+
+	members := make([]datatypes.ScoredMember, 0, len(pairs)/2)
+
+	for j := 0; j < len(pairs); j += 2 {
+		score, err := datatypes.ParseScore(pairs[j])
+
+		if err != nil {
+			return parser.SerializeSimpleError(errNotFloat)
+		}
+
+		members = append(members, datatypes.ScoredMember{Member: pairs[j+1], Score: score})
+	}
+
+	if options.Incr {
+		score, ok, err := kvStore.ZIncrBy(cmds[1], members[0].Member, members[0].Score, options)
+
+		if err != nil {
+			return parser.SerializeSimpleError(err.Error())
+		}
+
+		if !ok {
+			return parser.SerializeNullBulkString()
+		}
+
+		propagate(cmds, kvStore, cfg)
+
+		return parser.SerializeBulkString(datatypes.FormatScore(score))
+	}
+
+	added, updated, err := kvStore.ZAdd(cmds[1], members, options)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if added+updated > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	if ch {
+		return parser.SerializeInteger(added + updated)
+	}
+
+	return parser.SerializeInteger(added)
+}
+
+func handleZIncrByCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	increment, err := datatypes.ParseScore(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotFloat)
+	}
+
+	score, _, err := kvStore.ZIncrBy(cmds[1], cmds[3], increment, datatypes.ZAddOptions{})
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeBulkString(datatypes.FormatScore(score))
+}
+
+func handleZRemCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	removed, err := kvStore.ZRem(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if removed > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeInteger(removed)
+}
+
+// ZSCORE key member replies with a single score, ZMSCORE key member [member ...] with an array
+func handleZScoreCommand(cmds []string, kvStore *store.Store) []byte {
+	multi := strings.ToUpper(cmds[0]) == ZMSCORE
+
+	if (multi && len(cmds) < 3) || (!multi && len(cmds) != 3) {
+		return wrongArgsError(cmds[0])
+	}
+
+	scores, found, err := kvStore.ZMScore(cmds[1], cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	result := make([][]byte, len(scores))
+
+	for i, score := range scores {
+		if found[i] {
+			result[i] = parser.SerializeBulkString(datatypes.FormatScore(score))
+		} else {
+			result[i] = parser.SerializeNullBulkString()
+		}
+	}
+
+	if !multi {
+		return result[0]
+	}
+
+	return parser.SerializeRawArray(result)
+}
+
+func handleZCardCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	length, err := kvStore.ZCard(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+func handleZCountCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	r, err := parseScoreRange(cmds[2], cmds[3])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	count, err := kvStore.ZCount(cmds[1], r)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(count)
+}
+
+// ZRANK and ZREVRANK key member [WITHSCORE]
+func handleZRankCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 3 && len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	withScore := len(cmds) == 4
+
+	if withScore && strings.ToUpper(cmds[3]) != "WITHSCORE" {
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	rank, score, ok, err := kvStore.ZRank(cmds[1], cmds[2], strings.ToUpper(cmds[0]) == ZREVRANK)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !withScore {
+		if !ok {
+			return parser.SerializeNullBulkString()
+		}
+
+		return parser.SerializeInteger(rank)
+	}
+
+	if !ok {
+		return parser.SerializeNullArray()
+	}
+
+	return parser.SerializeRawArray([][]byte{
+		parser.SerializeInteger(rank),
+		parser.SerializeBulkString(datatypes.FormatScore(score)),
+	})
+}
+
+// ZRANGE key start stop [BYSCORE | BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func handleZRangeCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	query, withScores, err := parseZRangeQuery(cmds[2:], true)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	members, err := kvStore.ZRange(cmds[1], query)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return serializeScoredMembers(members, withScores)
+}
+
+// ZRANGESTORE dst src min max [BYSCORE | BYLEX] [REV] [LIMIT offset count]
+func handleZRangeStoreCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 5 {
+		return wrongArgsError(cmds[0])
+	}
+
+	query, _, err := parseZRangeQuery(cmds[3:], false)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	length, err := kvStore.ZRangeStore(cmds[1], cmds[2], query)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(length)
+}
+
+// ZPOPMIN and ZPOPMAX key [count]
+func handleZPopCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 2 && len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	count := 1
+
+	if len(cmds) == 3 {
+		var err error
+		count, err = strconv.Atoi(cmds[2])
+
+		if err != nil || count < 0 {
+			return parser.SerializeSimpleError("ERR value is out of range, must be positive")
+		}
+	}
+
+	members, err := kvStore.ZPop(cmds[1], count, strings.ToUpper(cmds[0]) == ZPOPMAX)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if len(members) > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return serializeScoredMembers(members, true)
+}
+
+// ZSCAN key cursor [MATCH pattern] [COUNT count]
+func handleZScanCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	cursor, pattern, count, err := parseScanArgs(cmds[2:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	next, members, err := kvStore.ZScan(cmds[1], cursor, count, pattern)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	pairs := make([]string, 0, len(members)*2)
+
+	for _, member := range members {
+		pairs = append(pairs, member.Member, datatypes.FormatScore(member.Score))
+	}
+
+	return serializeScanReply(next, pairs)
+}
+
+// ZUNION, ZINTER and ZDIFF numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM | MIN | MAX] [WITHSCORES]
+func handleZSetOperationCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	op := strings.TrimPrefix(strings.ToUpper(cmds[0]), "Z")
+
+	keys, weights, aggregate, withScores, err := parseZSetOperationArgs(cmds[0], op, cmds[1:], true)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	members, err := kvStore.ZSetOperation(op, keys, weights, aggregate)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return serializeScoredMembers(members, withScores)
+}
+
+// ZUNIONSTORE, ZINTERSTORE and ZDIFFSTORE destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM | MIN | MAX]
+func handleZSetOperationStoreCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	op := strings.TrimSuffix(strings.TrimPrefix(strings.ToUpper(cmds[0]), "Z"), "STORE")
+
+	keys, weights, aggregate, _, err := parseZSetOperationArgs(cmds[0], op, cmds[2:], false)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	length, err := kvStore.ZSetOperationStore(op, cmds[1], keys, weights, aggregate)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(length)
+}
+
+// ZINTERCARD numkeys key [key ...] [LIMIT limit]
+func handleZInterCardCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	keys, limit, errReply := parseInterCardArgs(cmds[1:])
+
+	if errReply != nil {
+		return errReply
+	}
+
+	length, err := kvStore.ZInterCard(keys, limit)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+// BZPOPMIN and BZPOPMAX key [key ...] timeout
+func handleBZPopCommand(ctx context.Context, cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	ctx, cancel, err := blockingContext(ctx, cmds[len(cmds)-1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	defer cancel()
+
+	max := strings.ToUpper(cmds[0]) == BZPOPMAX
+
+	result, err := kvStore.BZPop(ctx, cmds[1:len(cmds)-1], 1, max)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if result == nil {
+		return parser.SerializeNullArray()
+	}
+
+	// replicas must not block, so they get the pop that actually happened
+	if max {
+		propagate([]string{ZPOPMAX, result[0]}, kvStore, cfg)
+	} else {
+		propagate([]string{ZPOPMIN, result[0]}, kvStore, cfg)
+	}
+
+	return parser.SerializeArray(result)
+}
+
+// ZMPOP numkeys key [key ...] MIN|MAX [COUNT count]
+// BZMPOP timeout numkeys key [key ...] MIN|MAX [COUNT count]
+func handleZMPopCommand(ctx context.Context, cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	blocking := strings.ToUpper(cmds[0]) == BZMPOP
+	args := cmds[1:]
+
+	if blocking {
+		if len(args) == 0 {
+			return wrongArgsError(cmds[0])
+		}
+		args = args[1:]
+	}
+
+	if len(args) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	numKeys, err := strconv.Atoi(args[0])
+
+	if err != nil || numKeys <= 0 {
+		return parser.SerializeSimpleError("ERR numkeys should be greater than 0")
+	}
+
+	if numKeys > len(args)-2 {
+		return parser.SerializeSimpleError("ERR Number of keys can't be greater than number of args")
+	}
+
+	keys := args[1 : numKeys+1]
+
+	var max bool
+
+	switch strings.ToUpper(args[numKeys+1]) {
+	case MIN:
+		max = false
+	case MAX:
+		max = true
+	default:
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	count := 1
+	options := args[numKeys+2:]
+
+	if len(options) != 0 {
+		if len(options) != 2 || strings.ToUpper(options[0]) != "COUNT" {
+			return parser.SerializeSimpleError(errSyntax)
+		}
+
+		count, err = strconv.Atoi(options[1])
+
+		if err != nil || count <= 0 {
+			return parser.SerializeSimpleError("ERR count should be greater than 0")
+		}
+	}
+
+	var result []string
+
+	if blocking {
+		var cancel context.CancelFunc
+		ctx, cancel, err = blockingContext(ctx, cmds[1])
+
+		if err != nil {
+			return parser.SerializeSimpleError(err.Error())
+		}
+
+		defer cancel()
+
+		result, err = kvStore.BZPop(ctx, keys, count, max)
+	} else {
+		result, err = kvStore.ZMPop(keys, count, max)
+	}
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if result == nil {
+		return parser.SerializeNullArray()
+	}
+
+	popCommand := ZPOPMIN
+	if max {
+		popCommand = ZPOPMAX
+	}
+
+	pairs := result[1:]
+
+	propagate([]string{popCommand, result[0], strconv.Itoa(len(pairs) / 2)}, kvStore, cfg)
+
+	members := make([][]byte, 0, len(pairs)/2)
+
+	for i := 0; i < len(pairs); i += 2 {
+		members = append(members, parser.SerializeArray(pairs[i:i+2]))
+	}
+
+	return parser.SerializeRawArray([][]byte{
+		parser.SerializeBulkString(result[0]),
+		parser.SerializeRawArray(members),
+	})
+}
+
+// parses numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM | MIN | MAX] [WITHSCORES].
+// ZDIFF takes neither WEIGHTS nor AGGREGATE, and WITHSCORES is only accepted with withScoresAllowed.
+func parseZSetOperationArgs(command, op string, args []string, withScoresAllowed bool) (keys []string, weights []float64, aggregate string, withScores bool, err error) {
+	numKeys, err := strconv.Atoi(args[0])
+
+	if err != nil {
+		return nil, nil, "", false, errors.New(errNotInteger)
+	}
+
+	if numKeys < 1 {
+		return nil, nil, "", false, fmt.Errorf("ERR at least 1 input key is needed for '%s' command", strings.ToLower(command))
+	}
+
+	if numKeys > len(args)-1 {
+		return nil, nil, "", false, errors.New(errSyntax)
+	}
+
+	keys = args[1 : numKeys+1]
+	aggregate = store.AggregateSum
+	weights = make([]float64, numKeys)
+
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	options := args[numKeys+1:]
+
+	for i := 0; i < len(options); i++ {
+		option := strings.ToUpper(options[i])
+
+		switch {
+		case option == "WEIGHTS" && op != store.SetDiff && i+numKeys < len(options):
+			for j := range weights {
+				weights[j], err = datatypes.ParseScore(options[i+1+j])
+
+				if err != nil {
+					return nil, nil, "", false, errors.New("ERR weight value is not a float")
+				}
+			}
+
+			i += numKeys
+		case option == "AGGREGATE" && op != store.SetDiff && i+1 < len(options):
+			aggregate = strings.ToUpper(options[i+1])
+
+			if aggregate != store.AggregateSum && aggregate != store.AggregateMin && aggregate != store.AggregateMax {
+				return nil, nil, "", false, errors.New(errSyntax)
+			}
+
+			i++
+		case option == "WITHSCORES" && withScoresAllowed:
+			withScores = true
+		default:
+			return nil, nil, "", false, errors.New(errSyntax)
+		}
+	}
+
+	return keys, weights, aggregate, withScores, nil
+}
+
+// parses start stop [BYSCORE | BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func parseZRangeQuery(args []string, withScoresAllowed bool) (store.ZRangeQuery, bool, error) {
+	query := store.ZRangeQuery{By: store.ZRangeByRank, Count: -1}
+	withScores := false
+	hasLimit := false
+
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BYSCORE":
+			query.By = store.ZRangeByScore
+		case "BYLEX":
+			query.By = store.ZRangeByLex
+		case "REV":
+			query.Reverse = true
+		case "WITHSCORES":
+			if !withScoresAllowed {
+				return query, false, errors.New(errSyntax)
+			}
+			withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return query, false, errors.New(errSyntax)
+			}
+
+			offset, err1 := strconv.Atoi(args[i+1])
+			count, err2 := strconv.Atoi(args[i+2])
+
+			if err1 != nil || err2 != nil {
+				return query, false, errors.New(errNotInteger)
+			}
+
+			query.Offset, query.Count = offset, count
+			hasLimit = true
+			i += 2
+		default:
+			return query, false, errors.New(errSyntax)
+		}
+	}
+
+	if hasLimit && query.By == store.ZRangeByRank {
+		return query, false, errors.New("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+
+	if withScores && query.By == store.ZRangeByLex {
+		return query, false, errors.New("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+
+	// a negative offset always returns an empty range
+	if query.Offset < 0 {
+		query.Count = 0
+	}
+
+	// with REV the range is given from the highest to the lowest bound
+	min, max := args[0], args[1]
+
+	if query.Reverse {
+		min, max = max, min
+	}
+
+	var err error
+
+	switch query.By {
+	case store.ZRangeByScore:
+		query.Score, err = parseScoreRange(min, max)
+	case store.ZRangeByLex:
+		query.Lex, err = parseLexRange(min, max)
+	default:
+		start, err1 := strconv.Atoi(args[0])
+		stop, err2 := strconv.Atoi(args[1])
+
+		if err1 != nil || err2 != nil {
+			err = errors.New(errNotInteger)
+		}
+
+		query.Start, query.Stop = start, stop
+	}
+
+	return query, withScores, err
+}
+
+func parseScoreRange(min, max string) (datatypes.ScoreRange, error) {
+	var r datatypes.ScoreRange
+	var err error
+
+	r.Min, r.MinExclusive, err = datatypes.ParseScoreBound(min)
+
+	if err != nil {
+		return r, err
+	}
+
+	r.Max, r.MaxExclusive, err = datatypes.ParseScoreBound(max)
+
+	return r, err
+}
+
+func parseLexRange(min, max string) (datatypes.LexRange, error) {
+	var r datatypes.LexRange
+	var err error
+
+	r.Min, err = datatypes.ParseLexBound(min)
+
+	if err != nil {
+		return r, err
+	}
+
+	r.Max, err = datatypes.ParseLexBound(max)
+
+	return r, err
+}
+
+// replies with the members, flattened as member1, score1, member2, ... with withScores
+func serializeScoredMembers(members []datatypes.ScoredMember, withScores bool) []byte {
+	values := make([]string, 0, len(members)*2)
+
+	for _, member := range members {
+		values = append(values, member.Member)
+
+		if withScores {
+			values = append(values, datatypes.FormatScore(member.Score))
+		}
+	}
+
+	return parser.SerializeArray(values)
+}
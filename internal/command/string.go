@@ -0,0 +1,581 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+const (
+	GETEX       = "GETEX"
+	GETDEL      = "GETDEL"
+	GETSET      = "GETSET"
+	SETNX       = "SETNX"
+	SETEX       = "SETEX"
+	PSETEX      = "PSETEX"
+	MSET        = "MSET"
+	MSETNX      = "MSETNX"
+	MGET        = "MGET"
+	APPEND      = "APPEND"
+	STRLEN      = "STRLEN"
+	GETRANGE    = "GETRANGE"
+	SUBSTR      = "SUBSTR"
+	SETRANGE    = "SETRANGE"
+	LCS         = "LCS"
+	INCR        = "INCR"
+	DECR        = "DECR"
+	INCRBY      = "INCRBY"
+	DECRBY      = "DECRBY"
+	INCRBYFLOAT = "INCRBYFLOAT"
+)
+
+func handleGetCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	value, ok, err := kvStore.Get(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	return parser.SerializeBulkString(value)
+}
+
+// SET key value [NX | XX] [GET] [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
+func handleSetCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	options, err := parseSetOptions(cmds[0], cmds[3:])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	previous, existed, ok, err := kvStore.SetWithOptions(cmds[1], cmds[2], options)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if ok {
+		propagate(setPropagation(cmds[1], cmds[2], options), kvStore, cfg)
+	}
+
+	if options.Get {
+		if !existed {
+			return parser.SerializeNullBulkString()
+		}
+
+		return parser.SerializeBulkString(previous)
+	}
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	return parser.SerializeSimpleString(OK)
+}
+
+// GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | PERSIST]
+func handleGetExCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	var expiry time.Time
+	var err error
+
+	options := cmds[2:]
+	persist := len(options) == 1 && strings.ToUpper(options[0]) == "PERSIST"
+
+	switch {
+	case len(options) == 0 || persist:
+	case len(options) == 2 && isExpiryOption(options[0]):
+		expiry, err = parseExpiry(cmds[0], strings.ToUpper(options[0]), options[1])
+
+		if err != nil {
+			return parser.SerializeSimpleError(err.Error())
+		}
+	default:
+		return parser.SerializeSimpleError(errSyntax)
+	}
+
+	value, ok, err := kvStore.GetEx(cmds[1], expiry, persist)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	if persist {
+		propagate(cmds, kvStore, cfg)
+	} else if !expiry.IsZero() {
+		propagate([]string{GETEX, cmds[1], "PXAT", strconv.FormatInt(expiry.UnixMilli(), 10)}, kvStore, cfg)
+	}
+
+	return parser.SerializeBulkString(value)
+}
+
+func handleGetDelCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	value, ok, err := kvStore.GetDel(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !ok {
+		return parser.SerializeNullBulkString()
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeBulkString(value)
+}
+
+// GETSET key value, the same as SET key value GET
+func handleGetSetCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	previous, existed, _, err := kvStore.SetWithOptions(cmds[1], cmds[2], store.SetOptions{Get: true})
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate([]string{SET, cmds[1], cmds[2]}, kvStore, cfg)
+
+	if !existed {
+		return parser.SerializeNullBulkString()
+	}
+
+	return parser.SerializeBulkString(previous)
+}
+
+func handleSetNXCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	_, _, ok, err := kvStore.SetWithOptions(cmds[1], cmds[2], store.SetOptions{Condition: store.SetIfNotExists})
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if !ok {
+		return parser.SerializeInteger(0)
+	}
+
+	propagate([]string{SET, cmds[1], cmds[2]}, kvStore, cfg)
+
+	return parser.SerializeInteger(1)
+}
+
+// SETEX key seconds value and PSETEX key milliseconds value
+func handleSetExCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	unit := "EX"
+	if strings.ToUpper(cmds[0]) == PSETEX {
+		unit = PX
+	}
+
+	expiry, err := parseExpiry(cmds[0], unit, cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	options := store.SetOptions{Expiry: expiry}
+
+	if _, _, _, err := kvStore.SetWithOptions(cmds[1], cmds[3], options); err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(setPropagation(cmds[1], cmds[3], options), kvStore, cfg)
+
+	return parser.SerializeSimpleString(OK)
+}
+
+// MSET and MSETNX key value [key value ...]
+func handleMSetCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) < 3 || len(cmds)%2 != 1 {
+		return wrongArgsError(cmds[0])
+	}
+
+	onlyIfNoneExist := strings.ToUpper(cmds[0]) == MSETNX
+
+	if !kvStore.MSet(cmds[1:], onlyIfNoneExist) {
+		return parser.SerializeInteger(0)
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	if onlyIfNoneExist {
+		return parser.SerializeInteger(1)
+	}
+
+	return parser.SerializeSimpleString(OK)
+}
+
+func handleMGetCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	values, found := kvStore.MGet(cmds[1:])
+	result := make([][]byte, len(values))
+
+	for i, value := range values {
+		if found[i] {
+			result[i] = parser.SerializeBulkString(value)
+		} else {
+			result[i] = parser.SerializeNullBulkString()
+		}
+	}
+
+	return parser.SerializeRawArray(result)
+}
+
+func handleAppendCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	length, err := kvStore.Append(cmds[1], cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(length)
+}
+
+func handleStrLenCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 2 {
+		return wrongArgsError(cmds[0])
+	}
+
+	length, err := kvStore.StrLen(cmds[1])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+// GETRANGE key start end, SUBSTR being its old name
+func handleGetRangeCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	start, err1 := strconv.Atoi(cmds[2])
+	end, err2 := strconv.Atoi(cmds[3])
+
+	if err1 != nil || err2 != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	value, err := kvStore.GetRange(cmds[1], start, end)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	return parser.SerializeBulkString(value)
+}
+
+func handleSetRangeCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 4 {
+		return wrongArgsError(cmds[0])
+	}
+
+	offset, err := strconv.Atoi(cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(errNotInteger)
+	}
+
+	if offset < 0 {
+		return parser.SerializeSimpleError("ERR offset is out of range")
+	}
+
+	length, err := kvStore.SetRange(cmds[1], offset, cmds[3])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if len(cmds[3]) > 0 {
+		propagate(cmds, kvStore, cfg)
+	}
+
+	return parser.SerializeInteger(length)
+}
+
+// LCS key1 key2 [LEN] [IDX] [MINMATCHLEN min-match-len] [WITHMATCHLEN]
+func handleLCSCommand(cmds []string, kvStore *store.Store) []byte {
+	if len(cmds) < 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	var withLen, withIdx, withMatchLen bool
+	minMatchLen := 0
+
+	for i := 3; i < len(cmds); i++ {
+		switch strings.ToUpper(cmds[i]) {
+		case "LEN":
+			withLen = true
+		case "IDX":
+			withIdx = true
+		case "WITHMATCHLEN":
+			withMatchLen = true
+		case "MINMATCHLEN":
+			if i+1 == len(cmds) {
+				return parser.SerializeSimpleError(errSyntax)
+			}
+
+			var err error
+			minMatchLen, err = strconv.Atoi(cmds[i+1])
+
+			if err != nil {
+				return parser.SerializeSimpleError(errNotInteger)
+			}
+
+			i++
+		default:
+			return parser.SerializeSimpleError(errSyntax)
+		}
+	}
+
+	if withLen && withIdx {
+		return parser.SerializeSimpleError("ERR If you want both the length and indexes, please just use IDX.")
+	}
+
+	lcs, matches, err := kvStore.LCS(cmds[1], cmds[2])
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	if withLen {
+		return parser.SerializeInteger(len(lcs))
+	}
+
+	if !withIdx {
+		return parser.SerializeBulkString(lcs)
+	}
+
+	ranges := [][]byte{}
+
+	for _, match := range matches {
+		if match.Len < minMatchLen {
+			continue
+		}
+
+		elements := [][]byte{
+			serializeIntegers(match.A[:]),
+			serializeIntegers(match.B[:]),
+		}
+
+		if withMatchLen {
+			elements = append(elements, parser.SerializeInteger(match.Len))
+		}
+
+		ranges = append(ranges, parser.SerializeRawArray(elements))
+	}
+
+	return parser.SerializeRawArray([][]byte{
+		parser.SerializeBulkString("matches"),
+		parser.SerializeRawArray(ranges),
+		parser.SerializeBulkString("len"),
+		parser.SerializeInteger(len(lcs)),
+	})
+}
+
+// INCR and DECR key, INCRBY and DECRBY key increment
+func handleIncrCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	command := strings.ToUpper(cmds[0])
+	withIncrement := command == INCRBY || command == DECRBY
+
+	if (withIncrement && len(cmds) != 3) || (!withIncrement && len(cmds) != 2) {
+		return wrongArgsError(cmds[0])
+	}
+
+	increment := int64(1)
+
+	if withIncrement {
+		var err error
+		increment, err = strconv.ParseInt(cmds[2], 10, 64)
+
+		if err != nil {
+			return parser.SerializeSimpleError(errNotInteger)
+		}
+	}
+
+	if command == DECR || command == DECRBY {
+		if increment == math.MinInt64 {
+			return parser.SerializeSimpleError("ERR decrement would overflow")
+		}
+
+		increment = -increment
+	}
+
+	value, err := kvStore.IncrBy(cmds[1], increment)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	propagate(cmds, kvStore, cfg)
+
+	return parser.SerializeInteger(int(value))
+}
+
+func handleIncrByFloatCommand(cmds []string, kvStore *store.Store, cfg *config.ServerConfig) []byte {
+	if len(cmds) != 3 {
+		return wrongArgsError(cmds[0])
+	}
+
+	increment, err := strconv.ParseFloat(cmds[2], 64)
+
+	if err != nil || math.IsNaN(increment) {
+		return parser.SerializeSimpleError(errNotFloat)
+	}
+
+	value, err := kvStore.IncrByFloat(cmds[1], increment)
+
+	if err != nil {
+		return parser.SerializeSimpleError(err.Error())
+	}
+
+	// replicas get the resulting value so float formatting can never make them drift
+	propagate([]string{SET, cmds[1], value, "KEEPTTL"}, kvStore, cfg)
+
+	return parser.SerializeBulkString(value)
+}
+
+// parses the options following SET key value, conflicting options are a syntax error
+func parseSetOptions(command string, args []string) (store.SetOptions, error) {
+	var options store.SetOptions
+	hasExpiry := false
+
+	for i := 0; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+
+		switch {
+		case option == "NX" && options.Condition != store.SetIfExists:
+			options.Condition = store.SetIfNotExists
+		case option == "XX" && options.Condition != store.SetIfNotExists:
+			options.Condition = store.SetIfExists
+		case option == GET:
+			options.Get = true
+		case option == "KEEPTTL" && !hasExpiry:
+			options.KeepTTL = true
+		case isExpiryOption(option) && !hasExpiry && !options.KeepTTL && i+1 < len(args):
+			expiry, err := parseExpiry(command, option, args[i+1])
+
+			if err != nil {
+				return options, err
+			}
+
+			options.Expiry = expiry
+			hasExpiry = true
+			i++
+		default:
+			return options, errors.New(errSyntax)
+		}
+	}
+
+	return options, nil
+}
+
+func isExpiryOption(option string) bool {
+	switch strings.ToUpper(option) {
+	case "EX", PX, "EXAT", "PXAT":
+		return true
+	default:
+		return false
+	}
+}
+
+// converts the value of an EX, PX, EXAT or PXAT option to the time the key expires at
+func parseExpiry(command, unit, value string) (time.Time, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil {
+		return time.Time{}, errors.New(errNotInteger)
+	}
+
+	invalid := fmt.Errorf("ERR invalid expire time in '%s' command", strings.ToLower(command))
+
+	if n <= 0 {
+		return time.Time{}, invalid
+	}
+
+	ms := n
+
+	if unit == "EX" || unit == "EXAT" {
+		if n > math.MaxInt64/1000 {
+			return time.Time{}, invalid
+		}
+
+		ms = n * 1000
+	}
+
+	if unit == "EX" || unit == PX {
+		now := time.Now().UnixMilli()
+
+		if ms > math.MaxInt64-now {
+			return time.Time{}, invalid
+		}
+
+		ms += now
+	}
+
+	return time.UnixMilli(ms), nil
+}
+
+// the SET replicas get: NX, XX and GET only matter on the master, and the expiry is
+// sent as an absolute time so replicas expire the key at the same moment
+func setPropagation(key, value string, options store.SetOptions) []string {
+	cmds := []string{SET, key, value}
+
+	if !options.Expiry.IsZero() {
+		cmds = append(cmds, "PXAT", strconv.FormatInt(options.Expiry.UnixMilli(), 10))
+	} else if options.KeepTTL {
+		cmds = append(cmds, "KEEPTTL")
+	}
+
+	return cmds
+}
@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"sync"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
 )
 
 const (
@@ -13,6 +17,9 @@ const (
 	RoleSlave  = "slave"
 )
 
+// the most databases the server starts with, each one costing memory up front
+const maxDatabases = 1024
+
 type Replica struct {
 	ConnAddr       net.Conn
 	Offset         int
@@ -31,6 +38,16 @@ type ServerConfig struct {
 	Replicas                      []*Replica
 	ReplicaWriteQueue             chan []string
 	HandeshakeCompletedWithMaster bool
+	ReplicationDB                 int // the database the replicas last had selected, -1 forces a SELECT
+	Databases                     int
+	Hz                            int
+	ActiveExpireEffort            int
+	MaxMemory                     int64 // bytes, 0 for no limit
+	MaxMemoryPolicy               string
+	MaxMemorySamples              int
+	LFULogFactor                  int
+	LFUDecayTime                  int
+	EncodingThresholds            datatypes.EncodingThresholds
 	sync.RWMutex
 }
 
@@ -40,6 +57,9 @@ func (c *ServerConfig) AddReplica(conn net.Conn) {
 	c.Replicas = append(c.Replicas, &Replica{
 		ConnAddr: conn,
 	})
+
+	// the new replica starts on db 0, whatever the others have selected
+	c.ReplicationDB = -1
 }
 
 func (c *ServerConfig) GetRDBFilePath() string {
@@ -57,6 +77,27 @@ func New() *ServerConfig {
 	rdbFileDir := flag.String("dir", "", "Directory to store RDB file")
 	rdbFileName := flag.String("dbfilename", "", "Name of RDB file")
 
+	databases := flag.Int("databases", 16, "Number of databases")
+
+	hz := flag.Int("hz", 10, "Frequency of the background tasks, between 1 and 500")
+	activeExpireEffort := flag.Int("active-expire-effort", 1, "Effort of the active expire cycle, between 1 and 10")
+
+	maxMemory := flag.String("maxmemory", "0", "Memory limit for the data set, like 100mb, 0 for no limit")
+	maxMemoryPolicy := flag.String("maxmemory-policy", store.NoEviction, "How keys are evicted once maxmemory is reached")
+	maxMemorySamples := flag.Int("maxmemory-samples", 5, "Keys sampled by the lru, lfu and ttl eviction policies")
+	lfuLogFactor := flag.Int("lfu-log-factor", 10, "How slowly the lfu counters grow with accesses")
+	lfuDecayTime := flag.Int("lfu-decay-time", 1, "Minutes after which an idle key has its lfu counter decremented")
+
+	thresholds := datatypes.DefaultEncodingThresholds
+	listMaxListpackSize := flag.Int("list-max-listpack-size", thresholds.ListMaxListpackSize, "Entries of the listpack encoded lists, or -1 to -5 for 4kb to 64kb")
+	hashMaxListpackEntries := flag.Int("hash-max-listpack-entries", thresholds.HashMaxListpackEntries, "Fields of the listpack encoded hashes")
+	hashMaxListpackValue := flag.Int("hash-max-listpack-value", thresholds.HashMaxListpackValue, "Longest field or value of the listpack encoded hashes")
+	setMaxIntsetEntries := flag.Int("set-max-intset-entries", thresholds.SetMaxIntsetEntries, "Members of the intset encoded sets")
+	setMaxListpackEntries := flag.Int("set-max-listpack-entries", thresholds.SetMaxListpackEntries, "Members of the listpack encoded sets")
+	setMaxListpackValue := flag.Int("set-max-listpack-value", thresholds.SetMaxListpackValue, "Longest member of the listpack encoded sets")
+	zsetMaxListpackEntries := flag.Int("zset-max-listpack-entries", thresholds.ZSetMaxListpackEntries, "Members of the listpack encoded sorted sets")
+	zsetMaxListpackValue := flag.Int("zset-max-listpack-value", thresholds.ZSetMaxListpackValue, "Longest member of the listpack encoded sorted sets")
+
 	flag.Parse()
 
 	masterPort := getMasterPort(masterHost)
@@ -68,18 +109,50 @@ func New() *ServerConfig {
 
 	}
 
-	return &ServerConfig{
-		Role:              role,
-		Port:              *port,
-		RDBDir:            *rdbFileDir,
-		RDBFileName:       *rdbFileName,
-		MasterHost:        *masterHost,
-		MasterPort:        masterPort,
-		MasterReplid:      "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb",
-		MasterReplOffset:  0,
-		Replicas:          make([]*Replica, 0),
-		ReplicaWriteQueue: make(chan []string, 100),
+	cfg := &ServerConfig{
+		Role:               role,
+		Port:               *port,
+		RDBDir:             *rdbFileDir,
+		RDBFileName:        *rdbFileName,
+		MasterHost:         *masterHost,
+		MasterPort:         masterPort,
+		MasterReplid:       "8371b4fb1155b71f4a04d3e1bc3e18c4a990aeeb",
+		MasterReplOffset:   0,
+		Replicas:           make([]*Replica, 0),
+		ReplicaWriteQueue:  make(chan []string, 100),
+		ReplicationDB:      -1,
+		Databases:          clamp(*databases, 1, maxDatabases),
+		Hz:                 clamp(*hz, 1, 500),
+		ActiveExpireEffort: clamp(*activeExpireEffort, 1, 10),
+		EncodingThresholds: thresholds,
 	}
+
+	// the same checks as CONFIG SET
+	settings := map[string]string{
+		"maxmemory":         *maxMemory,
+		"maxmemory-policy":  *maxMemoryPolicy,
+		"maxmemory-samples": strconv.Itoa(*maxMemorySamples),
+		"lfu-log-factor":    strconv.Itoa(*lfuLogFactor),
+		"lfu-decay-time":    strconv.Itoa(*lfuDecayTime),
+
+		"list-max-listpack-size":    strconv.Itoa(*listMaxListpackSize),
+		"hash-max-listpack-entries": strconv.Itoa(*hashMaxListpackEntries),
+		"hash-max-listpack-value":   strconv.Itoa(*hashMaxListpackValue),
+		"set-max-intset-entries":    strconv.Itoa(*setMaxIntsetEntries),
+		"set-max-listpack-entries":  strconv.Itoa(*setMaxListpackEntries),
+		"set-max-listpack-value":    strconv.Itoa(*setMaxListpackValue),
+		"zset-max-listpack-entries": strconv.Itoa(*zsetMaxListpackEntries),
+		"zset-max-listpack-value":   strconv.Itoa(*zsetMaxListpackValue),
+	}
+
+	for name, value := range settings {
+		if err := cfg.SetParameter(name, value); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	return cfg
 }
 
 func getMasterPort(masterHost *string) string {
@@ -96,3 +169,15 @@ func getMasterPort(masterHost *string) string {
 	}
 	return ""
 }
+
+func clamp(n, low, high int) int {
+	if n < low {
+		return low
+	}
+
+	if n > high {
+		return high
+	}
+
+	return n
+}
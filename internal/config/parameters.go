@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// a setting exposed through CONFIG GET, and CONFIG SET unless set is nil
+type parameter struct {
+	get func(c *ServerConfig) string
+	set func(c *ServerConfig, value string) error
+}
+
+var parameters = map[string]parameter{
+	"dir": {
+		get: func(c *ServerConfig) string { return c.RDBDir },
+	},
+	"dbfilename": {
+		get: func(c *ServerConfig) string { return c.RDBFileName },
+	},
+	"databases": {
+		get: func(c *ServerConfig) string { return strconv.Itoa(c.Databases) },
+	},
+	"hz": {
+		get: func(c *ServerConfig) string { return strconv.Itoa(c.Hz) },
+	},
+	"active-expire-effort": {
+		get: func(c *ServerConfig) string { return strconv.Itoa(c.ActiveExpireEffort) },
+	},
+	"maxmemory": {
+		get: func(c *ServerConfig) string { return strconv.FormatInt(c.MaxMemory, 10) },
+		set: func(c *ServerConfig, value string) error {
+			maxMemory, err := ParseMemory(value)
+
+			if err != nil {
+				return err
+			}
+
+			c.MaxMemory = maxMemory
+			return nil
+		},
+	},
+	"maxmemory-policy": {
+		get: func(c *ServerConfig) string { return c.MaxMemoryPolicy },
+		set: func(c *ServerConfig, value string) error {
+			policy := strings.ToLower(value)
+
+			if !store.ValidEvictionPolicy(policy) {
+				return errors.New("argument(s) must be one of the following: " + strings.Join(maxMemoryPolicies, ", "))
+			}
+
+			c.MaxMemoryPolicy = policy
+			return nil
+		},
+	},
+	"maxmemory-samples": {
+		get: func(c *ServerConfig) string { return strconv.Itoa(c.MaxMemorySamples) },
+		set: func(c *ServerConfig, value string) error {
+			return setInt(&c.MaxMemorySamples, value, 1, 64)
+		},
+	},
+	"lfu-log-factor": {
+		get: func(c *ServerConfig) string { return strconv.Itoa(c.LFULogFactor) },
+		set: func(c *ServerConfig, value string) error {
+			if err := setInt(&c.LFULogFactor, value, 0, 1<<31-1); err != nil {
+				return err
+			}
+
+			store.SetLFUParams(c.LFULogFactor, c.LFUDecayTime)
+			return nil
+		},
+	},
+	"lfu-decay-time": {
+		get: func(c *ServerConfig) string { return strconv.Itoa(c.LFUDecayTime) },
+		set: func(c *ServerConfig, value string) error {
+			if err := setInt(&c.LFUDecayTime, value, 0, 1<<31-1); err != nil {
+				return err
+			}
+
+			store.SetLFUParams(c.LFULogFactor, c.LFUDecayTime)
+			return nil
+		},
+	},
+	"list-max-listpack-size": encodingThreshold(func(t *datatypes.EncodingThresholds) *int {
+		return &t.ListMaxListpackSize
+	}, -1<<31),
+	"hash-max-listpack-entries": encodingThreshold(func(t *datatypes.EncodingThresholds) *int {
+		return &t.HashMaxListpackEntries
+	}, 0),
+	"hash-max-listpack-value": encodingThreshold(func(t *datatypes.EncodingThresholds) *int {
+		return &t.HashMaxListpackValue
+	}, 0),
+	"set-max-intset-entries": encodingThreshold(func(t *datatypes.EncodingThresholds) *int {
+		return &t.SetMaxIntsetEntries
+	}, 0),
+	"set-max-listpack-entries": encodingThreshold(func(t *datatypes.EncodingThresholds) *int {
+		return &t.SetMaxListpackEntries
+	}, 0),
+	"set-max-listpack-value": encodingThreshold(func(t *datatypes.EncodingThresholds) *int {
+		return &t.SetMaxListpackValue
+	}, 0),
+	"zset-max-listpack-entries": encodingThreshold(func(t *datatypes.EncodingThresholds) *int {
+		return &t.ZSetMaxListpackEntries
+	}, 0),
+	"zset-max-listpack-value": encodingThreshold(func(t *datatypes.EncodingThresholds) *int {
+		return &t.ZSetMaxListpackValue
+	}, 0),
+}
+
+// a parameter for one of the encoding thresholds, which field picks, no lower than low
+func encodingThreshold(field func(t *datatypes.EncodingThresholds) *int, low int) parameter {
+	return parameter{
+		get: func(c *ServerConfig) string { return strconv.Itoa(*field(&c.EncodingThresholds)) },
+		set: func(c *ServerConfig, value string) error {
+			if err := setInt(field(&c.EncodingThresholds), value, low, 1<<31-1); err != nil {
+				return err
+			}
+
+			datatypes.SetEncodingThresholds(c.EncodingThresholds)
+			return nil
+		},
+	}
+}
+
+var maxMemoryPolicies = []string{
+	store.VolatileLRU, store.VolatileLFU, store.VolatileRandom, store.VolatileTTL,
+	store.AllKeysLRU, store.AllKeysLFU, store.AllKeysRandom, store.NoEviction,
+}
+
+// returns the names and values of the parameters matching pattern, flattened as
+// name1, value1, ...
+func (c *ServerConfig) GetParameters(pattern string) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	names := []string{}
+
+	for name := range parameters {
+		if glob.Match(strings.ToLower(pattern), name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names)*2)
+
+	for _, name := range names {
+		pairs = append(pairs, name, parameters[name].get(c))
+	}
+
+	return pairs
+}
+
+// sets the parameter called name, failing for unknown and read only parameters
+// and invalid values
+func (c *ServerConfig) SetParameter(name, value string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	p, ok := parameters[strings.ToLower(name)]
+
+	if !ok || p.set == nil {
+		return fmt.Errorf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", name)
+	}
+
+	if err := p.set(c, value); err != nil {
+		return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - %s", name, err.Error())
+	}
+
+	return nil
+}
+
+// returns maxmemory, maxmemory-policy and maxmemory-samples
+func (c *ServerConfig) MaxMemorySettings() (int64, string, int) {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.MaxMemory, c.MaxMemoryPolicy, c.MaxMemorySamples
+}
+
+// parses a memory amount like 100, 1k (1000 bytes), 1kb (1024 bytes), 10mb or 2gb
+func ParseMemory(value string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000}, {"b", 1},
+	}
+
+	value = strings.ToLower(value)
+	multiplier := int64(1)
+
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSuffix(value, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil || n < 0 || n > (1<<63-1)/multiplier {
+		return 0, errors.New("argument must be a memory value")
+	}
+
+	return n * multiplier, nil
+}
+
+func setInt(field *int, value string, low, high int) error {
+	n, err := strconv.Atoi(value)
+
+	if err != nil {
+		return errors.New("argument couldn't be parsed into an integer")
+	}
+
+	if n < low || n > high {
+		return fmt.Errorf("argument must be between %d and %d inclusive", low, high)
+	}
+
+	*field = n
+
+	return nil
+}
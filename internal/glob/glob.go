@@ -0,0 +1,114 @@
+package glob
+
+// Match reports whether str matches the redis style glob pattern. Supported are
+// '*', '?', character classes like [abc], [a-z] and [^x], and '\' to escape
+// any of the special characters.
+func Match(pattern, str string) bool {
+	p, s := 0, 0
+
+	for p < len(pattern) && s < len(str) {
+		switch pattern[p] {
+		case '*':
+			for p+1 < len(pattern) && pattern[p+1] == '*' {
+				p++
+			}
+
+			if p+1 == len(pattern) {
+				return true
+			}
+
+			for ; s < len(str); s++ {
+				if Match(pattern[p+1:], str[s:]) {
+					return true
+				}
+			}
+
+			return false
+
+		case '?':
+			s++
+
+		case '[':
+			p++
+			not := p < len(pattern) && pattern[p] == '^'
+
+			if not {
+				p++
+			}
+
+			matched := false
+
+			for {
+				if p >= len(pattern) {
+					// unterminated class, treat the end of the pattern as its end
+					p--
+					break
+				}
+
+				if pattern[p] == '\\' && p+1 < len(pattern) {
+					p++
+					if pattern[p] == str[s] {
+						matched = true
+					}
+				} else if pattern[p] == ']' {
+					break
+				} else if p+2 < len(pattern) && pattern[p+1] == '-' {
+					start, end := pattern[p], pattern[p+2]
+
+					if start > end {
+						start, end = end, start
+					}
+
+					if str[s] >= start && str[s] <= end {
+						matched = true
+					}
+
+					p += 2
+				} else if pattern[p] == str[s] {
+					matched = true
+				}
+
+				p++
+			}
+
+			if not {
+				matched = !matched
+			}
+
+			if !matched {
+				return false
+			}
+
+			s++
+
+		case '\\':
+			if p+1 < len(pattern) {
+				p++
+			}
+
+			if pattern[p] != str[s] {
+				return false
+			}
+
+			s++
+
+		default:
+			if pattern[p] != str[s] {
+				return false
+			}
+
+			s++
+		}
+
+		p++
+	}
+
+	// trailing stars also match the empty remainder of str
+	if s == len(str) {
+		for p < len(pattern) && pattern[p] == '*' {
+			p++
+		}
+	}
+
+	return p == len(pattern) && s == len(str)
+}
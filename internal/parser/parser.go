@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 )
@@ -94,12 +95,26 @@ func SerializeArray(input []string) []byte {
 }
 
 func SerializeBulkString(input string) []byte {
-	// null bulk string
-	if input == "" {
-		return []byte("$-1\r\n")
+	return []byte(fmt.Sprintf("$%d\r\n%s\r\n", len(input), input))
+}
+
+func SerializeNullBulkString() []byte {
+	return []byte("$-1\r\n")
+}
+
+func SerializeNullArray() []byte {
+	return []byte("*-1\r\n")
+}
+
+// wraps already serialized values into an array, used for nested and mixed type replies
+func SerializeRawArray(elements [][]byte) []byte {
+	buffer := []byte(fmt.Sprintf("*%d\r\n", len(elements)))
+
+	for _, element := range elements {
+		buffer = append(buffer, element...)
 	}
 
-	return []byte(fmt.Sprintf("$%d\r\n%s\r\n", len(input), input))
+	return buffer
 }
 
 func SerializeSimpleString(input string) []byte {
@@ -179,14 +194,25 @@ func parseBulkString(byteStream *bufio.Reader) (string, int, error) {
 		return "", bytesRead, err
 	}
 
-	data, n := readUntilCRLF(byteStream)
+	// null bulk string
+	if commandLength < 0 {
+		return "", bytesRead, nil
+	}
+
+	// the data is read by length as it may contain CRLF itself
+	data := make([]byte, commandLength+len(CRLF_INT))
+	n, err = io.ReadFull(byteStream, data)
 	bytesRead += n
 
-	if len(data) != commandLength {
+	if err != nil {
+		return "", bytesRead, err
+	}
+
+	if string(data[commandLength:]) != CRLF_INT {
 		return "", bytesRead, errors.New("length of data is not equal to the length specified")
 	}
 
-	return data, bytesRead, nil
+	return string(data[:commandLength]), bytesRead, nil
 }
 
 func parseInteger(byteStream *bufio.Reader) (int, int, error) {
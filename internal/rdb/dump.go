@@ -0,0 +1,84 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc64"
+	"io"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+)
+
+var (
+	errBadDumpPayload = errors.New("ERR DUMP payload version or checksum are wrong")
+	errBadDataFormat  = errors.New("ERR Bad data format")
+)
+
+// the reflected Jones polynomial of the CRC64 redis uses
+var crc64Table = crc64.MakeTable(0x95ac9329ac4bc9b5)
+
+// serializes value the way DUMP does: its RDB type and encoding, followed by the
+// RDB version and a CRC64 of everything before the checksum, little endian
+func Dump(value store.Data) []byte {
+	t := valueType(value)
+
+	var payload bytes.Buffer
+	writer := bufio.NewWriter(&payload)
+
+	writer.WriteByte(t)
+	writeValue(writer, t, value)
+	binary.Write(writer, binary.LittleEndian, uint16(rdbVersion))
+	writer.Flush()
+
+	binary.Write(&payload, binary.LittleEndian, checksum(payload.Bytes()))
+
+	return payload.Bytes()
+}
+
+// parses a payload made by Dump or by redis, rejecting the ones with a newer RDB
+// version or a checksum that does not match. see readValue for the encodings read.
+func ParseDump(payload []byte) (store.Data, error) {
+	if len(payload) < 10 {
+		return nil, errBadDumpPayload
+	}
+
+	body := payload[:len(payload)-10]
+	version := binary.LittleEndian.Uint16(payload[len(body):])
+	sum := binary.LittleEndian.Uint64(payload[len(payload)-8:])
+
+	if version > rdbVersion || sum != checksum(payload[:len(payload)-8]) {
+		return nil, errBadDumpPayload
+	}
+
+	return parseDumpBody(body)
+}
+
+// the readers panic on malformed input, like when loading a corrupt file
+func parseDumpBody(body []byte) (value store.Data, err error) {
+	defer func() {
+		if recover() != nil {
+			value, err = nil, errBadDataFormat
+		}
+	}()
+
+	reader := bufio.NewReader(bytes.NewReader(body))
+	value = readValue(reader, readByte(reader))
+
+	if _, readErr := reader.ReadByte(); readErr != io.EOF {
+		return nil, errBadDataFormat
+	}
+
+	// a hash whose fields all expired, like redis refuses to restore it
+	if isEmpty(value) {
+		return nil, errBadDataFormat
+	}
+
+	return value, nil
+}
+
+// crc-64-jones, without the initial and final inversions of hash/crc64
+func checksum(b []byte) uint64 {
+	return ^crc64.Update(^uint64(0), crc64Table, b)
+}
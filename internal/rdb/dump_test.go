@@ -0,0 +1,193 @@
+package rdb
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// a flat form of the values, to compare them whatever their encoding
+func flatten(t *testing.T, value store.Data) []string {
+	t.Helper()
+
+	switch v := value.(type) {
+	case *datatypes.String:
+		return []string{string(v.Value)}
+	case *datatypes.List:
+		return v.Values()
+	case *datatypes.Set:
+		members := v.Members()
+		sort.Strings(members)
+		return members
+	case *datatypes.Hash:
+		fields := v.Fields()
+		sort.Strings(fields)
+
+		pairs := []string{}
+
+		for _, field := range fields {
+			fieldValue, _ := v.Get(field)
+			pairs = append(pairs, field, fieldValue)
+		}
+
+		return pairs
+	case *datatypes.SortedSet:
+		pairs := []string{}
+
+		for _, member := range v.RangeByRank(0, -1, false) {
+			pairs = append(pairs, member.Member, strconv.FormatFloat(member.Score, 'f', -1, 64))
+		}
+
+		return pairs
+	case *datatypes.Stream:
+		entries := []string{}
+
+		for _, entry := range v.Values {
+			fields := make([]string, 0, len(entry.Values))
+
+			for field := range entry.Values {
+				fields = append(fields, field)
+			}
+
+			sort.Strings(fields)
+			entries = append(entries, entry.Id)
+
+			for _, field := range fields {
+				entries = append(entries, field, entry.Values[field])
+			}
+		}
+
+		return entries
+	default:
+		t.Fatalf("unexpected value %T", value)
+		return nil
+	}
+}
+
+func TestParseDump(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		// the DUMP examples of the redis documentation, RDB versions 9 and 10
+		{"integer string, redis 6", "\x00\xc0\n\t\x00\xbem\x06\x89Z(\x00\n", []string{"10"}},
+		{"integer string, redis 7.0", "\x00\xc0\n\n\x00n\x9fWE\x0e\xaec\xbb", []string{"10"}},
+
+		// laid out the way redis 7.2 dumps small values, RDB version 11
+		{
+			"quicklist of a listpack",
+			"\x12\x01\x02\x10\x10\x00\x00\x00\x03\x00\x81\x61\x02\x81\x62\x02\xc4\x00\x02\xff\x0b\x00\x06\xec\x7f\x47\x4c\xb2\x87\x2d",
+			[]string{"a", "b", "1024"},
+		},
+		{
+			"hash listpack",
+			"\x10\x12\x12\x00\x00\x00\x04\x00\x81\x66\x02\x81\x76\x02\x81\x6e\x02\x07\x01\xff\x0b\x00\xd1\xf5\x63\xdb\xbd\xe0\x13\x21",
+			[]string{"f", "v", "n", "7"},
+		},
+		{
+			"sorted set listpack",
+			"\x11\x14\x14\x00\x00\x00\x04\x00\x81\x61\x02\x83\x31\x2e\x35\x04\x81\x62\x02\x02\x01\xff\x0b\x00\x93\xdd\x09\x56\x52\x87\x6e\xea",
+			[]string{"a", "1.5", "b", "2"},
+		},
+		{
+			"intset",
+			"\x0b\x0e\x02\x00\x00\x00\x03\x00\x00\x00\x01\x00\x02\x00\x2c\x01\x0b\x00\x8c\xcf\x0f\xfd\x86\x0a\xc0\x23",
+			[]string{"1", "2", "300"},
+		},
+		{
+			"set listpack",
+			"\x14\x0d\x0d\x00\x00\x00\x02\x00\x81\x61\x02\x81\x62\x02\xff\x0b\x00\x0a\xec\x0a\xb4\x49\xa3\xd6\x54",
+			[]string{"a", "b"},
+		},
+		{
+			"lzf compressed string",
+			"\x00\xc3\x05\x28\x00\x61\xe0\x1e\x00\x0b\x00\x6a\x94\xcd\xc4\x0f\x11\x3f\x64",
+			[]string{strings.Repeat("a", 40)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := ParseDump([]byte(test.payload))
+
+			if err != nil {
+				t.Fatalf("ParseDump: %v", err)
+			}
+
+			if got := flatten(t, value); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseDumpRejectsBadPayloads(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    error
+	}{
+		{"wrong checksum", "\x00\xc0\n\n\x00n\x9fWE\x0e\xaec\xbc", errBadDumpPayload},
+		// a string declaring a terabyte with a valid checksum
+		{
+			"length past the payload",
+			"\x00\x81\x00\x00\x01\x00\x00\x00\x00\x00\x61\x62\x63\x0b\x00\xab\x34\x41\x6f\xd9\x9a\x98\xc0",
+			errBadDataFormat,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseDump([]byte(test.payload)); err != test.want {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	list := datatypes.NewList()
+	list.PushRight("a", "1", "b")
+
+	set := datatypes.NewSet()
+	set.Add("x")
+	set.Add("y")
+
+	hash := datatypes.NewHash()
+	hash.Set("f", "v")
+
+	zset := datatypes.NewSortedSet()
+	zset.Add("m", 1.5, datatypes.ZAddOptions{})
+	zset.Add("n", -2, datatypes.ZAddOptions{})
+
+	tests := []struct {
+		name  string
+		value store.Data
+	}{
+		{"string", &datatypes.String{DataType: "string", Value: []byte("value")}},
+		{"list", list},
+		{"set", set},
+		{"hash", hash},
+		{"hash with field expiries", newTestVolatileHash()},
+		{"sorted set", zset},
+		{"stream", newTestStream(t)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := ParseDump(Dump(test.value))
+
+			if err != nil {
+				t.Fatalf("ParseDump: %v", err)
+			}
+
+			assertSameValue(t, value, test.value)
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// the containers of a quicklist node
+const (
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+)
+
+// returns the entries of a listpack as redis writes them: a 4 bytes total size
+// and 2 bytes count header, then the entries, each one its encoding, its data
+// and its size written backwards, and a 0xFF terminator. integers are returned
+// formatted in base 10.
+func readListpack(b []byte) []string {
+	if len(b) < 7 || int(binary.LittleEndian.Uint32(b)) != len(b) || b[len(b)-1] != 0xFF {
+		panic("invalid listpack header")
+	}
+
+	entries := []string{}
+
+	for offset := 6; b[offset] != 0xFF; {
+		entry, size := readListpackEntry(b[offset : len(b)-1])
+		entries = append(entries, entry)
+		offset += size + listpackBacklenSize(size)
+
+		if offset >= len(b) {
+			panic("listpack entry past its end")
+		}
+	}
+
+	// the count saturates at 65535, past which the entries have to be walked
+	if count := int(binary.LittleEndian.Uint16(b[4:])); count != 65535 && count != len(entries) {
+		panic("listpack count does not match its entries")
+	}
+
+	return entries
+}
+
+// decodes the entry at the start of b, returns it with its size without the backlen
+func readListpackEntry(b []byte) (string, int) {
+	encoding := b[0]
+
+	switch {
+	case encoding&0x80 == 0: // 7 bits unsigned integer
+		return strconv.Itoa(int(encoding)), 1
+
+	case encoding&0xC0 == 0x80: // string of up to 63 bytes
+		return listpackString(b, 1, int(encoding&0x3F))
+
+	case encoding&0xE0 == 0xC0: // 13 bits signed integer
+		return listpackInt(b, 2, uint64(encoding&0x1F)<<8|uint64(b[1]), 13)
+
+	case encoding&0xF0 == 0xE0: // string of up to 4095 bytes
+		return listpackString(b, 2, int(encoding&0x0F)<<8|int(b[1]))
+
+	case encoding == 0xF0: // string with a 32 bits length
+		return listpackString(b, 5, int(binary.LittleEndian.Uint32(b[1:5])))
+
+	case encoding == 0xF1:
+		return listpackInt(b, 3, uint64(binary.LittleEndian.Uint16(b[1:])), 16)
+
+	case encoding == 0xF2:
+		return listpackInt(b, 4, uint64(b[1])|uint64(b[2])<<8|uint64(b[3])<<16, 24)
+
+	case encoding == 0xF3:
+		return listpackInt(b, 5, uint64(binary.LittleEndian.Uint32(b[1:])), 32)
+
+	case encoding == 0xF4:
+		return listpackInt(b, 9, binary.LittleEndian.Uint64(b[1:]), 64)
+
+	default:
+		panic(fmt.Sprintf("unknown listpack encoding: %08b", encoding))
+	}
+}
+
+func listpackString(b []byte, header, length int) (string, int) {
+	if length < 0 || length > len(b)-header {
+		panic("listpack string past its end")
+	}
+
+	return string(b[header : header+length]), header + length
+}
+
+// sign extends the bits lowest bits of value
+func listpackInt(b []byte, size int, value uint64, bits uint) (string, int) {
+	if size > len(b) {
+		panic("listpack integer past its end")
+	}
+
+	shift := 64 - bits
+
+	return strconv.FormatInt(int64(value<<shift)>>shift, 10), size
+}
+
+// the bytes the backwards size of an entry of size bytes takes
+func listpackBacklenSize(size int) int {
+	switch {
+	case size <= 127:
+		return 1
+	case size < 16383:
+		return 2
+	case size < 2097151:
+		return 3
+	case size < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// returns the members of an intset: the size of its integers, their count,
+// then the integers sorted, all little endian
+func readIntset(b []byte) []string {
+	if len(b) < 8 {
+		panic("invalid intset header")
+	}
+
+	width := int(binary.LittleEndian.Uint32(b))
+	count := int(binary.LittleEndian.Uint32(b[4:]))
+
+	if width != 2 && width != 4 && width != 8 || count > (len(b)-8)/width || len(b) != 8+count*width {
+		panic("invalid intset")
+	}
+
+	members := make([]string, count)
+
+	for i := range members {
+		v := b[8+i*width:]
+
+		switch width {
+		case 2:
+			members[i] = strconv.Itoa(int(int16(binary.LittleEndian.Uint16(v))))
+		case 4:
+			members[i] = strconv.Itoa(int(int32(binary.LittleEndian.Uint32(v))))
+		default:
+			members[i] = strconv.FormatInt(int64(binary.LittleEndian.Uint64(v)), 10)
+		}
+	}
+
+	return members
+}
+
+// decompresses an LZF compressed string of length bytes. each run starts with
+// a control byte: below 32 it is the count minus one of the literal bytes that
+// follow, otherwise a back reference to bytes already decompressed.
+func decompressLZF(in []byte, length int) []byte {
+	out := make([]byte, 0, sizeHint(length))
+
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+
+		if ctrl < 32 {
+			if i+ctrl+1 > len(in) {
+				panic("lzf literal past the end")
+			}
+
+			out = append(out, in[i:i+ctrl+1]...)
+			i += ctrl + 1
+			continue
+		}
+
+		n := ctrl >> 5
+
+		if n == 7 {
+			if i >= len(in) {
+				panic("lzf reference past the end")
+			}
+
+			n += int(in[i])
+			i++
+		}
+
+		if i >= len(in) {
+			panic("lzf reference past the end")
+		}
+
+		ref := len(out) - (ctrl&0x1F)<<8 - int(in[i]) - 1
+		i++
+
+		if ref < 0 {
+			panic("lzf reference before the start")
+		}
+
+		// the reference may overlap the bytes it produces, so byte by byte
+		for j := 0; j < n+2; j++ {
+			out = append(out, out[ref+j])
+		}
+
+		if len(out) > length {
+			panic("lzf string longer than declared")
+		}
+	}
+
+	if len(out) != length {
+		panic("lzf string shorter than declared")
+	}
+
+	return out
+}
+
+// the inverse of readListpack. the entries that are integers in base 10 are
+// written in the smallest integer encoding that holds them, like redis does.
+func writeListpack(entries []string) []byte {
+	b := make([]byte, 6, 7)
+
+	for _, entry := range entries {
+		b = appendListpackEntry(b, entry)
+	}
+
+	b = append(b, 0xFF)
+
+	count := len(entries)
+
+	if count > 65535 {
+		count = 65535
+	}
+
+	binary.LittleEndian.PutUint32(b, uint32(len(b)))
+	binary.LittleEndian.PutUint16(b[4:], uint16(count))
+
+	return b
+}
+
+func appendListpackEntry(b []byte, entry string) []byte {
+	start := len(b)
+
+	if v, err := strconv.ParseInt(entry, 10, 64); err == nil && strconv.FormatInt(v, 10) == entry {
+		switch {
+		case v >= 0 && v <= 127:
+			b = append(b, byte(v))
+		case v >= -1<<12 && v < 1<<12:
+			b = append(b, 0xC0|byte(uint64(v)>>8&0x1F), byte(v))
+		case v >= math.MinInt16 && v <= math.MaxInt16:
+			b = append(b, 0xF1)
+			b = binary.LittleEndian.AppendUint16(b, uint16(v))
+		case v >= -1<<23 && v < 1<<23:
+			b = append(b, 0xF2, byte(v), byte(v>>8), byte(v>>16))
+		case v >= math.MinInt32 && v <= math.MaxInt32:
+			b = append(b, 0xF3)
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		default:
+			b = append(b, 0xF4)
+			b = binary.LittleEndian.AppendUint64(b, uint64(v))
+		}
+	} else {
+		switch length := len(entry); {
+		case length < 1<<6:
+			b = append(b, 0x80|byte(length))
+		case length < 1<<12:
+			b = append(b, 0xE0|byte(length>>8), byte(length))
+		default:
+			b = append(b, 0xF0)
+			b = binary.LittleEndian.AppendUint32(b, uint32(length))
+		}
+
+		b = append(b, entry...)
+	}
+
+	// the size of the entry, 7 bits per byte from the highest ones, all but the
+	// first byte with their top bit set
+	size := len(b) - start
+	n := listpackBacklenSize(size)
+
+	for i := n - 1; i >= 0; i-- {
+		backlen := byte(size >> (7 * i) & 0x7F)
+
+		if i != n-1 {
+			backlen |= 0x80
+		}
+
+		b = append(b, backlen)
+	}
+
+	return b
+}
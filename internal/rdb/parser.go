@@ -2,10 +2,13 @@ package rdb
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/internal/config"
@@ -22,20 +25,51 @@ const (
 	opEXPIRETIMEMS byte = 0xFC
 )
 
+// value types. the ones past typeZSet2 but streams and typeHashMetadata are
+// the compact encodings redis writes small values in, which are read but never
+// written. the ziplist encodings of redis 6 and older are not read.
+const (
+	typeString              byte = 0
+	typeList                byte = 1
+	typeSet                 byte = 2
+	typeHash                byte = 4
+	typeZSet2               byte = 5
+	typeSetIntset           byte = 11
+	typeStreamListpacks     byte = 15
+	typeHashListpack        byte = 16
+	typeZSetListpack        byte = 17
+	typeListQuicklist2      byte = 18
+	typeStreamListpacks2    byte = 19
+	typeSetListpack         byte = 20
+	typeStreamListpacks3    byte = 21
+	typeHashMetadataPreGA   byte = 22
+	typeHashListpackExPreGA byte = 23
+	typeHashMetadata        byte = 24
+	typeHashListpackEx      byte = 25
+)
+
+// the most memory allocated ahead of reading the bytes it is for
+const maxPreallocated = 1 << 16
+
 const (
 	EMPTY_RDB_HEX = "524544495330303131fa0972656469732d76657205372e322e30fa0a72656469732d62697473c040fa056374696d65c26d08bc65fa08757365642d6d656dc2b0c41000fa08616f662d62617365c000fff06e3bfec0ff5aa2"
 )
 
 type RDBFile struct {
-	Items map[string]store.Data
+	Databases map[int]*Database
+}
+
+// the keys of a single database
+type Database struct {
+	Items   map[string]store.Data
+	Expires map[string]time.Time
 }
 
 func New(cfg *config.ServerConfig) *RDBFile {
 	path := cfg.GetRDBFilePath()
 
-	// currently only supports single db
 	db := &RDBFile{
-		Items: make(map[string]store.Data),
+		Databases: make(map[int]*Database),
 	}
 
 	if path == "" {
@@ -69,6 +103,9 @@ func (rdbFile *RDBFile) Parse(r io.Reader) {
 
 	reader.Discard(4) // discard version
 
+	// keys before the first SELECTDB belong to db 0
+	db := rdbFile.database(0)
+
 outerLoop:
 	for {
 		code, err := reader.ReadByte()
@@ -89,7 +126,7 @@ outerLoop:
 			case "redis-ver":
 				fmt.Println(key, readString(reader))
 			case "redis-bits":
-				fmt.Println(key, readInteger(reader))
+				fmt.Println(key, readString(reader))
 			case "ctime":
 				fmt.Println(key, readString(reader))
 			case "used-mem":
@@ -99,10 +136,15 @@ outerLoop:
 			}
 
 		case opSELECTDB:
-			fmt.Println("SELECTDB", readInteger(reader))
+			db = rdbFile.database(readInteger(reader))
 
 		case opRESIZEDB:
-			fmt.Println("RESIZEDB", readInteger(reader), readInteger(reader)) // hashTableSize, expiryHashTableSize
+			hashTableSize, expiryHashTableSize := readInteger(reader), readInteger(reader)
+
+			if len(db.Items) == 0 {
+				db.Items = make(map[string]store.Data, sizeHint(hashTableSize))
+				db.Expires = make(map[string]time.Time, sizeHint(expiryHashTableSize))
+			}
 
 		case opEOF:
 			fmt.Println("EOF")
@@ -125,35 +167,264 @@ outerLoop:
 			}
 
 			key := readString(reader)
-			switch valueType {
-			case 0: // strings
-				value := readString(reader)
-
-				// skip expired keys
-				if expiration.IsZero() || expiration.After(time.Now()) {
-					rdbFile.Items[key] = &datatypes.String{
-						DataType: "string",
-						Value:    value,
-						Expiry:   expiration,
-					}
+			value := readValue(reader, valueType)
+
+			// skip expired keys, and the hashes whose fields all expired
+			if (expiration.IsZero() || expiration.After(time.Now())) && !isEmpty(value) {
+				db.Items[key] = value
+
+				if !expiration.IsZero() {
+					db.Expires[key] = expiration
 				}
+			}
+		}
+	}
+}
+
+// returns the database numbered index, creating it when missing
+func (rdbFile *RDBFile) database(index int) *Database {
+	db, ok := rdbFile.Databases[index]
+
+	if !ok {
+		db = &Database{
+			Items:   make(map[string]store.Data),
+			Expires: make(map[string]time.Time),
+		}
+		rdbFile.Databases[index] = db
+	}
+
+	return db
+}
+
+func readValue(reader *bufio.Reader, valueType byte) store.Data {
+	switch valueType {
+	case typeString:
+		return &datatypes.String{
+			DataType: "string",
+			Value:    []byte(readString(reader)),
+		}
+
+	case typeList:
+		list := datatypes.NewList()
+
+		for n := readInteger(reader); n > 0; n-- {
+			list.PushRight(readString(reader))
+		}
+
+		return list
+
+	case typeSet:
+		set := datatypes.NewSet()
+
+		for n := readInteger(reader); n > 0; n-- {
+			set.Add(readString(reader))
+		}
+
+		return set
+
+	case typeHash:
+		hash := datatypes.NewHash()
+
+		for n := readInteger(reader); n > 0; n-- {
+			hash.Set(readString(reader), readString(reader))
+		}
+
+		return hash
+
+	case typeZSet2:
+		zset := datatypes.NewSortedSet()
+
+		for n := readInteger(reader); n > 0; n-- {
+			member := readString(reader)
+			score := math.Float64frombits(binary.LittleEndian.Uint64(readBytes(reader, 8)))
+			zset.Add(member, score, datatypes.ZAddOptions{})
+		}
+
+		return zset
+
+	case typeListQuicklist2:
+		list := datatypes.NewList()
+
+		for n := readInteger(reader); n > 0; n-- {
+			container := readInteger(reader)
+			node := readString(reader)
+
+			switch container {
+			case quicklistNodePlain:
+				list.PushRight(node)
+			case quicklistNodePacked:
+				list.PushRight(readListpack([]byte(node))...)
 			default:
-				panic(fmt.Sprintf("unknown value type: %08b", valueType))
+				panic(fmt.Sprintf("unknown quicklist container: %d", container))
 			}
 		}
+
+		return list
+
+	case typeSetIntset, typeSetListpack:
+		var members []string
+
+		if valueType == typeSetIntset {
+			members = readIntset([]byte(readString(reader)))
+		} else {
+			members = readListpack([]byte(readString(reader)))
+		}
+
+		set := datatypes.NewSet()
+
+		for _, member := range members {
+			set.Add(member)
+		}
+
+		return set
+
+	case typeHashListpack:
+		entries := readPairs(reader)
+		hash := datatypes.NewHash()
+
+		for i := 0; i < len(entries); i += 2 {
+			hash.Set(entries[i], entries[i+1])
+		}
+
+		return hash
+
+	case typeHashMetadata, typeHashMetadataPreGA:
+		// zero when the field does not expire, otherwise one more than the
+		// expiry minus the earliest one, the pre release encoding writing the
+		// expiries as they are
+		var minExpiry int64
+
+		if valueType == typeHashMetadata {
+			minExpiry = int64(binary.LittleEndian.Uint64(readBytes(reader, 8))) - 1
+		}
+
+		hash := datatypes.NewHash()
+
+		for n := readInteger(reader); n > 0; n-- {
+			expiry := int64(readInteger(reader))
+
+			if expiry != 0 {
+				expiry += minExpiry
+			}
+
+			loadHashField(hash, readString(reader), readString(reader), expiry)
+		}
+
+		return hash
+
+	case typeHashListpackEx, typeHashListpackExPreGA:
+		if valueType == typeHashListpackEx {
+			readBytes(reader, 8) // the earliest expiry
+		}
+
+		entries := readListpack([]byte(readString(reader)))
+
+		if len(entries)%3 != 0 {
+			panic("listpack of field value expiry triplets with a wrong number of entries")
+		}
+
+		hash := datatypes.NewHash()
+
+		for i := 0; i < len(entries); i += 3 {
+			expiry, err := strconv.ParseInt(entries[i+2], 10, 64)
+
+			if err != nil {
+				panic(fmt.Sprintf("invalid field expiry: %q", entries[i+2]))
+			}
+
+			loadHashField(hash, entries[i], entries[i+1], expiry)
+		}
+
+		return hash
+
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		return readStream(reader, valueType)
+
+	case typeZSetListpack:
+		entries := readPairs(reader)
+		zset := datatypes.NewSortedSet()
+
+		for i := 0; i < len(entries); i += 2 {
+			score, err := strconv.ParseFloat(entries[i+1], 64)
+
+			if err != nil || math.IsNaN(score) {
+				panic(fmt.Sprintf("invalid score: %q", entries[i+1]))
+			}
+
+			zset.Add(entries[i], score, datatypes.ZAddOptions{})
+		}
+
+		return zset
+
+	default:
+		panic(fmt.Sprintf("unknown value type: %08b", valueType))
 	}
 }
 
-func (rdb *RDBFile) Inject(store *store.Store) {
-	for key, entry := range rdb.Items {
-		stringEntry, ok := entry.(*datatypes.String)
-		if ok {
-			store.Set(key, stringEntry.Value, stringEntry.Expiry)
+// loads the keys into the databases of the same number
+func (rdb *RDBFile) Inject(dbs *store.Databases) {
+	for index, db := range rdb.Databases {
+		kvStore, err := dbs.Get(index)
+
+		if err != nil {
+			fmt.Println("skipping db", index, err.Error())
+			continue
 		}
+
+		for key, value := range db.Items {
+			kvStore.Load(key, value, db.Expires[key])
+		}
+	}
+}
+
+// whether the value has nothing left, which only happens to hashes loaded
+// after all their fields expired
+func isEmpty(value store.Data) bool {
+	hash, ok := value.(*datatypes.Hash)
+	return ok && hash.Len() == 0
+}
+
+// adds the field unless it expired, expiry being in unix milliseconds and zero
+// when the field does not expire, like redis skips the expired fields it loads
+func loadHashField(hash *datatypes.Hash, field, value string, expiry int64) {
+	if expiry == 0 {
+		hash.Set(field, value)
+		return
+	}
+
+	if expiry <= time.Now().UnixMilli() {
+		return
+	}
+
+	hash.Set(field, value)
+	hash.SetExpiry(field, time.UnixMilli(expiry))
+}
+
+// reads a listpack of field value pairs, like the ones of small hashes and sorted sets
+func readPairs(reader *bufio.Reader) []string {
+	entries := readListpack([]byte(readString(reader)))
+
+	if len(entries)%2 != 0 {
+		panic("listpack of pairs with an odd number of entries")
 	}
+
+	return entries
 }
 
 func readString(reader *bufio.Reader) string {
+	// integers encoded as strings start with 0b11, see readInteger, as do
+	// LZF compressed strings
+	if b, err := reader.Peek(1); err == nil && b[0]&0b1100_0000 == 0b1100_0000 {
+		if b[0] == 0b1100_0011 {
+			reader.Discard(1)
+			compressedLength, length := readInteger(reader), readInteger(reader)
+
+			return string(decompressLZF(readBytes(reader, compressedLength), length))
+		}
+
+		return strconv.Itoa(readInteger(reader))
+	}
+
 	bytesLength := readInteger(reader)
 	bytes := readBytes(reader, bytesLength)
 
@@ -176,20 +447,24 @@ func readInteger(reader *bufio.Reader) int {
 		nextByte := readByte(reader)
 		return int(lastSixBits)<<8 | int(nextByte)
 
-	// Discard the remaining 6 bits. The next 4 bytes from the stream represent the length
+	// Discard the remaining 6 bits. The next 4 (or 8 when the bits are 1) big endian bytes represent the length
 	case 0b1000_0000:
+		if lastSixBits == 1 {
+			return int(binary.BigEndian.Uint64(readBytes(reader, 8)))
+		}
+
 		next4Bytes := readBytes(reader, 4)
-		return int(binary.LittleEndian.Uint32(next4Bytes))
+		return int(binary.BigEndian.Uint32(next4Bytes))
 
 	// The next object is encoded in a special format. The remaining 6 bits indicate the format. May be used to store numbers or Strings
 	case 0b1100_0000:
 		switch lastSixBits {
 		case 0:
-			return int(readByte(reader))
+			return int(int8(readByte(reader)))
 		case 1:
-			return int(binary.LittleEndian.Uint16(readBytes(reader, 2)))
+			return int(int16(binary.LittleEndian.Uint16(readBytes(reader, 2))))
 		case 2:
-			return int(binary.LittleEndian.Uint32(readBytes(reader, 4)))
+			return int(int32(binary.LittleEndian.Uint32(readBytes(reader, 4))))
 		default:
 			panic(fmt.Sprintf("integer encoding does not YET handle: %08b", lastSixBits))
 		}
@@ -200,14 +475,44 @@ func readInteger(reader *bufio.Reader) int {
 
 }
 
+// bounds a size read from the input before it is used to preallocate
+func sizeHint(n int) int {
+	if n < 0 {
+		return 0
+	}
+
+	if n > maxPreallocated {
+		return maxPreallocated
+	}
+
+	return n
+}
+
 func readByte(reader *bufio.Reader) byte {
 	return readBytes(reader, 1)[0]
 }
 
+// lengths come from the input, so past maxPreallocated bytes the memory is only
+// allocated as the bytes arrive, a length larger than what is left failing on
+// EOF instead of allocating it all
 func readBytes(reader *bufio.Reader, n int) []byte {
+	if n < 0 {
+		panic(fmt.Sprintf("invalid length: %d", n))
+	}
+
+	if n > maxPreallocated {
+		var b bytes.Buffer
+
+		if _, err := io.CopyN(&b, reader, int64(n)); err != nil {
+			panic(err)
+		}
+
+		return b.Bytes()
+	}
+
 	b := make([]byte, n)
 
-	_, err := reader.Read(b)
+	_, err := io.ReadFull(reader, b)
 
 	if err != nil {
 		panic(err)
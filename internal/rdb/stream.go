@@ -0,0 +1,223 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// the flags of a stream entry
+const (
+	streamEntryDeleted    = 1
+	streamEntrySameFields = 2
+)
+
+// the most entries redis puts in a node, its stream-node-max-entries default
+const streamNodeMaxEntries = 100
+
+// writes the stream in the typeStreamListpacks3 encoding: its entries in
+// listpack nodes keyed by the id of their first entry, the metadata of the
+// stream, then its consumer groups, of which there are none here.
+//
+// a node starts with a master entry holding the count of entries, the count of
+// deleted ones and the fields they share, then each entry has its flags, its id
+// as a difference from the node key, its fields and values, and its count of
+// listpack entries. the master entries written here share no field.
+func writeStream(writer *bufio.Writer, stream *datatypes.Stream) {
+	entries := stream.Values
+
+	writeLength(writer, (len(entries)+streamNodeMaxEntries-1)/streamNodeMaxEntries)
+
+	for start := 0; start < len(entries); start += streamNodeMaxEntries {
+		node := entries[start:]
+
+		if len(node) > streamNodeMaxEntries {
+			node = node[:streamNodeMaxEntries]
+		}
+
+		masterMajor, masterMinor := node[0].IdParts()
+		lp := []string{strconv.Itoa(len(node)), "0", "0", "0"}
+
+		for _, entry := range node {
+			major, minor := entry.IdParts()
+			lp = append(lp, "0", strconv.Itoa(major-masterMajor), strconv.Itoa(minor-masterMinor))
+			lp = append(lp, strconv.Itoa(len(entry.Values)))
+
+			for field, value := range entry.Values {
+				lp = append(lp, field, value)
+			}
+
+			lp = append(lp, strconv.Itoa(len(entry.Values)*2+4))
+		}
+
+		writeString(writer, string(streamId(masterMajor, masterMinor)))
+		writeString(writer, string(writeListpack(lp)))
+	}
+
+	var first, last datatypes.Entry
+
+	if len(entries) > 0 {
+		first, last = entries[0], entries[len(entries)-1]
+	}
+
+	writeLength(writer, len(entries))
+	writeIdParts(writer, last)
+	writeIdParts(writer, first)
+	writeLength(writer, 0) // the greatest deleted id
+	writeLength(writer, 0)
+	writeLength(writer, len(entries)) // the entries ever added
+	writeLength(writer, 0)            // the consumer groups
+}
+
+func writeIdParts(writer *bufio.Writer, entry datatypes.Entry) {
+	major, minor := entry.IdParts()
+	writeLength(writer, major)
+	writeLength(writer, minor)
+}
+
+// the key of a node, the two parts of the id big endian
+func streamId(major, minor int) []byte {
+	b := binary.BigEndian.AppendUint64(nil, uint64(major))
+	return binary.BigEndian.AppendUint64(b, uint64(minor))
+}
+
+// the inverse of writeStream, also reading the older typeStreamListpacks and
+// typeStreamListpacks2 encodings. deleted entries and consumer groups are
+// dropped, as they have no counterpart here.
+func readStream(reader *bufio.Reader, valueType byte) *datatypes.Stream {
+	stream := &datatypes.Stream{
+		DataType:    "stream",
+		Values:      make([]datatypes.Entry, 0),
+		Subscribers: make(map[string]chan string),
+	}
+
+	for nodes := readInteger(reader); nodes > 0; nodes-- {
+		key := []byte(readString(reader))
+
+		if len(key) != 16 {
+			panic("invalid stream node key")
+		}
+
+		readStreamNode(stream, key, readListpack([]byte(readString(reader))))
+	}
+
+	readInteger(reader) // the length
+	readInteger(reader) // the last id
+	readInteger(reader)
+
+	if valueType != typeStreamListpacks {
+		readInteger(reader) // the first id
+		readInteger(reader)
+		readInteger(reader) // the greatest deleted id
+		readInteger(reader)
+		readInteger(reader) // the entries ever added
+	}
+
+	for groups := readInteger(reader); groups > 0; groups-- {
+		readString(reader)  // the name
+		readInteger(reader) // the last delivered id
+		readInteger(reader)
+
+		if valueType != typeStreamListpacks {
+			readInteger(reader) // the entries read
+		}
+
+		// the pending entries: their id, delivery time and delivery count
+		for pending := readInteger(reader); pending > 0; pending-- {
+			readBytes(reader, 16+8)
+			readInteger(reader)
+		}
+
+		for consumers := readInteger(reader); consumers > 0; consumers-- {
+			readString(reader)   // the name
+			readBytes(reader, 8) // the seen time
+
+			if valueType == typeStreamListpacks3 {
+				readBytes(reader, 8) // the active time
+			}
+
+			// the ids of its pending entries
+			for pending := readInteger(reader); pending > 0; pending-- {
+				readBytes(reader, 16)
+			}
+		}
+	}
+
+	return stream
+}
+
+// appends the entries of a node, see writeStream for its layout
+func readStreamNode(stream *datatypes.Stream, key []byte, lp []string) {
+	masterMajor := int(binary.BigEndian.Uint64(key))
+	masterMinor := int(binary.BigEndian.Uint64(key[8:]))
+
+	next := func() string {
+		if len(lp) == 0 {
+			panic("stream node past its end")
+		}
+
+		entry := lp[0]
+		lp = lp[1:]
+
+		return entry
+	}
+
+	nextInt := func() int {
+		n, err := strconv.Atoi(next())
+
+		if err != nil {
+			panic("invalid integer in stream node")
+		}
+
+		return n
+	}
+
+	count, deleted, fields := nextInt(), nextInt(), nextInt()
+
+	if fields < 0 || fields > len(lp) {
+		panic("invalid stream master entry")
+	}
+
+	masterFields := make([]string, fields)
+
+	for i := range masterFields {
+		masterFields[i] = next()
+	}
+
+	if next() != "0" {
+		panic("invalid stream master entry")
+	}
+
+	for entries := count + deleted; entries > 0; entries-- {
+		flags := nextInt()
+		major, minor := masterMajor+nextInt(), masterMinor+nextInt()
+		values := make(map[string]string)
+
+		if flags&streamEntrySameFields != 0 {
+			for _, field := range masterFields {
+				values[field] = next()
+			}
+		} else {
+			for fields := nextInt(); fields > 0; fields-- {
+				field := next()
+				values[field] = next()
+			}
+		}
+
+		next() // the count of listpack entries
+
+		if flags&streamEntryDeleted != 0 {
+			continue
+		}
+
+		if err := stream.LoadEntry(major, minor, values); err != nil {
+			panic(err)
+		}
+	}
+
+	if len(lp) != 0 {
+		panic("stream node longer than its entries")
+	}
+}
@@ -0,0 +1,245 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// the file SAVE writes to when no dir and dbfilename are configured
+const defaultRDBFileName = "dump.rdb"
+
+// the version of the RDB format written, also the newest one DUMP payloads may have
+const rdbVersion = 12
+
+// writes the databases to the configured RDB file, through a temporary file so
+// a failed save leaves the previous file intact
+func Save(cfg *config.ServerConfig, dbs *store.Databases) error {
+	path := cfg.GetRDBFilePath()
+
+	if path == "" {
+		path = defaultRDBFileName
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "temp-*.rdb")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	// CreateTemp only lets the owner read the file
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := Write(file, dbs); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// writes a snapshot of every non empty database in the RDB format. each
+// database is serialized while its lock is held.
+func Write(w io.Writer, dbs *store.Databases) error {
+	writer := bufio.NewWriter(w)
+
+	writer.WriteString(fmt.Sprintf("REDIS%04d", rdbVersion))
+	writeAux(writer, "redis-ver", "7.4.0")
+	writeAux(writer, "redis-bits", "64")
+
+	for index := 0; index < dbs.Len(); index++ {
+		kvStore, _ := dbs.Get(index)
+
+		var entries bytes.Buffer
+		entriesWriter := bufio.NewWriter(&entries)
+		keys, expires := 0, 0
+
+		kvStore.ForEach(func(key string, value store.Data, expiry time.Time) {
+			if !expiry.IsZero() {
+				entriesWriter.WriteByte(opEXPIRETIMEMS)
+				binary.Write(entriesWriter, binary.LittleEndian, uint64(expiry.UnixMilli()))
+				expires++
+			}
+
+			t := valueType(value)
+			entriesWriter.WriteByte(t)
+			writeString(entriesWriter, key)
+			writeValue(entriesWriter, t, value)
+			keys++
+		})
+
+		if keys == 0 {
+			continue
+		}
+
+		entriesWriter.Flush()
+
+		writer.WriteByte(opSELECTDB)
+		writeLength(writer, index)
+		writer.WriteByte(opRESIZEDB)
+		writeLength(writer, keys)
+		writeLength(writer, expires)
+		writer.Write(entries.Bytes())
+	}
+
+	writer.WriteByte(opEOF)
+
+	// a zero checksum tells readers it was not computed
+	writer.Write(make([]byte, 8))
+
+	return writer.Flush()
+}
+
+// returns the RDB type value is written as
+func valueType(value store.Data) byte {
+	switch v := value.(type) {
+	case *datatypes.String:
+		return typeString
+	case *datatypes.List:
+		return typeList
+	case *datatypes.Set:
+		return typeSet
+	case *datatypes.Hash:
+		if v.HasVolatileFields() {
+			return typeHashMetadata
+		}
+
+		return typeHash
+	case *datatypes.SortedSet:
+		return typeZSet2
+	case *datatypes.Stream:
+		return typeStreamListpacks3
+	default:
+		panic(fmt.Sprintf("no RDB type for %T", value))
+	}
+}
+
+// writes the value in the encoding of t, its valueType, the inverse of
+// readValue. t is passed along as the fields of a hash may expire in between.
+func writeValue(writer *bufio.Writer, t byte, value store.Data) {
+	switch v := value.(type) {
+	case *datatypes.String:
+		writeString(writer, string(v.Value))
+
+	case *datatypes.List:
+		writeStrings(writer, v.Values())
+
+	case *datatypes.Set:
+		writeStrings(writer, v.Members())
+
+	case *datatypes.Hash:
+		if t == typeHashMetadata {
+			writeHashMetadata(writer, v)
+			break
+		}
+
+		fields := v.Fields()
+
+		writeLength(writer, len(fields))
+
+		for _, field := range fields {
+			fieldValue, _ := v.Get(field)
+			writeString(writer, field)
+			writeString(writer, fieldValue)
+		}
+
+	case *datatypes.Stream:
+		writeStream(writer, v)
+
+	case *datatypes.SortedSet:
+		members := v.RangeByRank(0, -1, false)
+
+		writeLength(writer, len(members))
+
+		for _, member := range members {
+			writeString(writer, member.Member)
+			binary.Write(writer, binary.LittleEndian, math.Float64bits(member.Score))
+		}
+	}
+}
+
+// writes the hash in the typeHashMetadata encoding: the earliest expiry of its
+// fields, then for each field its expiry relative to the earliest one, see
+// readValue, its name and its value
+func writeHashMetadata(writer *bufio.Writer, hash *datatypes.Hash) {
+	fields := hash.Fields()
+	var minExpiry int64
+
+	for _, field := range fields {
+		if expiry, ok := hash.Expiry(field); ok && (minExpiry == 0 || expiry.UnixMilli() < minExpiry) {
+			minExpiry = expiry.UnixMilli()
+		}
+	}
+
+	binary.Write(writer, binary.LittleEndian, uint64(minExpiry))
+	writeLength(writer, len(fields))
+
+	for _, field := range fields {
+		// a field expiring from now on is still written, as loading skips it
+		fieldValue, _ := hash.Get(field)
+		relative := 0
+
+		if expiry, ok := hash.Expiry(field); ok {
+			relative = int(expiry.UnixMilli()-minExpiry) + 1
+		}
+
+		writeLength(writer, relative)
+		writeString(writer, field)
+		writeString(writer, fieldValue)
+	}
+}
+
+func writeAux(writer *bufio.Writer, key, value string) {
+	writer.WriteByte(opAUX)
+	writeString(writer, key)
+	writeString(writer, value)
+}
+
+func writeStrings(writer *bufio.Writer, values []string) {
+	writeLength(writer, len(values))
+
+	for _, value := range values {
+		writeString(writer, value)
+	}
+}
+
+func writeString(writer *bufio.Writer, value string) {
+	writeLength(writer, len(value))
+	writer.WriteString(value)
+}
+
+// the inverse of readInteger for lengths
+func writeLength(writer *bufio.Writer, n int) {
+	switch {
+	case n < 1<<6:
+		writer.WriteByte(byte(n))
+	case n < 1<<14:
+		writer.WriteByte(byte(n>>8) | 0b0100_0000)
+		writer.WriteByte(byte(n))
+	case n <= math.MaxUint32:
+		writer.WriteByte(0b1000_0000)
+		binary.Write(writer, binary.BigEndian, uint32(n))
+	default:
+		writer.WriteByte(0b1000_0001)
+		binary.Write(writer, binary.BigEndian, uint64(n))
+	}
+}
@@ -0,0 +1,133 @@
+package rdb
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// a stream spanning several nodes, with ids whose parts go both up and down
+// between entries and with varying fields
+func newTestStream(t *testing.T) *datatypes.Stream {
+	t.Helper()
+
+	stream := &datatypes.Stream{DataType: "stream", Subscribers: make(map[string]chan string)}
+
+	for i := 0; i < 2*streamNodeMaxEntries+10; i++ {
+		values := map[string]string{"n": strconv.Itoa(i)}
+
+		if i%3 == 0 {
+			values["big"] = strconv.Itoa(-i * 1_000_003)
+			values["text"] = "entry " + strconv.Itoa(i)
+		}
+
+		if err := stream.LoadEntry(1700000000000+i/2*1000, (i%2)*(i+5), values); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return stream
+}
+
+// a hash with a persistent field, two fields that expire and an expired one
+func newTestVolatileHash() *datatypes.Hash {
+	now := time.Now()
+	hash := datatypes.NewHash()
+
+	hash.Set("persistent", "1")
+	hash.Set("soon", "2")
+	hash.SetExpiry("soon", now.Add(time.Hour))
+	hash.Set("later", "3")
+	hash.SetExpiry("later", now.Add(48*time.Hour))
+	hash.Set("expired", "4")
+	hash.SetExpiry("expired", now.Add(-time.Second))
+
+	return hash
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value store.Data
+	}{
+		{"stream", newTestStream(t)},
+		{"empty stream", &datatypes.Stream{DataType: "stream", Subscribers: make(map[string]chan string)}},
+		{"hash with field expiries", newTestVolatileHash()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbs := store.NewDatabases(1)
+			db, _ := dbs.Get(0)
+			db.Load("key", test.value, time.Time{})
+
+			var file bytes.Buffer
+
+			if err := Write(&file, dbs); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			rdbFile := &RDBFile{Databases: make(map[int]*Database)}
+			rdbFile.Parse(&file)
+
+			got, ok := rdbFile.Databases[0].Items["key"]
+
+			if !ok {
+				t.Fatal("key missing after parsing")
+			}
+
+			assertSameValue(t, got, test.value)
+		})
+	}
+}
+
+// compares the values and, for hashes, the expiries of their fields
+func assertSameValue(t *testing.T, got, want store.Data) {
+	t.Helper()
+
+	if g, w := flatten(t, got), flatten(t, want); !reflect.DeepEqual(g, w) {
+		t.Fatalf("got %q, want %q", g, w)
+	}
+
+	hash, ok := want.(*datatypes.Hash)
+
+	if !ok {
+		return
+	}
+
+	for _, field := range hash.Fields() {
+		gotExpiry, gotOk := got.(*datatypes.Hash).Expiry(field)
+		wantExpiry, wantOk := hash.Expiry(field)
+
+		if gotOk != wantOk || gotExpiry.UnixMilli() != wantExpiry.UnixMilli() {
+			t.Errorf("expiry of %q: got %v %v, want %v %v", field, gotExpiry, gotOk, wantExpiry, wantOk)
+		}
+	}
+}
+
+func TestListpackRoundTrip(t *testing.T) {
+	entries := []string{
+		"0", "127", "128", "-1", "4095", "-4096", "4096", "32767", "-32768",
+		"8388607", "-8388608", "2147483647", "-2147483648", "9223372036854775807",
+		"-9223372036854775808", "007", "+1", "-0", "", "a", string(make([]byte, 63)),
+		string(make([]byte, 64)), string(make([]byte, 4096)), string(make([]byte, 20000)),
+	}
+
+	if got := readListpack(writeListpack(entries)); !reflect.DeepEqual(got, entries) {
+		t.Errorf("got %q, want %q", got, entries)
+	}
+}
+
+func TestWriteListpackLikeRedis(t *testing.T) {
+	// the listpack of the hash listpack DUMP example
+	want := "\x12\x00\x00\x00\x04\x00\x81\x66\x02\x81\x76\x02\x81\x6e\x02\x07\x01\xff"
+
+	if got := string(writeListpack([]string{"f", "v", "n", "7"})); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package replication
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -16,7 +17,7 @@ import (
 	"github.com/codecrafters-io/redis-starter-go/internal/store"
 )
 
-func ConnectToMaster(config *config.ServerConfig, kvStore *store.Store) {
+func ConnectToMaster(config *config.ServerConfig, dbs *store.Databases) {
 
 	if config.MasterHost == "" || config.MasterPort == "" {
 		panic("Master host and port are required to connect to master")
@@ -44,6 +45,7 @@ func ConnectToMaster(config *config.ServerConfig, kvStore *store.Store) {
 
 	// Read from master
 	reader := bufio.NewReader(conn)
+	client := &command.Client{}
 	for {
 		message, err := parser.Deserialize(reader)
 
@@ -76,7 +78,7 @@ func ConnectToMaster(config *config.ServerConfig, kvStore *store.Store) {
 			continue
 		}
 
-		response = command.Handler(message.Commands, conn, kvStore, config)
+		response = command.Handler(context.Background(), message.Commands, conn, dbs, client, config)
 
 		if leadCommand == command.REPLCONF {
 			conn.Write(response)
@@ -0,0 +1,147 @@
+package store
+
+import "github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+
+// sets or clears the bit at offset of the string at key, creating it when missing.
+// returns the previous value of the bit.
+func (s *Store) SetBit(key string, offset int, on bool) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	str, err := s.getString(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if str == nil {
+		str = &datatypes.String{DataType: "string"}
+		s.setKey(key, str)
+	}
+
+	return str.SetBit(offset, on), nil
+}
+
+func (s *Store) GetBit(key string, offset int) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	str, err := s.getString(key)
+
+	if err != nil || str == nil {
+		return 0, err
+	}
+
+	return str.GetBit(offset), nil
+}
+
+// counts the set bits of the string at key within start and end, see String.BitCount
+func (s *Store) BitCount(key string, start, end int, unitBit bool) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	str, err := s.getString(key)
+
+	if err != nil || str == nil {
+		return 0, err
+	}
+
+	return str.BitCount(start, end, unitBit), nil
+}
+
+// returns the offset of the first bit set to bit in the string at key, see String.BitPos
+func (s *Store) BitPos(key string, bit, start, end int, endGiven, unitBit bool) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	str, err := s.getString(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	// a missing key is an empty string whose bits are all 0
+	if str == nil {
+		if bit == 1 {
+			return -1, nil
+		}
+
+		return 0, nil
+	}
+
+	return str.BitPos(bit, start, end, endGiven, unitBit), nil
+}
+
+// stores the result of a BITOP operation over keys in destination and returns its length,
+// missing keys count as empty strings and an empty result deletes destination
+func (s *Store) BitOp(op, destination string, keys []string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	values := make([][]byte, len(keys))
+
+	for i, key := range keys {
+		str, err := s.getString(key)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if str != nil {
+			values[i] = str.Value
+		}
+	}
+
+	result := datatypes.BitOp(op, values)
+
+	if len(result) == 0 {
+		s.deleteKey(destination)
+		return 0, nil
+	}
+
+	s.setKey(destination, &datatypes.String{
+		DataType: "string",
+		Value:    result,
+		Raw:      true,
+	})
+
+	return len(result), nil
+}
+
+// runs the BITFIELD ops on the string at key, see String.Bitfield.
+// the key is only created when one of the ops may write.
+func (s *Store) Bitfield(key string, ops []datatypes.BitfieldOp) ([]int64, []bool, error) {
+	writes := false
+
+	for _, op := range ops {
+		if op.Kind != datatypes.BitfieldGet {
+			writes = true
+		}
+	}
+
+	if writes {
+		s.lock()
+		defer s.unlock()
+	} else {
+		s.mutex.RLock()
+		defer s.mutex.RUnlock()
+	}
+
+	str, err := s.getString(key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if str == nil {
+		str = &datatypes.String{DataType: "string"}
+
+		if writes {
+			s.setKey(key, str)
+		}
+	}
+
+	results, ok := str.Bitfield(ops)
+
+	return results, ok, nil
+}
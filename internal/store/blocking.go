@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+)
+
+// serves a blocked client from key. it runs with the write lock held and
+// reports false when the key can not satisfy the client (yet).
+type serveFunc func(key string) ([]string, bool, error)
+
+// a client parked on one or more keys by a blocking command
+type waiter struct {
+	keys   []string
+	serve  serveFunc
+	result chan []string
+	served bool
+}
+
+// Block tries serve on every key in order and returns the first result. When none of
+// the keys can serve the client it waits, in FIFO order with other clients blocked on
+// the same keys, until a write makes one of them ready or ctx is done.
+// A nil result means ctx was done before the client could be served.
+func (s *Store) Block(ctx context.Context, keys []string, serve serveFunc) ([]string, error) {
+	s.lock()
+
+	for _, key := range keys {
+		result, ok, err := serve(key)
+
+		if err != nil || ok {
+			s.handleReadyKeys()
+			s.unlock()
+			return result, err
+		}
+	}
+
+	w := &waiter{
+		keys:   keys,
+		serve:  serve,
+		result: make(chan []string, 1),
+	}
+
+	for _, key := range keys {
+		s.blocked[key] = append(s.blocked[key], w)
+	}
+
+	s.unlock()
+
+	select {
+	case result := <-w.result:
+		return result, nil
+	case <-ctx.Done():
+	}
+
+	s.lock()
+	defer s.unlock()
+
+	// a writer may have served us while we were waiting for the lock
+	if w.served {
+		return <-w.result, nil
+	}
+
+	s.removeWaiter(w)
+
+	return nil, nil
+}
+
+// marks key as possibly able to serve blocked clients, callers must hold the write lock
+func (s *Store) signalKeyAsReady(key string) {
+	if _, ok := s.blocked[key]; ok {
+		s.readyKeys = append(s.readyKeys, key)
+	}
+}
+
+// serves the clients blocked on the keys signaled as ready. serving a client can
+// make other keys ready (e.g. BLMOVE pushing to its destination), so this keeps
+// going until no key is left. callers must hold the write lock.
+func (s *Store) handleReadyKeys() {
+	for len(s.readyKeys) > 0 {
+		key := s.readyKeys[0]
+		s.readyKeys = s.readyKeys[1:]
+
+		queue := append([]*waiter{}, s.blocked[key]...)
+
+		for _, w := range queue {
+			result, ok, err := w.serve(key)
+
+			if err != nil || !ok {
+				continue
+			}
+
+			s.removeWaiter(w)
+			w.served = true
+			w.result <- result
+		}
+	}
+}
+
+func (s *Store) removeWaiter(w *waiter) {
+	for _, key := range w.keys {
+		queue := s.blocked[key]
+
+		for i, other := range queue {
+			if other == w {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+
+		if len(queue) == 0 {
+			delete(s.blocked, key)
+		} else {
+			s.blocked[key] = queue
+		}
+	}
+}
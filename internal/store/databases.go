@@ -0,0 +1,213 @@
+package store
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+var errDBIndexOutOfRange = errors.New("ERR DB index is out of range")
+
+// the logical databases of the server. each connection works on the one it
+// selected, db 0 unless it ran SELECT.
+type Databases struct {
+	dbs            []*Store
+	evictionMutex  sync.Mutex          // serializes PerformEvictions
+	evictionPool   []evictionCandidate // the best keys to evict seen so far, idlest last
+	nextEvictionDB int                 // where the random policies look for a key next
+	evicted        atomic.Int64        // keys evicted since the server started
+	peak           atomic.Int64        // the highest used memory seen, see UsedMemory
+}
+
+func NewDatabases(count int) *Databases {
+	dbs := make([]*Store, count)
+
+	for i := range dbs {
+		dbs[i] = New(i)
+	}
+
+	return &Databases{dbs: dbs}
+}
+
+// returns the database numbered index, failing when it is out of range
+func (d *Databases) Get(index int) (*Store, error) {
+	if index < 0 || index >= len(d.dbs) {
+		return nil, errDBIndexOutOfRange
+	}
+
+	return d.dbs[index], nil
+}
+
+// returns the number of databases
+func (d *Databases) Len() int {
+	return len(d.dbs)
+}
+
+// moves key from src to dst along with its expiry. returns false when key does
+// not exist in src or already exists in dst.
+func (d *Databases) Move(key string, src, dst *Store) bool {
+	unlock := lockPair(src, dst)
+	defer unlock()
+
+	e, ok := src.lookup(key)
+
+	if !ok {
+		return false
+	}
+
+	if _, exists := dst.lookup(key); exists {
+		return false
+	}
+
+	expiry, hasExpiry := src.expires[key]
+
+	src.deleteKey(key)
+	dst.add(key, e)
+
+	if hasExpiry {
+		dst.expires[key] = expiry
+	}
+
+	dst.handleReadyKeys()
+
+	return true
+}
+
+// exchanges the contents of the databases numbered a and b, so clients connected
+// to one see the data of the other. clients blocked on either database are
+// served from the swapped in data.
+func (d *Databases) Swap(a, b int) error {
+	first, err := d.Get(a)
+
+	if err != nil {
+		return err
+	}
+
+	second, err := d.Get(b)
+
+	if err != nil {
+		return err
+	}
+
+	unlock := lockPair(first, second)
+	defer unlock()
+
+	if first == second {
+		return nil
+	}
+
+	first.data, second.data = second.data, first.data
+	first.keys, second.keys = second.keys, first.keys
+	first.expires, second.expires = second.expires, first.expires
+	first.volatileHashes, second.volatileHashes = second.volatileHashes, first.volatileHashes
+
+	used := first.used.Load()
+	first.used.Store(second.used.Load())
+	second.used.Store(used)
+
+	for _, s := range []*Store{first, second} {
+		for key := range s.blocked {
+			s.signalKeyAsReady(key)
+		}
+
+		s.handleReadyKeys()
+	}
+
+	return nil
+}
+
+// removes every key of every database
+func (d *Databases) FlushAll() {
+	for _, db := range d.dbs {
+		db.FlushDB()
+	}
+}
+
+// runs the active expire cycle of every database hz times per second, see
+// Store.activeExpire, calling expired with the keys deleted. effort (1 to 10)
+// raises how many keys are sampled and how much of each tick may be spent.
+func (d *Databases) ActiveExpireCycle(hz, effort int, expired func(db *Store, keys []string)) {
+	effort--
+
+	keysPerLoop := activeExpireKeysPerLoop + activeExpireKeysPerLoop/4*effort
+	acceptableStale := activeExpireAcceptableStale - effort
+	timeLimit := time.Second * time.Duration(activeExpireSlowTimePerc+2*effort) / time.Duration(hz) / 100
+
+	ticker := time.NewTicker(time.Second / time.Duration(hz))
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deadline := time.Now().Add(timeLimit)
+
+		// keeps the peak memory up to date between commands
+		d.UsedMemory()
+
+		// the time limit is shared, so later databases get what the earlier ones left
+		for _, db := range d.dbs {
+			if time.Now().After(deadline) {
+				break
+			}
+
+			db.activeExpire(keysPerLoop, acceptableStale, deadline, expired)
+		}
+	}
+}
+
+// reclaims the expired hash fields of every database ten times per second, so
+// hashes nobody touches get reclaimed too, calling reclaimed with the fields
+// removed. see Store.reclaimExpiredHashFields.
+func (d *Databases) ReclaimExpiredHashFields(reclaimed func(db *Store, hashes []ReclaimedFields)) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, db := range d.dbs {
+			db.reclaimExpiredHashFields(reclaimed)
+		}
+	}
+}
+
+// removes every key of the database
+func (s *Store) FlushDB() {
+	s.lock()
+	defer s.unlock()
+
+	// the old tables become garbage at once, the collector frees them off the request path
+	s.data = make(map[string]*entry)
+	s.keys = datatypes.NewScanIndex()
+	s.expires = make(map[string]time.Time)
+	s.volatileHashes = make(map[string]struct{})
+	s.used.Store(0)
+}
+
+// returns the number of keys, including expired keys that were not reclaimed yet
+func (s *Store) DBSize() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.data)
+}
+
+// write locks a and b in index order, so two goroutines locking the same pair
+// can not deadlock, and returns the function that unlocks them
+func lockPair(a, b *Store) func() {
+	if a == b {
+		a.lock()
+		return a.unlock
+	}
+
+	if a.index > b.index {
+		a, b = b, a
+	}
+
+	a.lock()
+	b.lock()
+
+	return func() {
+		b.unlock()
+		a.unlock()
+	}
+}
@@ -0,0 +1,192 @@
+package datatypes
+
+import "math"
+
+// BITFIELD subcommands
+const (
+	BitfieldGet = iota
+	BitfieldSet
+	BitfieldIncrBy
+)
+
+// BITFIELD OVERFLOW behaviours
+const (
+	BitfieldWrap = iota
+	BitfieldSat
+	BitfieldFail
+)
+
+// BitfieldOp is a single GET, SET or INCRBY of a BITFIELD command, on an integer of
+// Bits bits (signed or not) stored at the bit Offset.
+type BitfieldOp struct {
+	Kind     int
+	Signed   bool
+	Bits     int
+	Offset   int
+	Value    int64
+	Overflow int
+}
+
+// runs ops in order and returns the reply of each one: the value for GET, the previous
+// value for SET and the new value for INCRBY. ok[i] is false when an OVERFLOW FAIL
+// prevented ops[i] from writing.
+func (s *String) Bitfield(ops []BitfieldOp) (results []int64, ok []bool) {
+	results = make([]int64, len(ops))
+	ok = make([]bool, len(ops))
+
+	for i, op := range ops {
+		if op.Kind == BitfieldGet {
+			if op.Signed {
+				results[i] = s.getSignedBitfield(op.Offset, op.Bits)
+			} else {
+				results[i] = int64(s.getUnsignedBitfield(op.Offset, op.Bits))
+			}
+
+			ok[i] = true
+			continue
+		}
+
+		var value uint64
+		var overflow int
+
+		if op.Signed {
+			current := s.getSignedBitfield(op.Offset, op.Bits)
+
+			if op.Kind == BitfieldIncrBy {
+				newValue, o := signedBitfieldOverflow(current, op.Value, op.Bits, op.Overflow)
+				value, overflow, results[i] = uint64(newValue), o, newValue
+			} else {
+				newValue, o := signedBitfieldOverflow(op.Value, 0, op.Bits, op.Overflow)
+				value, overflow, results[i] = uint64(newValue), o, current
+			}
+		} else {
+			current := s.getUnsignedBitfield(op.Offset, op.Bits)
+
+			if op.Kind == BitfieldIncrBy {
+				value, overflow = unsignedBitfieldOverflow(current, op.Value, op.Bits, op.Overflow)
+				results[i] = int64(value)
+			} else {
+				value, overflow = unsignedBitfieldOverflow(uint64(op.Value), 0, op.Bits, op.Overflow)
+				results[i] = int64(current)
+			}
+		}
+
+		if overflow != 0 && op.Overflow == BitfieldFail {
+			continue
+		}
+
+		s.setBitfield(op.Offset, op.Bits, value)
+		ok[i] = true
+	}
+
+	return results, ok
+}
+
+func (s *String) getUnsignedBitfield(offset, bits int) uint64 {
+	var value uint64
+
+	for i := 0; i < bits; i++ {
+		value = value<<1 | uint64(s.GetBit(offset+i))
+	}
+
+	return value
+}
+
+func (s *String) getSignedBitfield(offset, bits int) int64 {
+	value := s.getUnsignedBitfield(offset, bits)
+
+	// sign extend
+	if bits < 64 && value&(1<<(bits-1)) != 0 {
+		value |= math.MaxUint64 << bits
+	}
+
+	return int64(value)
+}
+
+// writes the low bits of value at offset, most significant bit first
+func (s *String) setBitfield(offset, bits int, value uint64) {
+	s.grow((offset+bits-1)/8 + 1)
+
+	for i := 0; i < bits; i++ {
+		s.SetBit(offset+i, value>>(bits-1-i)&1 == 1)
+	}
+}
+
+// adds incr to value within an unsigned integer of bits bits. returns the result
+// after applying the overflow behaviour, and 1 or -1 when it overflowed or underflowed.
+func unsignedBitfieldOverflow(value uint64, incr int64, bits int, behaviour int) (uint64, int) {
+	max := uint64(math.MaxUint64)
+
+	if bits < 64 {
+		max = 1<<bits - 1
+	}
+
+	maxIncr := max - value
+	minIncr := -int64(value)
+
+	overflow := 0
+
+	switch {
+	case value > max || (incr > 0 && uint64(incr) > maxIncr):
+		overflow = 1
+	case incr < 0 && incr < minIncr:
+		overflow = -1
+	default:
+		return value + uint64(incr), 0
+	}
+
+	switch {
+	case behaviour == BitfieldSat && overflow == 1:
+		return max, overflow
+	case behaviour == BitfieldSat:
+		return 0, overflow
+	default:
+		return (value + uint64(incr)) & max, overflow
+	}
+}
+
+// like unsignedBitfieldOverflow for signed integers
+func signedBitfieldOverflow(value, incr int64, bits int, behaviour int) (int64, int) {
+	max := int64(math.MaxInt64)
+
+	if bits < 64 {
+		max = 1<<(bits-1) - 1
+	}
+
+	min := -max - 1
+	maxIncr := max - value
+	minIncr := min - value
+
+	overflow := 0
+
+	switch {
+	case value > max || (bits != 64 && incr > maxIncr) || (value >= 0 && incr > 0 && incr > maxIncr):
+		overflow = 1
+	case value < min || (bits != 64 && incr < minIncr) || (value < 0 && incr < 0 && incr < minIncr):
+		overflow = -1
+	default:
+		return value + incr, 0
+	}
+
+	switch {
+	case behaviour == BitfieldSat && overflow == 1:
+		return max, overflow
+	case behaviour == BitfieldSat:
+		return min, overflow
+	}
+
+	// wrap around by keeping the low bits and extending the sign
+	result := uint64(value + incr)
+
+	if bits < 64 {
+		mask := uint64(math.MaxUint64) << bits
+
+		if result&(1<<(bits-1)) != 0 {
+			result |= mask
+		} else {
+			result &^= mask
+		}
+	}
+
+	return int64(result), overflow
+}
@@ -0,0 +1,180 @@
+package datatypes
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// bitmap operations on the raw bytes of strings. bit 0 is the most significant
+// bit of the first byte, like in redis.
+
+// BITOP operations
+const (
+	BitOpAnd = "AND"
+	BitOpOr  = "OR"
+	BitOpXor = "XOR"
+	BitOpNot = "NOT"
+)
+
+// sets or clears the bit at offset, growing the value as needed, and returns its previous value
+func (s *String) SetBit(offset int, on bool) int {
+	s.grow(offset/8 + 1)
+	s.Raw = true
+
+	previous := s.GetBit(offset)
+	mask := byte(1) << (7 - offset%8)
+
+	if on {
+		s.Value[offset/8] |= mask
+	} else {
+		s.Value[offset/8] &^= mask
+	}
+
+	return previous
+}
+
+// returns the bit at offset, bits past the end of the value are 0
+func (s *String) GetBit(offset int) int {
+	if offset/8 >= len(s.Value) {
+		return 0
+	}
+
+	return int(s.Value[offset/8]>>(7-offset%8)) & 1
+}
+
+// counts the set bits between start and end, inclusive offsets counted in bytes
+// or in bits with unitBit. negative offsets count from the end.
+func (s *String) BitCount(start, end int, unitBit bool) int {
+	length := len(s.Value)
+
+	if unitBit {
+		length *= 8
+	}
+
+	start, end, ok := normalizeStringRange(start, end, length)
+
+	if !ok {
+		return 0
+	}
+
+	if !unitBit {
+		return popcount(s.Value[start : end+1])
+	}
+
+	first, last := start/8, end/8
+	count := popcount(s.Value[first : last+1])
+
+	// leave out the bits of the first and last bytes that are outside the range
+	count -= bits.OnesCount8(s.Value[first] &^ (0xff >> (start % 8)))
+	count -= bits.OnesCount8(s.Value[last] & (0xff >> (end%8 + 1)))
+
+	return count
+}
+
+// returns the offset of the first bit set to bit between start and end, or -1.
+// the offsets are interpreted like in BitCount. when looking for a 0 without an end,
+// the bits past the value count as 0 so the first one after it is returned.
+func (s *String) BitPos(bit, start, end int, endGiven, unitBit bool) int {
+	length := len(s.Value)
+
+	if unitBit {
+		length *= 8
+	}
+
+	start, end, ok := normalizeStringRange(start, end, length)
+
+	if !ok {
+		return -1
+	}
+
+	if !unitBit {
+		start, end = start*8, end*8+7
+	}
+
+	// bytes that are entirely made of the bit we are not looking for
+	skip := byte(0)
+	if bit == 0 {
+		skip = 0xff
+	}
+
+	for offset := start; offset <= end; {
+		if offset%8 == 0 && offset+7 <= end && s.Value[offset/8] == skip {
+			offset += 8
+			continue
+		}
+
+		if s.GetBit(offset) == bit {
+			return offset
+		}
+
+		offset++
+	}
+
+	if bit == 0 && !endGiven {
+		return end + 1
+	}
+
+	return -1
+}
+
+// applies a BITOP operation to values, the shorter ones being padded with zero bytes
+func BitOp(op string, values [][]byte) []byte {
+	length := 0
+
+	for _, value := range values {
+		if len(value) > length {
+			length = len(value)
+		}
+	}
+
+	result := make([]byte, length)
+
+	if op == BitOpNot {
+		for i, b := range values[0] {
+			result[i] = ^b
+		}
+
+		return result
+	}
+
+	copy(result, values[0])
+
+	for _, value := range values[1:] {
+		switch op {
+		case BitOpAnd:
+			for i, b := range value {
+				result[i] &= b
+			}
+
+			// the missing bytes of a shorter value are zeros
+			for i := len(value); i < length; i++ {
+				result[i] = 0
+			}
+		case BitOpOr:
+			for i, b := range value {
+				result[i] |= b
+			}
+		case BitOpXor:
+			for i, b := range value {
+				result[i] ^= b
+			}
+		}
+	}
+
+	return result
+}
+
+// counts the set bits of b, eight bytes at a time
+func popcount(b []byte) int {
+	count := 0
+
+	for ; len(b) >= 8; b = b[8:] {
+		count += bits.OnesCount64(binary.LittleEndian.Uint64(b))
+	}
+
+	for _, x := range b {
+		count += bits.OnesCount8(x)
+	}
+
+	return count
+}
@@ -0,0 +1,233 @@
+package datatypes
+
+import "math"
+
+// GeoShape is the area searched by GEOSEARCH, a circle of Radius or a box of
+// Width by Height around the center. The sizes are expressed in the unit given
+// by the user, Conversion turning them into meters.
+type GeoShape struct {
+	Longitude  float64
+	Latitude   float64
+	ByBox      bool
+	Radius     float64
+	Width      float64
+	Height     float64
+	Conversion float64
+}
+
+// GeoPoint is a member found by GEOSEARCH, Distance is in meters from the center of the shape
+type GeoPoint struct {
+	Member    string
+	Score     float64
+	Longitude float64
+	Latitude  float64
+	Distance  float64
+}
+
+// GeoSearch returns the members within shape. The sorted set is searched in the
+// geohash box containing the center and its 8 neighbors, with a step large
+// enough for those 9 boxes to cover the whole shape. A positive limit stops the
+// search once that many members were found.
+func (z *SortedSet) GeoSearch(shape GeoShape, limit int) []GeoPoint {
+	points := []GeoPoint{}
+	boxes := shape.boxes()
+
+	last := -1
+
+	for i, box := range boxes {
+		if box.bits == 0 && box.step == 0 {
+			continue
+		}
+
+		// with huge radiuses neighbors may be the same box
+		if last >= 0 && box == boxes[last] {
+			continue
+		}
+
+		if limit > 0 && len(points) >= limit {
+			break
+		}
+
+		r := box.scoreRange()
+
+		for _, member := range z.RangeByScore(r, false, 0, -1) {
+			if point, ok := shape.contains(member.Member, member.Score); ok {
+				points = append(points, point)
+
+				if limit > 0 && len(points) >= limit {
+					break
+				}
+			}
+		}
+
+		last = i
+	}
+
+	return points
+}
+
+// returns the geohash boxes to search, zeroed when they are not needed
+func (shape GeoShape) boxes() [9]geohash {
+	minLon, minLat, maxLon, maxLat := shape.boundingBox()
+
+	var radius float64
+
+	if shape.ByBox {
+		// the distance between the center and the corners of the box
+		radius = math.Sqrt((shape.Width/2)*(shape.Width/2) + (shape.Height/2)*(shape.Height/2))
+	} else {
+		radius = shape.Radius
+	}
+
+	steps := geohashEstimateSteps(radius*shape.Conversion, shape.Latitude)
+
+	hash := geohashEncode(wgs84LonRange, wgs84LatRange, shape.Longitude, shape.Latitude, steps)
+	neighbors := hash.neighbors()
+	area := geohashDecode(wgs84LonRange, wgs84LatRange, hash)
+
+	// near the edges of the center box the estimated step may be too small for
+	// the neighbors to cover the whole shape
+	north := geohashDecode(wgs84LonRange, wgs84LatRange, neighbors.north)
+	south := geohashDecode(wgs84LonRange, wgs84LatRange, neighbors.south)
+	east := geohashDecode(wgs84LonRange, wgs84LatRange, neighbors.east)
+	west := geohashDecode(wgs84LonRange, wgs84LatRange, neighbors.west)
+
+	decreaseStep := north.latitude.max < maxLat || south.latitude.min > minLat ||
+		east.longitude.max < maxLon || west.longitude.min > minLon
+
+	if steps > 1 && decreaseStep {
+		steps--
+		hash = geohashEncode(wgs84LonRange, wgs84LatRange, shape.Longitude, shape.Latitude, steps)
+		neighbors = hash.neighbors()
+		area = geohashDecode(wgs84LonRange, wgs84LatRange, hash)
+	}
+
+	// exclude the boxes that are out of the shape
+	if steps >= 2 {
+		if area.latitude.min < minLat {
+			neighbors.south, neighbors.southWest, neighbors.southEast = geohash{}, geohash{}, geohash{}
+		}
+
+		if area.latitude.max > maxLat {
+			neighbors.north, neighbors.northEast, neighbors.northWest = geohash{}, geohash{}, geohash{}
+		}
+
+		if area.longitude.min < minLon {
+			neighbors.west, neighbors.southWest, neighbors.northWest = geohash{}, geohash{}, geohash{}
+		}
+
+		if area.longitude.max > maxLon {
+			neighbors.east, neighbors.southEast, neighbors.northEast = geohash{}, geohash{}, geohash{}
+		}
+	}
+
+	return [9]geohash{
+		hash,
+		neighbors.north,
+		neighbors.south,
+		neighbors.east,
+		neighbors.west,
+		neighbors.northEast,
+		neighbors.northWest,
+		neighbors.southEast,
+		neighbors.southWest,
+	}
+}
+
+// returns the coordinates bounding the shape
+func (shape GeoShape) boundingBox() (minLon, minLat, maxLon, maxLat float64) {
+	height, width := shape.Radius, shape.Radius
+
+	if shape.ByBox {
+		height, width = shape.Height/2, shape.Width/2
+	}
+
+	height *= shape.Conversion
+	width *= shape.Conversion
+
+	latDelta := radToDeg(height / earthRadiusInMeters)
+	lonDeltaTop := radToDeg(width / earthRadiusInMeters / math.Cos(degToRad(shape.Latitude+latDelta)))
+	lonDeltaBottom := radToDeg(width / earthRadiusInMeters / math.Cos(degToRad(shape.Latitude-latDelta)))
+
+	// the box is widest on the side closest to the equator
+	lonDelta := lonDeltaTop
+
+	if shape.Latitude < 0 {
+		lonDelta = lonDeltaBottom
+	}
+
+	return shape.Longitude - lonDelta, shape.Latitude - latDelta, shape.Longitude + lonDelta, shape.Latitude + latDelta
+}
+
+// returns the member as a GeoPoint when it lies within the shape
+func (shape GeoShape) contains(member string, score float64) (GeoPoint, bool) {
+	longitude, latitude := GeoDecode(score)
+
+	var distance float64
+
+	if shape.ByBox {
+		height := shape.Height * shape.Conversion
+		width := shape.Width * shape.Conversion
+
+		// the latitude distance is cheaper, so it is checked first
+		if geoLatDistance(latitude, shape.Latitude) > height/2 {
+			return GeoPoint{}, false
+		}
+
+		if GeoDistance(longitude, latitude, shape.Longitude, latitude) > width/2 {
+			return GeoPoint{}, false
+		}
+
+		distance = GeoDistance(shape.Longitude, shape.Latitude, longitude, latitude)
+	} else {
+		distance = GeoDistance(shape.Longitude, shape.Latitude, longitude, latitude)
+
+		if distance > shape.Radius*shape.Conversion {
+			return GeoPoint{}, false
+		}
+	}
+
+	return GeoPoint{
+		Member:    member,
+		Score:     score,
+		Longitude: longitude,
+		Latitude:  latitude,
+		Distance:  distance,
+	}, true
+}
+
+// returns the geohash step whose boxes are about as large as the searched range
+func geohashEstimateSteps(rangeMeters, latitude float64) uint {
+	if rangeMeters == 0 {
+		return GeoStepMax
+	}
+
+	step := 1
+
+	for rangeMeters < mercatorMax {
+		rangeMeters *= 2
+		step++
+	}
+
+	// make sure the range is included in most of the base cases
+	step -= 2
+
+	// boxes get narrower towards the poles
+	if latitude > 66 || latitude < -66 {
+		step--
+
+		if latitude > 80 || latitude < -80 {
+			step--
+		}
+	}
+
+	if step < 1 {
+		step = 1
+	}
+
+	if step > GeoStepMax {
+		step = GeoStepMax
+	}
+
+	return uint(step)
+}
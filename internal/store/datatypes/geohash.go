@@ -0,0 +1,258 @@
+package datatypes
+
+import "math"
+
+// geohashes interleave the bits of the latitude (even bits) and longitude (odd
+// bits) offsets within their ranges. Geo members are stored in sorted sets with
+// their 52 bits (26 steps) geohash as score, like in redis.
+const (
+	GeoStepMax = 26
+	GeoLatMin  = -85.05112878
+	GeoLatMax  = 85.05112878
+	GeoLonMin  = -180.0
+	GeoLonMax  = 180.0
+
+	earthRadiusInMeters = 6372797.560856
+	mercatorMax         = 20037726.37
+)
+
+type geohashRange struct {
+	min float64
+	max float64
+}
+
+type geohash struct {
+	bits uint64
+	step uint
+}
+
+type geohashArea struct {
+	hash      geohash
+	longitude geohashRange
+	latitude  geohashRange
+}
+
+// the 8 boxes around a geohash, in the order redis searches them
+type geohashNeighbors struct {
+	north     geohash
+	south     geohash
+	east      geohash
+	west      geohash
+	northEast geohash
+	northWest geohash
+	southEast geohash
+	southWest geohash
+}
+
+var (
+	wgs84LonRange = geohashRange{GeoLonMin, GeoLonMax}
+	wgs84LatRange = geohashRange{GeoLatMin, GeoLatMax}
+)
+
+// GeoValid reports whether the coordinates can be indexed, the latitudes
+// beyond ±85.05112878 being out of the web mercator projection
+func GeoValid(longitude, latitude float64) bool {
+	return longitude >= GeoLonMin && longitude <= GeoLonMax && latitude >= GeoLatMin && latitude <= GeoLatMax
+}
+
+// GeoScore returns the sorted set score of the coordinates
+func GeoScore(longitude, latitude float64) float64 {
+	return float64(geohashEncode(wgs84LonRange, wgs84LatRange, longitude, latitude, GeoStepMax).bits)
+}
+
+// GeoDecode returns the coordinates at the center of the geohash stored as score
+func GeoDecode(score float64) (longitude, latitude float64) {
+	return geohashDecode(wgs84LonRange, wgs84LatRange, geohash{uint64(score), GeoStepMax}).center()
+}
+
+// GeohashString returns the standard 11 characters geohash of the member stored
+// with score. redis only has 52 bits so the last character is always '0'.
+func GeohashString(score float64) string {
+	const alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+	longitude, latitude := GeoDecode(score)
+
+	// the standard geohash uses the whole [-90, 90] latitude range
+	hash := geohashEncode(geohashRange{-180, 180}, geohashRange{-90, 90}, longitude, latitude, GeoStepMax)
+	result := make([]byte, 11)
+
+	for i := 0; i < 10; i++ {
+		result[i] = alphabet[(hash.bits>>(52-(i+1)*5))&0x1f]
+	}
+
+	result[10] = alphabet[0]
+
+	return string(result)
+}
+
+// GeoDistance returns the distance in meters between two points using the haversine formula
+func GeoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	v := math.Sin((degToRad(lon2) - degToRad(lon1)) / 2)
+
+	// the points share the same meridian
+	if v == 0 {
+		return geoLatDistance(lat1, lat2)
+	}
+
+	lat1r := degToRad(lat1)
+	lat2r := degToRad(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	a := u*u + math.Cos(lat1r)*math.Cos(lat2r)*v*v
+
+	return 2 * earthRadiusInMeters * math.Asin(math.Sqrt(a))
+}
+
+func geoLatDistance(lat1, lat2 float64) float64 {
+	return earthRadiusInMeters * math.Abs(degToRad(lat2)-degToRad(lat1))
+}
+
+func degToRad(degrees float64) float64 {
+	return degrees * (math.Pi / 180)
+}
+
+func radToDeg(radians float64) float64 {
+	return radians / (math.Pi / 180)
+}
+
+func geohashEncode(lonRange, latRange geohashRange, longitude, latitude float64, step uint) geohash {
+	latOffset := (latitude - latRange.min) / (latRange.max - latRange.min)
+	lonOffset := (longitude - lonRange.min) / (lonRange.max - lonRange.min)
+
+	latOffset *= float64(uint64(1) << step)
+	lonOffset *= float64(uint64(1) << step)
+
+	return geohash{interleave64(uint32(latOffset), uint32(lonOffset)), step}
+}
+
+func geohashDecode(lonRange, latRange geohashRange, hash geohash) geohashArea {
+	separated := deinterleave64(hash.bits)
+	latScale := latRange.max - latRange.min
+	lonScale := lonRange.max - lonRange.min
+
+	lat := float64(uint32(separated))
+	lon := float64(uint32(separated >> 32))
+	cells := float64(uint64(1) << hash.step)
+
+	return geohashArea{
+		hash: hash,
+		latitude: geohashRange{
+			min: latRange.min + (lat/cells)*latScale,
+			max: latRange.min + ((lat+1)/cells)*latScale,
+		},
+		longitude: geohashRange{
+			min: lonRange.min + (lon/cells)*lonScale,
+			max: lonRange.min + ((lon+1)/cells)*lonScale,
+		},
+	}
+}
+
+// returns the center of the area, clamped to the valid coordinates
+func (area geohashArea) center() (longitude, latitude float64) {
+	longitude = (area.longitude.min + area.longitude.max) / 2
+	latitude = (area.latitude.min + area.latitude.max) / 2
+
+	longitude = math.Max(GeoLonMin, math.Min(GeoLonMax, longitude))
+	latitude = math.Max(GeoLatMin, math.Min(GeoLatMax, latitude))
+
+	return longitude, latitude
+}
+
+// the range of scores covered by the box of the geohash, the max being exclusive
+func (hash geohash) scoreRange() ScoreRange {
+	shift := 52 - hash.step*2
+
+	return ScoreRange{
+		Min:          float64(hash.bits << shift),
+		Max:          float64((hash.bits + 1) << shift),
+		MaxExclusive: true,
+	}
+}
+
+func (hash geohash) neighbors() geohashNeighbors {
+	return geohashNeighbors{
+		north:     hash.move(0, 1),
+		south:     hash.move(0, -1),
+		east:      hash.move(1, 0),
+		west:      hash.move(-1, 0),
+		northEast: hash.move(1, 1),
+		northWest: hash.move(-1, 1),
+		southEast: hash.move(1, -1),
+		southWest: hash.move(-1, -1),
+	}
+}
+
+// returns the adjacent box dx boxes east and dy boxes north, wrapping around
+func (hash geohash) move(dx, dy int) geohash {
+	const oddBits = 0xaaaaaaaaaaaaaaaa
+	const evenBits = 0x5555555555555555
+
+	x := hash.bits & oddBits
+	y := hash.bits & evenBits
+
+	if dx != 0 {
+		zz := uint64(evenBits) >> (64 - hash.step*2)
+
+		if dx > 0 {
+			x += zz + 1
+		} else {
+			x |= zz
+			x -= zz + 1
+		}
+
+		x &= oddBits >> (64 - hash.step*2)
+	}
+
+	if dy != 0 {
+		zz := uint64(oddBits) >> (64 - hash.step*2)
+
+		if dy > 0 {
+			y += zz + 1
+		} else {
+			y |= zz
+			y -= zz + 1
+		}
+
+		y &= evenBits >> (64 - hash.step*2)
+	}
+
+	return geohash{x | y, hash.step}
+}
+
+// interleaves the bits of x (even positions) and y (odd positions)
+func interleave64(xlo, ylo uint32) uint64 {
+	x := spreadBits(uint64(xlo))
+	y := spreadBits(uint64(ylo))
+
+	return x | y<<1
+}
+
+// the reverse of interleave64, x in the low 32 bits and y in the high ones
+func deinterleave64(interleaved uint64) uint64 {
+	x := squashBits(interleaved)
+	y := squashBits(interleaved >> 1)
+
+	return x | y<<32
+}
+
+// moves the low 32 bits of v to the even positions
+func spreadBits(v uint64) uint64 {
+	v = (v | v<<16) & 0x0000FFFF0000FFFF
+	v = (v | v<<8) & 0x00FF00FF00FF00FF
+	v = (v | v<<4) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v<<2) & 0x3333333333333333
+	v = (v | v<<1) & 0x5555555555555555
+
+	return v
+}
+
+// moves the even bits of v to the low 32 bits
+func squashBits(v uint64) uint64 {
+	v &= 0x5555555555555555
+	v = (v | v>>1) & 0x3333333333333333
+	v = (v | v>>2) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v>>4) & 0x00FF00FF00FF00FF
+	v = (v | v>>8) & 0x0000FFFF0000FFFF
+	v = (v | v>>16) & 0x00000000FFFFFFFF
+
+	return v
+}
@@ -0,0 +1,298 @@
+package datatypes
+
+import "time"
+
+// Hash is a field value map where each field can have its own expiry.
+// Expired fields are invisible to every read, and are physically removed
+// by DeleteExpired, which the store runs both lazily and in the background.
+// Small hashes keep their fields and values in a listpack, and are converted
+// to a map once they grow past hash-max-listpack-entries or hold a field or
+// value longer than hash-max-listpack-value.
+type Hash struct {
+	DataType string
+	lp       *listpack // field value pairs
+	fields   map[string]string
+	index    *ScanIndex           // the fields in HSCAN order, along with fields
+	expires  map[string]time.Time // allocated with the first field expiry
+}
+
+func NewHash() *Hash {
+	return &Hash{
+		DataType: "hash",
+		lp:       &listpack{},
+	}
+}
+
+func (h *Hash) GetType() string {
+	return h.DataType
+}
+
+func (h *Hash) Encoding() string {
+	if h.lp == nil {
+		return "hashtable"
+	}
+
+	if len(h.expires) > 0 {
+		return "listpackex"
+	}
+
+	return "listpack"
+}
+
+func (h *Hash) Len() int {
+	length := len(h.fields)
+
+	if h.lp != nil {
+		length = h.lp.len() / 2
+	}
+	now := time.Now()
+
+	for _, expiry := range h.expires {
+		if !now.Before(expiry) {
+			length--
+		}
+	}
+
+	return length
+}
+
+func (h *Hash) Get(field string) (string, bool) {
+	if h.isExpired(field) {
+		return "", false
+	}
+
+	return h.lookup(field)
+}
+
+// sets the value and clears any expiry of the field.
+// returns true when the field did not exist before.
+func (h *Hash) Set(field, value string) bool {
+	_, exists := h.Get(field)
+	h.store(field, value)
+	delete(h.expires, field)
+
+	return !exists
+}
+
+// like Set but keeps the expiry of an existing field, used by HINCRBY and friends
+func (h *Hash) Update(field, value string) {
+	if h.isExpired(field) {
+		delete(h.expires, field)
+	}
+
+	h.store(field, value)
+}
+
+// returns true when the field existed
+func (h *Hash) Delete(field string) bool {
+	_, exists := h.Get(field)
+	h.remove(field)
+	delete(h.expires, field)
+
+	return exists
+}
+
+func (h *Hash) Fields() []string {
+	fields := make([]string, 0, h.Len())
+
+	if h.lp != nil {
+		entries := h.lp.entries()
+
+		for i := 0; i < len(entries); i += 2 {
+			if !h.isExpired(entries[i]) {
+				fields = append(fields, entries[i])
+			}
+		}
+
+		return fields
+	}
+
+	for field := range h.fields {
+		if !h.isExpired(field) {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// returns the fields from cursor on, at least count unless the iteration is
+// over, and the cursor to resume from, 0 once it is. like redis, the listpack
+// encoding returns every field at once.
+func (h *Hash) Scan(cursor uint64, count int) ([]string, uint64) {
+	if h.lp != nil {
+		return h.Fields(), 0
+	}
+
+	fields, next := h.index.collect(cursor, count)
+	live := fields[:0]
+
+	for _, field := range fields {
+		if !h.isExpired(field) {
+			live = append(live, field)
+		}
+	}
+
+	return live, next
+}
+
+// returns the expiry of the field, false when the field does not expire
+func (h *Hash) Expiry(field string) (time.Time, bool) {
+	expiry, ok := h.expires[field]
+	return expiry, ok
+}
+
+func (h *Hash) SetExpiry(field string, expiry time.Time) {
+	if h.expires == nil {
+		h.expires = make(map[string]time.Time)
+	}
+
+	h.expires[field] = expiry
+}
+
+// removes the expiry of the field, returns false when it had none
+func (h *Hash) Persist(field string) bool {
+	_, ok := h.expires[field]
+	delete(h.expires, field)
+
+	return ok
+}
+
+// number of fields with an expiry, including the ones not reclaimed yet
+func (h *Hash) VolatileLen() int {
+	return len(h.expires)
+}
+
+// whether a field not expired yet has an expiry
+func (h *Hash) HasVolatileFields() bool {
+	for field := range h.expires {
+		if !h.isExpired(field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removes the expired fields and returns them
+func (h *Hash) DeleteExpired() []string {
+	removed := []string{}
+
+	for field := range h.expires {
+		if h.isExpired(field) {
+			h.remove(field)
+			delete(h.expires, field)
+			removed = append(removed, field)
+		}
+	}
+
+	return removed
+}
+
+func (h *Hash) isExpired(field string) bool {
+	expiry, ok := h.expires[field]
+	return ok && !time.Now().Before(expiry)
+}
+
+// returns the value of the field, expired or not
+func (h *Hash) lookup(field string) (string, bool) {
+	if h.lp != nil {
+		offset := h.lp.find(field, 2)
+
+		if offset < 0 {
+			return "", false
+		}
+
+		_, offset = h.lp.entry(offset)
+		value, _ := h.lp.entry(offset)
+
+		return string(value), true
+	}
+
+	value, ok := h.fields[field]
+	return value, ok
+}
+
+// sets the value of the field, leaving its expiry alone
+func (h *Hash) store(field, value string) {
+	if h.lp != nil {
+		limit := thresholds().HashMaxListpackValue
+
+		if len(field) > limit || len(value) > limit {
+			h.convertToHashTable()
+		}
+	}
+
+	if h.lp == nil {
+		if _, ok := h.fields[field]; !ok {
+			h.index.Add(field)
+		}
+
+		h.fields[field] = value
+		return
+	}
+
+	if offset := h.lp.find(field, 2); offset >= 0 {
+		_, offset = h.lp.entry(offset)
+		h.lp.replace(offset, value)
+		return
+	}
+
+	h.lp.insert(h.lp.end(), field, value)
+
+	if h.lp.len()/2 > thresholds().HashMaxListpackEntries {
+		h.convertToHashTable()
+	}
+}
+
+// removes the field, leaving its expiry alone
+func (h *Hash) remove(field string) {
+	if h.lp == nil {
+		if _, ok := h.fields[field]; ok {
+			delete(h.fields, field)
+			h.index.Remove(field)
+		}
+
+		return
+	}
+
+	if offset := h.lp.find(field, 2); offset >= 0 {
+		h.lp.delete(offset, 2)
+	}
+}
+
+func (h *Hash) convertToHashTable() {
+	entries := h.lp.entries()
+	h.fields = make(map[string]string, len(entries)/2)
+	h.index = NewScanIndex()
+
+	for i := 0; i < len(entries); i += 2 {
+		h.fields[entries[i]] = entries[i+1]
+		h.index.Add(entries[i])
+	}
+
+	h.lp = nil
+}
+
+// Copy returns a copy of the hash, keeping its encoding and field expiries
+func (h *Hash) Copy() *Hash {
+	c := &Hash{DataType: h.DataType}
+
+	if h.lp != nil {
+		c.lp = h.lp.copy()
+	} else {
+		c.fields = make(map[string]string, len(h.fields))
+		c.index = NewScanIndex()
+
+		for field, value := range h.fields {
+			c.fields[field] = value
+			c.index.Add(field)
+		}
+	}
+
+	for field, expiry := range h.expires {
+		c.SetExpiry(field, expiry)
+	}
+
+	return c
+}
@@ -0,0 +1,714 @@
+package datatypes
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// HyperLogLogs are plain strings laid out exactly like the redis ones, so they
+// survive RDB files and GET/SET round trips: a 16 bytes header ("HYLL", the
+// encoding, 3 unused bytes and the cached cardinality as a little endian
+// uint64 whose most significant bit flags it as stale) followed by the
+// registers, either dense (16384 registers of 6 bits) or sparse (run length
+// encoded with the ZERO, XZERO and VAL opcodes).
+const (
+	hllP           = 14
+	hllQ           = 64 - hllP
+	hllRegisters   = 1 << hllP
+	hllPMask       = hllRegisters - 1
+	hllBits        = 6
+	hllRegisterMax = 1<<hllBits - 1
+	hllHeaderSize  = 16
+	hllDenseSize   = hllHeaderSize + (hllRegisters*hllBits+7)/8
+	hllAlphaInf    = 0.721347520444481703680 // 0.5/ln(2)
+
+	hllDense       = 0
+	hllSparse      = 1
+	hllMaxEncoding = hllSparse
+
+	hllSparseXZeroBit      = 0x40 // 01xxxxxx yyyyyyyy
+	hllSparseValBit        = 0x80 // 1vvvvvxx
+	hllSparseValMaxValue   = 32
+	hllSparseValMaxLen     = 4
+	hllSparseZeroMaxLen    = 64
+	hllSparseXZeroMaxLen   = 16384
+	hllCardinalityStaleBit = 1 << 7
+)
+
+// HLLSparseMaxBytes is the size above which a sparse HyperLogLog is converted
+// to the dense encoding, as hll-sparse-max-bytes in redis
+var HLLSparseMaxBytes = 3000
+
+var (
+	ErrInvalidHLL   = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	ErrCorruptedHLL = errors.New("INVALIDOBJ Corrupted HLL object detected")
+)
+
+// NewHyperLogLog returns an empty HyperLogLog using the sparse encoding
+func NewHyperLogLog() *String {
+	value := make([]byte, hllHeaderSize, hllHeaderSize+2)
+	copy(value, "HYLL")
+	value[4] = hllSparse
+
+	// a single XZERO opcode covers every register
+	value = appendSparseXZero(value, hllRegisters)
+
+	return &String{DataType: "string", Value: value, Raw: true}
+}
+
+// IsHyperLogLog reports whether the string holds a HyperLogLog header and,
+// when dense, the right amount of registers
+func (s *String) IsHyperLogLog() bool {
+	if len(s.Value) < hllHeaderSize || string(s.Value[:4]) != "HYLL" || s.Value[4] > hllMaxEncoding {
+		return false
+	}
+
+	return s.Value[4] != hllDense || len(s.Value) == hllDenseSize
+}
+
+func (s *String) HLLEncoding() string {
+	if s.Value[4] == hllDense {
+		return "dense"
+	}
+
+	return "sparse"
+}
+
+// HLLAdd adds element to the HyperLogLog, returning whether a register changed
+func (s *String) HLLAdd(element string) (bool, error) {
+	index, count := hllPatternLength(element)
+
+	var updated bool
+	var err error
+
+	if s.Value[4] == hllDense {
+		updated = hllDenseSet(s.Value[hllHeaderSize:], index, count)
+	} else {
+		updated, err = s.hllSparseSet(index, count)
+	}
+
+	if updated {
+		s.invalidateHLLCardinality()
+	}
+
+	return updated, err
+}
+
+// HLLCount returns the estimated cardinality, using and refreshing the cached one
+func (s *String) HLLCount() (uint64, error) {
+	card := s.Value[8:hllHeaderSize]
+
+	if card[7]&hllCardinalityStaleBit == 0 {
+		return binary.LittleEndian.Uint64(card), nil
+	}
+
+	var histogram [hllQ + 2]int
+
+	if s.Value[4] == hllDense {
+		hllDenseHistogram(s.Value[hllHeaderSize:], &histogram)
+	} else if !hllSparseHistogram(s.Value[hllHeaderSize:], &histogram) {
+		return 0, ErrCorruptedHLL
+	}
+
+	count := hllEstimate(&histogram)
+	binary.LittleEndian.PutUint64(card, count)
+
+	return count, nil
+}
+
+// HLLCountUnion estimates the cardinality of the union of the HyperLogLogs
+// without modifying any of them
+func HLLCountUnion(hlls []*String) (uint64, error) {
+	registers := make([]uint8, hllRegisters)
+
+	for _, hll := range hlls {
+		if err := hll.hllMergeInto(registers); err != nil {
+			return 0, err
+		}
+	}
+
+	var histogram [hllQ + 2]int
+
+	for _, register := range registers {
+		histogram[register]++
+	}
+
+	return hllEstimate(&histogram), nil
+}
+
+// HLLMerge sets every register of s to the maximum of itself and the sources.
+// s turns dense when any of the sources is dense.
+func (s *String) HLLMerge(sources []*String) error {
+	registers := make([]uint8, hllRegisters)
+	dense := false
+
+	for _, hll := range append([]*String{s}, sources...) {
+		if hll.Value[4] == hllDense {
+			dense = true
+		}
+
+		if err := hll.hllMergeInto(registers); err != nil {
+			return err
+		}
+	}
+
+	if dense {
+		if _, err := s.HLLToDense(); err != nil {
+			return err
+		}
+	}
+
+	for index, count := range registers {
+		if count == 0 {
+			continue
+		}
+
+		if s.Value[4] == hllDense {
+			hllDenseSet(s.Value[hllHeaderSize:], index, count)
+		} else if _, err := s.hllSparseSet(index, count); err != nil {
+			return err
+		}
+	}
+
+	s.invalidateHLLCardinality()
+
+	return nil
+}
+
+// HLLToDense converts a sparse HyperLogLog to the dense encoding, returning
+// false when it already was dense
+func (s *String) HLLToDense() (bool, error) {
+	if s.Value[4] == hllDense {
+		return false, nil
+	}
+
+	dense := make([]byte, hllDenseSize)
+	copy(dense, s.Value[:hllHeaderSize])
+	dense[4] = hllDense
+
+	registers := dense[hllHeaderSize:]
+	index := 0
+	sparse := s.Value[hllHeaderSize:]
+
+	for p := 0; p < len(sparse); {
+		switch {
+		case isSparseZero(sparse[p]):
+			index += sparseZeroLen(sparse[p])
+			p++
+		case isSparseXZero(sparse[p]):
+			if p+1 >= len(sparse) {
+				return false, ErrCorruptedHLL
+			}
+
+			index += sparseXZeroLen(sparse[p], sparse[p+1])
+			p += 2
+		default:
+			runLength, value := sparseValLen(sparse[p]), sparseValValue(sparse[p])
+
+			if index+runLength > hllRegisters {
+				return false, ErrCorruptedHLL
+			}
+
+			for ; runLength > 0; runLength-- {
+				hllDenseSetRegister(registers, index, value)
+				index++
+			}
+
+			p++
+		}
+	}
+
+	if index != hllRegisters {
+		return false, ErrCorruptedHLL
+	}
+
+	s.Value = dense
+
+	return true, nil
+}
+
+// HLLRegisters returns the value of every register, converting s to the dense encoding
+func (s *String) HLLRegisters() ([]int, error) {
+	if _, err := s.HLLToDense(); err != nil {
+		return nil, err
+	}
+
+	registers := make([]int, hllRegisters)
+
+	for i := range registers {
+		registers[i] = int(hllDenseGetRegister(s.Value[hllHeaderSize:], i))
+	}
+
+	return registers, nil
+}
+
+// HLLDecode describes the opcodes of a sparse HyperLogLog, e.g. "Z:100 v:3,1 z:5"
+func (s *String) HLLDecode() (string, error) {
+	if s.Value[4] != hllSparse {
+		return "", errors.New("ERR HLL encoding is not sparse")
+	}
+
+	var opcodes []string
+	sparse := s.Value[hllHeaderSize:]
+
+	for p := 0; p < len(sparse); p++ {
+		switch {
+		case isSparseZero(sparse[p]):
+			opcodes = append(opcodes, fmt.Sprintf("z:%d", sparseZeroLen(sparse[p])))
+		case isSparseXZero(sparse[p]):
+			if p+1 >= len(sparse) {
+				return "", ErrCorruptedHLL
+			}
+
+			opcodes = append(opcodes, fmt.Sprintf("Z:%d", sparseXZeroLen(sparse[p], sparse[p+1])))
+			p++
+		default:
+			opcodes = append(opcodes, fmt.Sprintf("v:%d,%d", sparseValValue(sparse[p]), sparseValLen(sparse[p])))
+		}
+	}
+
+	return strings.Join(opcodes, " "), nil
+}
+
+func (s *String) invalidateHLLCardinality() {
+	s.Value[hllHeaderSize-1] |= hllCardinalityStaleBit
+}
+
+// sets registers to the maximum of themselves and the registers of s
+func (s *String) hllMergeInto(registers []uint8) error {
+	if s.Value[4] == hllDense {
+		for i := range registers {
+			if value := hllDenseGetRegister(s.Value[hllHeaderSize:], i); value > registers[i] {
+				registers[i] = value
+			}
+		}
+
+		return nil
+	}
+
+	index := 0
+	sparse := s.Value[hllHeaderSize:]
+
+	for p := 0; p < len(sparse); {
+		switch {
+		case isSparseZero(sparse[p]):
+			index += sparseZeroLen(sparse[p])
+			p++
+		case isSparseXZero(sparse[p]):
+			if p+1 >= len(sparse) {
+				return ErrCorruptedHLL
+			}
+
+			index += sparseXZeroLen(sparse[p], sparse[p+1])
+			p += 2
+		default:
+			runLength, value := sparseValLen(sparse[p]), sparseValValue(sparse[p])
+
+			if index+runLength > hllRegisters {
+				return ErrCorruptedHLL
+			}
+
+			for ; runLength > 0; runLength-- {
+				if value > registers[index] {
+					registers[index] = value
+				}
+
+				index++
+			}
+
+			p++
+		}
+	}
+
+	if index != hllRegisters {
+		return ErrCorruptedHLL
+	}
+
+	return nil
+}
+
+// sets the register at index to count when it is higher than its current value
+// in a sparse HyperLogLog, splitting the opcode covering the register. Like in
+// redis the HyperLogLog is converted to the dense encoding once a value does not
+// fit a VAL opcode or the encoding grows beyond HLLSparseMaxBytes.
+func (s *String) hllSparseSet(index int, count uint8) (bool, error) {
+	if count > hllSparseValMaxValue {
+		return s.hllPromoteAndSet(index, count)
+	}
+
+	sparse := s.Value[hllHeaderSize:]
+
+	// find the opcode covering index, and the one before it
+	p, prev, first, span := 0, -1, 0, 0
+
+	for p < len(sparse) {
+		opLength := 1
+
+		switch {
+		case isSparseZero(sparse[p]):
+			span = sparseZeroLen(sparse[p])
+		case isSparseXZero(sparse[p]):
+			if p+1 >= len(sparse) {
+				return false, ErrCorruptedHLL
+			}
+
+			span = sparseXZeroLen(sparse[p], sparse[p+1])
+			opLength = 2
+		default:
+			span = sparseValLen(sparse[p])
+		}
+
+		if index <= first+span-1 {
+			break
+		}
+
+		prev = p
+		p += opLength
+		first += span
+	}
+
+	if span == 0 || p >= len(sparse) {
+		return false, ErrCorruptedHLL
+	}
+
+	opcode := sparse[p]
+	last := first + span - 1
+
+	switch {
+	case !isSparseZero(opcode) && !isSparseXZero(opcode):
+		if sparseValValue(opcode) >= count {
+			return false, nil
+		}
+
+		if span == 1 {
+			sparse[p] = sparseVal(count, 1)
+			s.hllSparseMergeValues(prev)
+
+			return true, nil
+		}
+	case isSparseZero(opcode) && span == 1:
+		sparse[p] = sparseVal(count, 1)
+		s.hllSparseMergeValues(prev)
+
+		return true, nil
+	}
+
+	// general case, the opcode is replaced by up to 3 opcodes (5 bytes)
+	sequence := make([]byte, 0, 5)
+
+	if isSparseZero(opcode) || isSparseXZero(opcode) {
+		if index != first {
+			sequence = appendSparseZero(sequence, index-first)
+		}
+
+		sequence = append(sequence, sparseVal(count, 1))
+
+		if index != last {
+			sequence = appendSparseZero(sequence, last-index)
+		}
+	} else {
+		value := sparseValValue(opcode)
+
+		if index != first {
+			sequence = append(sequence, sparseVal(value, index-first))
+		}
+
+		sequence = append(sequence, sparseVal(count, 1))
+
+		if index != last {
+			sequence = append(sequence, sparseVal(value, last-index))
+		}
+	}
+
+	oldLength := 1
+
+	if isSparseXZero(opcode) {
+		oldLength = 2
+	}
+
+	if len(sequence) > oldLength && len(s.Value)+len(sequence)-oldLength > HLLSparseMaxBytes {
+		return s.hllPromoteAndSet(index, count)
+	}
+
+	value := make([]byte, 0, len(s.Value)+len(sequence)-oldLength)
+	value = append(value, s.Value[:hllHeaderSize+p]...)
+	value = append(value, sequence...)
+	value = append(value, s.Value[hllHeaderSize+p+oldLength:]...)
+	s.Value = value
+
+	s.hllSparseMergeValues(prev)
+
+	return true, nil
+}
+
+// merges adjacent VAL opcodes holding the same value, scanning up to 5 opcodes
+// from the one at p (the start of the registers when p is negative)
+func (s *String) hllSparseMergeValues(p int) {
+	if p < 0 {
+		p = 0
+	}
+
+	p += hllHeaderSize
+
+	for scan := 5; p < len(s.Value) && scan > 0; scan-- {
+		switch {
+		case isSparseXZero(s.Value[p]):
+			p += 2
+			continue
+		case isSparseZero(s.Value[p]):
+			p++
+			continue
+		}
+
+		if p+1 < len(s.Value) && !isSparseZero(s.Value[p+1]) && !isSparseXZero(s.Value[p+1]) {
+			value := sparseValValue(s.Value[p])
+			length := sparseValLen(s.Value[p]) + sparseValLen(s.Value[p+1])
+
+			if value == sparseValValue(s.Value[p+1]) && length <= hllSparseValMaxLen {
+				s.Value[p+1] = sparseVal(value, length)
+				s.Value = append(s.Value[:p], s.Value[p+1:]...)
+
+				// try to merge the merged opcode with the one on its right
+				continue
+			}
+		}
+
+		p++
+	}
+}
+
+func (s *String) hllPromoteAndSet(index int, count uint8) (bool, error) {
+	if _, err := s.HLLToDense(); err != nil {
+		return false, err
+	}
+
+	return hllDenseSet(s.Value[hllHeaderSize:], index, count), nil
+}
+
+// returns the register selected by the hash of element, and the position of the
+// first set bit of the rest of the hash, which is the value for that register
+func hllPatternLength(element string) (int, uint8) {
+	hash := murmurHash64A([]byte(element), 0xadc83b19)
+	index := int(hash & hllPMask)
+
+	hash >>= hllP
+	hash |= 1 << hllQ // makes sure count is at most Q+1
+
+	count := uint8(1)
+
+	for bit := uint64(1); hash&bit == 0; bit <<= 1 {
+		count++
+	}
+
+	return index, count
+}
+
+// MurmurHash2, 64-bit version by Austin Appleby, as used by redis
+func murmurHash64A(key []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+
+	h := seed ^ (uint64(len(key)) * m)
+	blocks := len(key) - len(key)%8
+
+	for i := 0; i < blocks; i += 8 {
+		k := binary.LittleEndian.Uint64(key[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+
+		h ^= k
+		h *= m
+	}
+
+	tail := key[blocks:]
+
+	if len(tail) > 0 {
+		for i := len(tail) - 1; i >= 0; i-- {
+			h ^= uint64(tail[i]) << (8 * i)
+		}
+
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+
+	return h
+}
+
+// registers are 6 bits wide, packed from the least significant bit of each byte
+func hllDenseGetRegister(registers []byte, index int) uint8 {
+	byteIndex := index * hllBits / 8
+	firstBit := uint(index * hllBits & 7)
+
+	value := uint(registers[byteIndex]) >> firstBit
+
+	if byteIndex+1 < len(registers) {
+		value |= uint(registers[byteIndex+1]) << (8 - firstBit)
+	}
+
+	return uint8(value & hllRegisterMax)
+}
+
+func hllDenseSetRegister(registers []byte, index int, value uint8) {
+	byteIndex := index * hllBits / 8
+	firstBit := uint(index * hllBits & 7)
+
+	registers[byteIndex] &^= byte(hllRegisterMax << firstBit)
+	registers[byteIndex] |= byte(uint(value) << firstBit)
+
+	if byteIndex+1 < len(registers) {
+		registers[byteIndex+1] &^= byte(hllRegisterMax >> (8 - firstBit))
+		registers[byteIndex+1] |= byte(uint(value) >> (8 - firstBit))
+	}
+}
+
+func hllDenseSet(registers []byte, index int, count uint8) bool {
+	if hllDenseGetRegister(registers, index) >= count {
+		return false
+	}
+
+	hllDenseSetRegister(registers, index, count)
+
+	return true
+}
+
+func hllDenseHistogram(registers []byte, histogram *[hllQ + 2]int) {
+	for i := 0; i < hllRegisters; i++ {
+		histogram[hllDenseGetRegister(registers, i)]++
+	}
+}
+
+// returns false when the opcodes do not cover exactly every register
+func hllSparseHistogram(sparse []byte, histogram *[hllQ + 2]int) bool {
+	index := 0
+
+	for p := 0; p < len(sparse); p++ {
+		switch {
+		case isSparseZero(sparse[p]):
+			runLength := sparseZeroLen(sparse[p])
+			index += runLength
+			histogram[0] += runLength
+		case isSparseXZero(sparse[p]):
+			if p+1 >= len(sparse) {
+				return false
+			}
+
+			runLength := sparseXZeroLen(sparse[p], sparse[p+1])
+			index += runLength
+			histogram[0] += runLength
+			p++
+		default:
+			runLength := sparseValLen(sparse[p])
+			index += runLength
+			histogram[sparseValValue(sparse[p])] += runLength
+		}
+	}
+
+	return index == hllRegisters
+}
+
+// the improved estimator of Otmar Ertl's "New cardinality estimation algorithms
+// for HyperLogLog sketches", as implemented by redis
+func hllEstimate(histogram *[hllQ + 2]int) uint64 {
+	m := float64(hllRegisters)
+
+	z := m * hllTau((m-float64(histogram[hllQ+1]))/m)
+
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histogram[j])
+		z *= 0.5
+	}
+
+	z += m * hllSigma(float64(histogram[0])/m)
+
+	return uint64(math.Round(hllAlphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+
+	y := 1.0
+	z := x
+
+	for {
+		x *= x
+		previous := z
+		z += x * y
+		y += y
+
+		if previous == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+
+	y := 1.0
+	z := 1 - x
+
+	for {
+		x = math.Sqrt(x)
+		previous := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+
+		if previous == z {
+			return z / 3
+		}
+	}
+}
+
+func isSparseZero(opcode byte) bool {
+	return opcode&0xc0 == 0
+}
+
+func isSparseXZero(opcode byte) bool {
+	return opcode&0xc0 == hllSparseXZeroBit
+}
+
+func sparseZeroLen(opcode byte) int {
+	return int(opcode&0x3f) + 1
+}
+
+func sparseXZeroLen(opcode, next byte) int {
+	return (int(opcode&0x3f)<<8 | int(next)) + 1
+}
+
+func sparseValValue(opcode byte) uint8 {
+	return (opcode>>2)&0x1f + 1
+}
+
+func sparseValLen(opcode byte) int {
+	return int(opcode&0x3) + 1
+}
+
+func sparseVal(value uint8, length int) byte {
+	return (value-1)<<2 | byte(length-1) | hllSparseValBit
+}
+
+// appends a ZERO opcode, or an XZERO one when the run is too long for ZERO
+func appendSparseZero(sparse []byte, length int) []byte {
+	if length > hllSparseZeroMaxLen {
+		return appendSparseXZero(sparse, length)
+	}
+
+	return append(sparse, byte(length-1))
+}
+
+func appendSparseXZero(sparse []byte, length int) []byte {
+	length--
+
+	return append(sparse, byte(length>>8)|hllSparseXZeroBit, byte(length&0xff))
+}
@@ -0,0 +1,145 @@
+package datatypes
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// intset is a sorted array of integers packed in a byte slice using the smallest
+// width (2, 4 or 8 bytes) able to hold every member, like the redis intset. The
+// width only grows: adding a member that does not fit upgrades the whole set.
+type intset struct {
+	width    int
+	contents []byte
+}
+
+func newIntset() *intset {
+	return &intset{width: 2}
+}
+
+func (is *intset) len() int {
+	return len(is.contents) / is.width
+}
+
+func (is *intset) get(i int) int64 {
+	b := is.contents[i*is.width:]
+
+	switch is.width {
+	case 2:
+		return int64(int16(binary.LittleEndian.Uint16(b)))
+	case 4:
+		return int64(int32(binary.LittleEndian.Uint32(b)))
+	default:
+		return int64(binary.LittleEndian.Uint64(b))
+	}
+}
+
+func (is *intset) set(i int, value int64) {
+	b := is.contents[i*is.width:]
+
+	switch is.width {
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(value))
+	case 4:
+		binary.LittleEndian.PutUint32(b, uint32(value))
+	default:
+		binary.LittleEndian.PutUint64(b, uint64(value))
+	}
+}
+
+// returns the position of value, or where it would have to be inserted
+func (is *intset) search(value int64) (int, bool) {
+	low, high := 0, is.len()-1
+
+	for low <= high {
+		mid := (low + high) / 2
+		current := is.get(mid)
+
+		if current == value {
+			return mid, true
+		}
+
+		if current < value {
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+
+	return low, false
+}
+
+func (is *intset) contains(value int64) bool {
+	_, found := is.search(value)
+	return found
+}
+
+// returns false when the value was already a member
+func (is *intset) add(value int64) bool {
+	if width := intsetWidth(value); width > is.width {
+		is.upgrade(width)
+	}
+
+	pos, found := is.search(value)
+
+	if found {
+		return false
+	}
+
+	length := is.len()
+	is.contents = append(is.contents, make([]byte, is.width)...)
+	copy(is.contents[(pos+1)*is.width:], is.contents[pos*is.width:length*is.width])
+	is.set(pos, value)
+
+	return true
+}
+
+// returns false when the value was not a member
+func (is *intset) remove(value int64) bool {
+	pos, found := is.search(value)
+
+	if !found {
+		return false
+	}
+
+	copy(is.contents[pos*is.width:], is.contents[(pos+1)*is.width:])
+	is.contents = is.contents[:len(is.contents)-is.width]
+
+	return true
+}
+
+func (is *intset) upgrade(width int) {
+	old := *is
+	is.width = width
+	is.contents = make([]byte, old.len()*width)
+
+	for i := 0; i < old.len(); i++ {
+		is.set(i, old.get(i))
+	}
+}
+
+func intsetWidth(value int64) int {
+	if value >= math.MinInt16 && value <= math.MaxInt16 {
+		return 2
+	}
+
+	if value >= math.MinInt32 && value <= math.MaxInt32 {
+		return 4
+	}
+
+	return 8
+}
+
+// parses member as an integer only when it is the canonical representation of
+// one, so that converting it back gives the exact same string ("01" or "+1" are
+// kept as strings)
+func parseCanonicalInt(member string) (int64, bool) {
+	value, err := strconv.ParseInt(member, 10, 64)
+
+	if err != nil || strconv.FormatInt(value, 10) != member {
+		return 0, false
+	}
+
+	return value, true
+}
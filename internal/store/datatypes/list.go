@@ -0,0 +1,463 @@
+package datatypes
+
+// List is a double ended queue. Small lists are packed in a listpack, and
+// moved to a ring buffer once they grow past list-max-listpack-size, so pushes
+// and pops on both ends are O(1) and indexing does not need to walk any nodes.
+type List struct {
+	DataType string
+	lp       *listpack
+	buf      []string
+	head     int
+	size     int
+}
+
+const minListCapacity = 8
+
+func NewList() *List {
+	return &List{
+		DataType: "list",
+		lp:       &listpack{},
+	}
+}
+
+func (l *List) GetType() string {
+	return l.DataType
+}
+
+func (l *List) Encoding() string {
+	if l.lp != nil {
+		return "listpack"
+	}
+
+	return "quicklist"
+}
+
+func (l *List) Len() int {
+	if l.lp != nil {
+		return l.lp.len()
+	}
+
+	return l.size
+}
+
+// values are pushed one after the other, so LPUSH a b c results in c b a
+func (l *List) PushLeft(values ...string) {
+	l.convertIfTooBig(values)
+
+	if l.lp != nil {
+		for _, value := range values {
+			l.lp.insert(0, value)
+		}
+
+		return
+	}
+
+	for _, value := range values {
+		l.grow()
+		l.head = (l.head - 1 + len(l.buf)) % len(l.buf)
+		l.buf[l.head] = value
+		l.size++
+	}
+}
+
+func (l *List) PushRight(values ...string) {
+	l.convertIfTooBig(values)
+
+	if l.lp != nil {
+		l.lp.insert(l.lp.end(), values...)
+		return
+	}
+
+	for _, value := range values {
+		l.grow()
+		l.buf[l.at(l.size)] = value
+		l.size++
+	}
+}
+
+func (l *List) PopLeft() (string, bool) {
+	if l.Len() == 0 {
+		return "", false
+	}
+
+	if l.lp != nil {
+		data, _ := l.lp.entry(0)
+		value := string(data)
+		l.lp.delete(0, 1)
+
+		return value, true
+	}
+
+	value := l.buf[l.head]
+	l.buf[l.head] = ""
+	l.head = (l.head + 1) % len(l.buf)
+	l.size--
+	l.shrink()
+
+	return value, true
+}
+
+func (l *List) PopRight() (string, bool) {
+	if l.Len() == 0 {
+		return "", false
+	}
+
+	if l.lp != nil {
+		offset := l.lp.prev(l.lp.end())
+		data, _ := l.lp.entry(offset)
+		value := string(data)
+		l.lp.delete(offset, 1)
+
+		return value, true
+	}
+
+	idx := l.at(l.size - 1)
+	value := l.buf[idx]
+	l.buf[idx] = ""
+	l.size--
+	l.shrink()
+
+	return value, true
+}
+
+// negative indexes count from the tail, -1 being the last element
+func (l *List) Index(index int) (string, bool) {
+	index, ok := l.normalizeIndex(index)
+
+	if !ok {
+		return "", false
+	}
+
+	if l.lp != nil {
+		value, _ := l.lp.entry(l.lp.seek(index))
+		return string(value), true
+	}
+
+	return l.buf[l.at(index)], true
+}
+
+func (l *List) Set(index int, value string) bool {
+	index, ok := l.normalizeIndex(index)
+
+	if !ok {
+		return false
+	}
+
+	l.convertIfTooBig([]string{value})
+
+	if l.lp != nil {
+		l.lp.replace(l.lp.seek(index), value)
+		return true
+	}
+
+	l.buf[l.at(index)] = value
+	return true
+}
+
+// start and stop are inclusive and follow the LRANGE semantics
+func (l *List) Range(start, stop int) []string {
+	start, stop, ok := NormalizeRange(start, stop, l.Len())
+
+	if !ok {
+		return []string{}
+	}
+
+	values := make([]string, 0, stop-start+1)
+
+	if l.lp != nil {
+		offset := l.lp.seek(start)
+
+		for i := start; i <= stop; i++ {
+			var value []byte
+			value, offset = l.lp.entry(offset)
+			values = append(values, string(value))
+		}
+
+		return values
+	}
+
+	for i := start; i <= stop; i++ {
+		values = append(values, l.buf[l.at(i)])
+	}
+
+	return values
+}
+
+func (l *List) Values() []string {
+	return l.Range(0, -1)
+}
+
+// returns the new length of the list, or -1 when the pivot was not found
+func (l *List) Insert(pivot, value string, before bool) int {
+	values := l.Values()
+
+	for i, v := range values {
+		if v != pivot {
+			continue
+		}
+
+		if !before {
+			i++
+		}
+
+		values = append(values[:i], append([]string{value}, values[i:]...)...)
+		l.reset(values)
+		return l.Len()
+	}
+
+	return -1
+}
+
+// removes the first count occurrences of value. count > 0 scans from the head,
+// count < 0 from the tail and count == 0 removes every occurrence.
+func (l *List) Remove(value string, count int) int {
+	values := l.Values()
+	keep := make([]bool, len(values))
+	removed := 0
+
+	for i := range keep {
+		keep[i] = true
+	}
+
+	for i := 0; i < len(values); i++ {
+		idx := i
+		if count < 0 {
+			idx = len(values) - 1 - i
+		}
+
+		if values[idx] != value {
+			continue
+		}
+
+		keep[idx] = false
+		removed++
+
+		if count != 0 && (removed == count || removed == -count) {
+			break
+		}
+	}
+
+	if removed == 0 {
+		return 0
+	}
+
+	remaining := make([]string, 0, len(values)-removed)
+
+	for i, v := range values {
+		if keep[i] {
+			remaining = append(remaining, v)
+		}
+	}
+
+	l.reset(remaining)
+	return removed
+}
+
+func (l *List) Trim(start, stop int) {
+	start, stop, ok := NormalizeRange(start, stop, l.Len())
+
+	if !ok {
+		l.reset(nil)
+		return
+	}
+
+	l.reset(l.Range(start, stop))
+}
+
+// returns the indexes of the elements matching value following the LPOS rules.
+// rank selects the nth match (negative ranks scan from the tail), count == 0
+// returns every match and maxLen == 0 compares the whole list.
+func (l *List) Positions(value string, rank, count, maxLen int) []int {
+	positions := []int{}
+	matches := 0
+	length := l.Len()
+
+	get := func(i int) string {
+		return l.buf[l.at(i)]
+	}
+
+	if l.lp != nil {
+		values := l.lp.entries()
+
+		get = func(i int) string {
+			return values[i]
+		}
+	}
+
+	for i := 0; i < length; i++ {
+		if maxLen != 0 && i >= maxLen {
+			break
+		}
+
+		idx := i
+		if rank < 0 {
+			idx = length - 1 - i
+		}
+
+		if get(idx) != value {
+			continue
+		}
+
+		matches++
+
+		if matches < rank || matches < -rank {
+			continue
+		}
+
+		positions = append(positions, idx)
+
+		if count != 0 && len(positions) == count {
+			break
+		}
+	}
+
+	return positions
+}
+
+func (l *List) at(index int) int {
+	return (l.head + index) % len(l.buf)
+}
+
+func (l *List) normalizeIndex(index int) (int, bool) {
+	length := l.Len()
+
+	if index < 0 {
+		index += length
+	}
+
+	if index < 0 || index >= length {
+		return 0, false
+	}
+
+	return index, true
+}
+
+func (l *List) reset(values []string) {
+	if l.lp != nil {
+		l.lp = &listpack{}
+		l.convertIfTooBig(values)
+	}
+
+	if l.lp != nil {
+		l.lp.insert(0, values...)
+		return
+	}
+
+	l.buf = values
+	l.head = 0
+	l.size = len(values)
+
+	if len(l.buf) == 0 {
+		l.buf = nil
+	}
+}
+
+// moves the list to a ring buffer when adding values would take its listpack
+// past list-max-listpack-size
+func (l *List) convertIfTooBig(values []string) {
+	if l.lp == nil {
+		return
+	}
+
+	count, size := l.lp.len()+len(values), l.lp.end()
+
+	for _, value := range values {
+		size += listpackEntrySize(len(value))
+	}
+
+	if !listpackTooBig(count, size) {
+		return
+	}
+
+	values = l.lp.entries()
+	l.lp = nil
+	l.reset(values)
+}
+
+// whether a listpack of count entries taking size bytes is past list-max-listpack-size
+func listpackTooBig(count, size int) bool {
+	fill := thresholds().ListMaxListpackSize
+
+	if fill >= 0 {
+		return count > fill
+	}
+
+	if fill < -5 {
+		fill = -5
+	}
+
+	return size > 4096<<(-fill-1)
+}
+
+func (l *List) grow() {
+	if l.size < len(l.buf) {
+		return
+	}
+
+	newCapacity := len(l.buf) * 2
+
+	if newCapacity < minListCapacity {
+		newCapacity = minListCapacity
+	}
+
+	l.resize(newCapacity)
+}
+
+// give memory back once a drained queue only uses a quarter of its buffer
+func (l *List) shrink() {
+	if len(l.buf) <= minListCapacity || l.size > len(l.buf)/4 {
+		return
+	}
+
+	l.resize(len(l.buf) / 2)
+}
+
+func (l *List) resize(capacity int) {
+	buf := make([]string, capacity)
+
+	for i := 0; i < l.size; i++ {
+		buf[i] = l.buf[l.at(i)]
+	}
+
+	l.buf = buf
+	l.head = 0
+}
+
+// converts redis style inclusive start/stop indexes (which may be negative)
+// into valid offsets for a sequence of the given length
+func NormalizeRange(start, stop, length int) (int, int, bool) {
+	if start < 0 {
+		start += length
+	}
+
+	if stop < 0 {
+		stop += length
+	}
+
+	if start < 0 {
+		start = 0
+	}
+
+	if stop >= length {
+		stop = length - 1
+	}
+
+	if start > stop || start >= length {
+		return 0, 0, false
+	}
+
+	return start, stop, true
+}
+
+func (l *List) Copy() *List {
+	if l.lp != nil {
+		return &List{DataType: l.DataType, lp: l.lp.copy()}
+	}
+
+	return &List{
+		DataType: l.DataType,
+		buf:      append([]string{}, l.buf...),
+		head:     l.head,
+		size:     l.size,
+	}
+}
@@ -0,0 +1,221 @@
+package datatypes
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+)
+
+// EncodingThresholds are the sizes past which small values leave their compact
+// encoding for one that scales, like the *-max-listpack-* settings of redis.
+// Values never go back to the compact encoding once converted.
+type EncodingThresholds struct {
+	ListMaxListpackSize    int // entries when positive, -1 to -5 for 4kb to 64kb
+	HashMaxListpackEntries int
+	HashMaxListpackValue   int // bytes of the longest field or value
+	SetMaxIntsetEntries    int
+	SetMaxListpackEntries  int
+	SetMaxListpackValue    int
+	ZSetMaxListpackEntries int
+	ZSetMaxListpackValue   int
+}
+
+var DefaultEncodingThresholds = EncodingThresholds{
+	ListMaxListpackSize:    -2,
+	HashMaxListpackEntries: 128,
+	HashMaxListpackValue:   64,
+	SetMaxIntsetEntries:    512,
+	SetMaxListpackEntries:  128,
+	SetMaxListpackValue:    64,
+	ZSetMaxListpackEntries: 128,
+	ZSetMaxListpackValue:   64,
+}
+
+// read by every value of every database, hence atomic
+var encodingThresholds atomic.Pointer[EncodingThresholds]
+
+func init() {
+	SetEncodingThresholds(DefaultEncodingThresholds)
+}
+
+// sets the thresholds checked from now on, values already converted stay converted
+func SetEncodingThresholds(t EncodingThresholds) {
+	encodingThresholds.Store(&t)
+}
+
+func thresholds() *EncodingThresholds {
+	return encodingThresholds.Load()
+}
+
+// listpack is a sequence of strings packed one after the other in a single byte
+// slice, like the redis listpack. An entry is the length of its data as a
+// uvarint, the data, then the size of the whole entry as a uvarint written
+// backwards so the entries can be walked from both ends. Lookups are linear,
+// which is fine for the small values it holds.
+type listpack struct {
+	contents []byte
+	count    int
+}
+
+func (lp *listpack) len() int {
+	return lp.count
+}
+
+// the offset right after the last entry, where appended entries go
+func (lp *listpack) end() int {
+	return len(lp.contents)
+}
+
+// returns the data of the entry at offset, which aliases the listpack, and the
+// offset of the next entry
+func (lp *listpack) entry(offset int) ([]byte, int) {
+	length, n := binary.Uvarint(lp.contents[offset:])
+	start := offset + n
+	end := start + int(length)
+
+	return lp.contents[start:end], end + uvarintSize(n+int(length))
+}
+
+// returns the offset of the entry before the one at offset
+func (lp *listpack) prev(offset int) int {
+	var size uint64
+	var shift uint
+
+	i := offset - 1
+
+	for {
+		b := lp.contents[i]
+		size |= uint64(b&0x7f) << shift
+
+		if b < 0x80 {
+			break
+		}
+
+		shift += 7
+		i--
+	}
+
+	return i - int(size)
+}
+
+// returns the offset of the entry at index, or end() when index is len()
+func (lp *listpack) seek(index int) int {
+	if index > lp.count/2 {
+		offset := lp.end()
+
+		for i := lp.count; i > index; i-- {
+			offset = lp.prev(offset)
+		}
+
+		return offset
+	}
+
+	offset := 0
+
+	for i := 0; i < index; i++ {
+		_, offset = lp.entry(offset)
+	}
+
+	return offset
+}
+
+// returns the offset of the first entry equal to value, looking at one entry out
+// of step (2 to only look at the fields of field value pairs), -1 when there is none
+func (lp *listpack) find(value string, step int) int {
+	for offset := 0; offset < lp.end(); {
+		data, next := lp.entry(offset)
+
+		if string(data) == value {
+			return offset
+		}
+
+		offset = next
+
+		for i := 1; i < step; i++ {
+			_, offset = lp.entry(offset)
+		}
+	}
+
+	return -1
+}
+
+// inserts values, in order, before the entry at offset
+func (lp *listpack) insert(offset int, values ...string) {
+	var encoded []byte
+
+	for _, value := range values {
+		encoded = appendListpackEntry(encoded, value)
+	}
+
+	lp.contents = append(lp.contents, encoded...)
+	copy(lp.contents[offset+len(encoded):], lp.contents[offset:])
+	copy(lp.contents[offset:], encoded)
+	lp.count += len(values)
+}
+
+// deletes n entries from the one at offset on
+func (lp *listpack) delete(offset, n int) {
+	end := offset
+
+	for i := 0; i < n; i++ {
+		_, end = lp.entry(end)
+	}
+
+	lp.contents = append(lp.contents[:offset], lp.contents[end:]...)
+	lp.count -= n
+}
+
+func (lp *listpack) replace(offset int, value string) {
+	lp.delete(offset, 1)
+	lp.insert(offset, value)
+}
+
+func (lp *listpack) entries() []string {
+	entries := make([]string, 0, lp.count)
+
+	for offset := 0; offset < lp.end(); {
+		var data []byte
+		data, offset = lp.entry(offset)
+		entries = append(entries, string(data))
+	}
+
+	return entries
+}
+
+func (lp *listpack) copy() *listpack {
+	return &listpack{
+		contents: append([]byte{}, lp.contents...),
+		count:    lp.count,
+	}
+}
+
+func appendListpackEntry(b []byte, value string) []byte {
+	var backlen [binary.MaxVarintLen64]byte
+
+	header := len(b)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	b = append(b, value...)
+	n := binary.PutUvarint(backlen[:], uint64(len(b)-header))
+
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, backlen[i])
+	}
+
+	return b
+}
+
+// the bytes an entry holding length bytes of data takes
+func listpackEntrySize(length int) int {
+	size := uvarintSize(length) + length
+	return size + uvarintSize(size)
+}
+
+func uvarintSize(n int) int {
+	size := 1
+
+	for n >= 0x80 {
+		n >>= 7
+		size++
+	}
+
+	return size
+}
@@ -0,0 +1,167 @@
+package datatypes
+
+import "unsafe"
+
+// rough sizes of the Go runtime structures backing the values, on 64 bit platforms
+const (
+	stringHeaderSize = int(unsafe.Sizeof(""))
+	sliceHeaderSize  = int(unsafe.Sizeof([]byte{}))
+	pointerSize      = int(unsafe.Sizeof(uintptr(0)))
+	mapHeaderSize    = 48
+	mapEntryOverhead = 16 // bucket top hash, overflow pointers and load factor slack
+)
+
+// the memory estimates below look at samples elements and extrapolate to the
+// whole value, like MEMORY USAGE in redis. samples 0 looks at every element.
+
+func (s *String) MemoryUsage(samples int) int {
+	return stringHeaderSize + sliceHeaderSize + allocSize(cap(s.Value))
+}
+
+func (l *List) MemoryUsage(samples int) int {
+	size := stringHeaderSize + sliceHeaderSize + 3*pointerSize + cap(l.buf)*stringHeaderSize
+
+	if l.lp != nil {
+		return size + l.lp.memoryUsage()
+	}
+
+	elements := 0
+	n := sampleCount(samples, l.size)
+
+	for i := 0; i < n; i++ {
+		elements += allocSize(len(l.buf[l.at(i)]))
+	}
+
+	return size + extrapolate(elements, n, l.size)
+}
+
+func (h *Hash) MemoryUsage(samples int) int {
+	size := stringHeaderSize + 3*pointerSize
+
+	// field expiries share the field strings
+	size += len(h.expires) * (mapEntryOverhead + stringHeaderSize + int(unsafe.Sizeof(h.expires[""])))
+
+	if h.expires != nil {
+		size += mapHeaderSize
+	}
+
+	if h.lp != nil {
+		return size + h.lp.memoryUsage()
+	}
+
+	size += mapHeaderSize
+	elements := 0
+	n := sampleCount(samples, len(h.fields))
+	i := 0
+
+	for field, value := range h.fields {
+		if i == n {
+			break
+		}
+
+		elements += mapEntryOverhead + 2*stringHeaderSize + allocSize(len(field)) + allocSize(len(value))
+		i++
+	}
+
+	return size + extrapolate(elements, n, len(h.fields))
+}
+
+func (s *Set) MemoryUsage(samples int) int {
+	size := stringHeaderSize + 3*pointerSize
+
+	if s.intset != nil {
+		return size + pointerSize + sliceHeaderSize + allocSize(cap(s.intset.contents))
+	}
+
+	if s.lp != nil {
+		return size + s.lp.memoryUsage()
+	}
+
+	elements := 0
+	n := sampleCount(samples, len(s.members))
+	i := 0
+
+	for member := range s.members {
+		if i == n {
+			break
+		}
+
+		elements += mapEntryOverhead + stringHeaderSize + allocSize(len(member))
+		i++
+	}
+
+	return size + mapHeaderSize + extrapolate(elements, n, len(s.members))
+}
+
+func (z *SortedSet) MemoryUsage(samples int) int {
+	size := stringHeaderSize + 3*pointerSize
+
+	if z.lp != nil {
+		return size + z.lp.memoryUsage()
+	}
+
+	size += mapHeaderSize
+	elements := 0
+	n := sampleCount(samples, len(z.dict))
+
+	// a node has 1.33 levels on average with p = 1/4
+	nodeSize := stringHeaderSize + 8 + pointerSize + sliceHeaderSize + 4*int(unsafe.Sizeof(skiplistLevel{}))/3
+	i := 0
+
+	for member := range z.dict {
+		if i == n {
+			break
+		}
+
+		// the dict and the skiplist node share the member string
+		elements += mapEntryOverhead + stringHeaderSize + 8 + nodeSize + allocSize(len(member))
+		i++
+	}
+
+	return size + extrapolate(elements, n, len(z.dict))
+}
+
+func (s *Stream) MemoryUsage(samples int) int {
+	size := stringHeaderSize + sliceHeaderSize + pointerSize + cap(s.Values)*int(unsafe.Sizeof(Entry{}))
+	elements := 0
+	n := sampleCount(samples, len(s.Values))
+
+	for i := 0; i < n; i++ {
+		entry := s.Values[i]
+		elements += allocSize(len(entry.Id)) + mapHeaderSize
+
+		for field, value := range entry.Values {
+			elements += mapEntryOverhead + 2*stringHeaderSize + allocSize(len(field)) + allocSize(len(value))
+		}
+	}
+
+	return size + extrapolate(elements, n, len(s.Values))
+}
+
+// a listpack is a single allocation whatever the number of entries
+func (lp *listpack) memoryUsage() int {
+	return sliceHeaderSize + pointerSize + allocSize(cap(lp.contents))
+}
+
+// the number of elements to look at out of length
+func sampleCount(samples, length int) int {
+	if samples <= 0 || samples > length {
+		return length
+	}
+
+	return samples
+}
+
+// scales the size of the sampled elements up to all of them
+func extrapolate(size, sampled, length int) int {
+	if sampled == 0 {
+		return 0
+	}
+
+	return size * length / sampled
+}
+
+// n rounded up to the 8 byte size classes of the allocator
+func allocSize(n int) int {
+	return (n + 7) &^ 7
+}
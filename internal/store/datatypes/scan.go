@@ -0,0 +1,112 @@
+package datatypes
+
+import (
+	"hash/maphash"
+	"math/bits"
+)
+
+// the fewest buckets a ScanIndex has
+const minScanBuckets = 4
+
+var scanSeed = maphash.MakeSeed()
+
+// ScanIndex buckets the keys of a database, or the members of a hash table
+// encoded collection, by hash so the SCAN family can walk a few buckets per
+// call, like the dict of redis. The number of buckets is a power of two that
+// doubles as keys are added and halves as they are removed, and the cursor is
+// a bucket counted in reverse binary, so a key present for the whole iteration
+// is returned even when the buckets are resized in between.
+type ScanIndex struct {
+	buckets [][]string
+	count   int
+}
+
+func NewScanIndex() *ScanIndex {
+	return &ScanIndex{buckets: make([][]string, minScanBuckets)}
+}
+
+// the key must not be in the index already
+func (ki *ScanIndex) Add(key string) {
+	if ki.count >= len(ki.buckets) {
+		ki.resize(len(ki.buckets) * 2)
+	}
+
+	b := ki.bucket(key)
+	ki.buckets[b] = append(ki.buckets[b], key)
+	ki.count++
+}
+
+func (ki *ScanIndex) Remove(key string) {
+	b := ki.bucket(key)
+	keys := ki.buckets[b]
+
+	for i, k := range keys {
+		if k == key {
+			keys[i] = keys[len(keys)-1]
+			ki.buckets[b] = keys[:len(keys)-1]
+			ki.count--
+			break
+		}
+	}
+
+	// shrinking at an eighth full leaves room before growing again
+	if len(ki.buckets) > minScanBuckets && ki.count*8 < len(ki.buckets) {
+		ki.resize(len(ki.buckets) / 2)
+	}
+}
+
+func (ki *ScanIndex) bucket(key string) uint64 {
+	return maphash.String(scanSeed, key) & uint64(len(ki.buckets)-1)
+}
+
+func (ki *ScanIndex) resize(size int) {
+	old := ki.buckets
+	ki.buckets = make([][]string, size)
+
+	for _, keys := range old {
+		for _, key := range keys {
+			b := ki.bucket(key)
+			ki.buckets[b] = append(ki.buckets[b], key)
+		}
+	}
+}
+
+// calls fn with the keys of the buckets from cursor on, until at least count
+// keys were visited or, as buckets may be empty, count*10 buckets were. returns
+// the cursor to resume from, 0 once every bucket has been visited.
+func (ki *ScanIndex) Scan(cursor uint64, count int, fn func(key string)) uint64 {
+	if count < 1 {
+		count = 1
+	}
+
+	mask := uint64(len(ki.buckets) - 1)
+	visited := 0
+
+	for i := 0; i < count*10; i++ {
+		for _, key := range ki.buckets[cursor&mask] {
+			fn(key)
+			visited++
+		}
+
+		// increments the bits under the mask starting from the highest one
+		cursor |= ^mask
+		cursor = bits.Reverse64(bits.Reverse64(cursor) + 1)
+
+		if cursor == 0 || visited >= count {
+			break
+		}
+	}
+
+	return cursor
+}
+
+// returns the keys of the buckets from cursor on, see Scan
+func (ki *ScanIndex) collect(cursor uint64, count int) ([]string, uint64) {
+	keys := []string{}
+
+	next := ki.Scan(cursor, count, func(key string) {
+		keys = append(keys, key)
+	})
+
+	return keys, next
+}
@@ -0,0 +1,272 @@
+package datatypes
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// Set is an unordered collection of unique strings. Sets of integers are kept
+// in a compact intset up to set-max-intset-entries members, and other small
+// sets in a listpack up to set-max-listpack-entries members no longer than
+// set-max-listpack-value. Past that they are converted to a hash table.
+type Set struct {
+	DataType string
+	intset   *intset
+	lp       *listpack
+	members  map[string]struct{}
+	index    *ScanIndex // the members in SSCAN order, along with members
+}
+
+func NewSet() *Set {
+	return &Set{
+		DataType: "set",
+		intset:   newIntset(),
+	}
+}
+
+func (s *Set) GetType() string {
+	return s.DataType
+}
+
+func (s *Set) Encoding() string {
+	if s.intset != nil {
+		return "intset"
+	}
+
+	if s.lp != nil {
+		return "listpack"
+	}
+
+	return "hashtable"
+}
+
+func (s *Set) Len() int {
+	if s.intset != nil {
+		return s.intset.len()
+	}
+
+	if s.lp != nil {
+		return s.lp.len()
+	}
+
+	return len(s.members)
+}
+
+// returns false when member was already part of the set
+func (s *Set) Add(member string) bool {
+	t := thresholds()
+
+	if s.intset != nil {
+		if value, ok := parseCanonicalInt(member); ok {
+			if !s.intset.add(value) {
+				return false
+			}
+
+			if s.intset.len() > t.SetMaxIntsetEntries {
+				s.convertToHashTable()
+			}
+
+			return true
+		}
+
+		if s.intset.len() < t.SetMaxListpackEntries && len(member) <= t.SetMaxListpackValue {
+			s.convertToListpack()
+		} else {
+			s.convertToHashTable()
+		}
+	}
+
+	if s.lp != nil {
+		if s.lp.find(member, 1) >= 0 {
+			return false
+		}
+
+		if s.lp.len() < t.SetMaxListpackEntries && len(member) <= t.SetMaxListpackValue {
+			s.lp.insert(s.lp.end(), member)
+			return true
+		}
+
+		s.convertToHashTable()
+	}
+
+	if _, ok := s.members[member]; ok {
+		return false
+	}
+
+	s.members[member] = struct{}{}
+	s.index.Add(member)
+
+	return true
+}
+
+// returns false when member was not part of the set
+func (s *Set) Remove(member string) bool {
+	if s.intset != nil {
+		value, ok := parseCanonicalInt(member)
+		return ok && s.intset.remove(value)
+	}
+
+	if s.lp != nil {
+		offset := s.lp.find(member, 1)
+
+		if offset < 0 {
+			return false
+		}
+
+		s.lp.delete(offset, 1)
+
+		return true
+	}
+
+	if _, ok := s.members[member]; !ok {
+		return false
+	}
+
+	delete(s.members, member)
+	s.index.Remove(member)
+
+	return true
+}
+
+func (s *Set) Contains(member string) bool {
+	if s.intset != nil {
+		value, ok := parseCanonicalInt(member)
+		return ok && s.intset.contains(value)
+	}
+
+	if s.lp != nil {
+		return s.lp.find(member, 1) >= 0
+	}
+
+	_, ok := s.members[member]
+
+	return ok
+}
+
+func (s *Set) Members() []string {
+	if s.lp != nil {
+		return s.lp.entries()
+	}
+
+	members := make([]string, 0, s.Len())
+
+	if s.intset != nil {
+		for i := 0; i < s.intset.len(); i++ {
+			members = append(members, strconv.FormatInt(s.intset.get(i), 10))
+		}
+
+		return members
+	}
+
+	for member := range s.members {
+		members = append(members, member)
+	}
+
+	return members
+}
+
+// returns the members from cursor on, at least count unless the iteration is
+// over, and the cursor to resume from, 0 once it is. like redis, the compact
+// encodings return every member at once.
+func (s *Set) Scan(cursor uint64, count int) ([]string, uint64) {
+	if s.members == nil {
+		return s.Members(), 0
+	}
+
+	return s.index.collect(cursor, count)
+}
+
+// returns count distinct random members, or every member when count >= Len()
+func (s *Set) RandomMembers(count int) []string {
+	members := s.Members()
+
+	if count >= len(members) {
+		return members
+	}
+
+	// partial fisher-yates, only the first count positions need to be shuffled
+	for i := 0; i < count; i++ {
+		j := i + rand.Intn(len(members)-i)
+		members[i], members[j] = members[j], members[i]
+	}
+
+	return members[:count]
+}
+
+// returns count random members that may repeat
+func (s *Set) RandomMembersWithRepetition(count int) []string {
+	// count comes from the client, so only a bounded part is preallocated
+	capacity := count
+
+	if capacity > 1024 {
+		capacity = 1024
+	}
+
+	picked := make([]string, 0, capacity)
+
+	if s.intset != nil {
+		for i := 0; i < count; i++ {
+			picked = append(picked, strconv.FormatInt(s.intset.get(rand.Intn(s.intset.len())), 10))
+		}
+
+		return picked
+	}
+
+	members := s.Members()
+
+	for i := 0; i < count; i++ {
+		picked = append(picked, members[rand.Intn(len(members))])
+	}
+
+	return picked
+}
+
+// moves the members of an intset to a listpack
+func (s *Set) convertToListpack() {
+	lp := &listpack{}
+	lp.insert(0, s.Members()...)
+
+	s.intset = nil
+	s.lp = lp
+}
+
+// moves the members of an intset or a listpack to a hash table
+func (s *Set) convertToHashTable() {
+	members := make(map[string]struct{}, s.Len())
+	index := NewScanIndex()
+
+	for _, member := range s.Members() {
+		members[member] = struct{}{}
+		index.Add(member)
+	}
+
+	s.intset = nil
+	s.lp = nil
+	s.members = members
+	s.index = index
+}
+
+// Copy returns a copy of the set, keeping its encoding
+func (s *Set) Copy() *Set {
+	c := &Set{DataType: s.DataType}
+
+	if s.intset != nil {
+		c.intset = &intset{width: s.intset.width, contents: append([]byte{}, s.intset.contents...)}
+		return c
+	}
+
+	if s.lp != nil {
+		c.lp = s.lp.copy()
+		return c
+	}
+
+	c.members = make(map[string]struct{}, len(s.members))
+	c.index = NewScanIndex()
+
+	for member := range s.members {
+		c.members[member] = struct{}{}
+		c.index.Add(member)
+	}
+
+	return c
+}
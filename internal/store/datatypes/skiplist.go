@@ -0,0 +1,313 @@
+package datatypes
+
+import "math/rand"
+
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+// skiplist ordered by score then member, with the span of every link stored so
+// ranks can be computed in O(log N). It follows the redis zskiplist closely.
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	levels   []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: newSkiplistNode(skiplistMaxLevel, 0, ""),
+		level:  1,
+	}
+}
+
+func newSkiplistNode(level int, score float64, member string) *skiplistNode {
+	return &skiplistNode{
+		member: member,
+		score:  score,
+		levels: make([]skiplistLevel, level),
+	}
+}
+
+// returns a level between 1 and skiplistMaxLevel, higher levels being
+// exponentially less likely
+func randomSkiplistLevel() int {
+	level := 1
+
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+
+	return level
+}
+
+// orders nodes by score, and members lexicographically for equal scores
+func skiplistLess(node *skiplistNode, score float64, member string) bool {
+	return node.score < score || (node.score == score && node.member < member)
+}
+
+// inserts a new node, the member must not be part of the skiplist already
+func (zsl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+
+	x := zsl.header
+
+	for i := zsl.level - 1; i >= 0; i-- {
+		if i != zsl.level-1 {
+			rank[i] = rank[i+1]
+		}
+
+		for x.levels[i].forward != nil && skiplistLess(x.levels[i].forward, score, member) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		update[i] = x
+	}
+
+	level := randomSkiplistLevel()
+
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = zsl.header
+			update[i].levels[i].span = zsl.length
+		}
+
+		zsl.level = level
+	}
+
+	x = newSkiplistNode(level, score, member)
+
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+
+	// levels above the new node now span one more element
+	for i := level; i < zsl.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+
+	zsl.length++
+
+	return x
+}
+
+// finds the nodes preceding the (score, member) node on every level
+func (zsl *skiplist) findUpdate(score float64, member string) ([skiplistMaxLevel]*skiplistNode, *skiplistNode) {
+	var update [skiplistMaxLevel]*skiplistNode
+
+	x := zsl.header
+
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && skiplistLess(x.levels[i].forward, score, member) {
+			x = x.levels[i].forward
+		}
+
+		update[i] = x
+	}
+
+	return update, x.levels[0].forward
+}
+
+func (zsl *skiplist) delete(score float64, member string) bool {
+	update, x := zsl.findUpdate(score, member)
+
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+
+	zsl.deleteNode(x, update)
+
+	return true
+}
+
+func (zsl *skiplist) deleteNode(x *skiplistNode, update [skiplistMaxLevel]*skiplistNode) {
+	for i := 0; i < zsl.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+
+	for zsl.level > 1 && zsl.header.levels[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+
+	zsl.length--
+}
+
+// changes the score of an existing member, reusing its node when the order is unchanged
+func (zsl *skiplist) updateScore(currentScore float64, member string, newScore float64) {
+	update, x := zsl.findUpdate(currentScore, member)
+
+	if (x.backward == nil || x.backward.score < newScore) &&
+		(x.levels[0].forward == nil || x.levels[0].forward.score > newScore) {
+		x.score = newScore
+		return
+	}
+
+	zsl.deleteNode(x, update)
+	zsl.insert(newScore, member)
+}
+
+// returns the 1 based rank of the node, 0 when it is not found
+func (zsl *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := zsl.header
+
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil &&
+			(x.levels[i].forward.score < score ||
+				(x.levels[i].forward.score == score && x.levels[i].forward.member <= member)) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		if x != zsl.header && x.score == score && x.member == member {
+			return rank
+		}
+	}
+
+	return 0
+}
+
+// returns the node at the 1 based rank
+func (zsl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := zsl.header
+
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		if traversed == rank {
+			return x
+		}
+	}
+
+	return nil
+}
+
+func (zsl *skiplist) firstInScoreRange(r ScoreRange) *skiplistNode {
+	if r.isEmpty() || zsl.tail == nil || !r.gteMin(zsl.tail.score) || !r.lteMax(zsl.header.levels[0].forward.score) {
+		return nil
+	}
+
+	x := zsl.header
+
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !r.gteMin(x.levels[i].forward.score) {
+			x = x.levels[i].forward
+		}
+	}
+
+	x = x.levels[0].forward
+
+	if !r.lteMax(x.score) {
+		return nil
+	}
+
+	return x
+}
+
+func (zsl *skiplist) lastInScoreRange(r ScoreRange) *skiplistNode {
+	if r.isEmpty() || zsl.tail == nil || !r.gteMin(zsl.tail.score) || !r.lteMax(zsl.header.levels[0].forward.score) {
+		return nil
+	}
+
+	x := zsl.header
+
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && r.lteMax(x.levels[i].forward.score) {
+			x = x.levels[i].forward
+		}
+	}
+
+	if !r.gteMin(x.score) {
+		return nil
+	}
+
+	return x
+}
+
+func (zsl *skiplist) firstInLexRange(r LexRange) *skiplistNode {
+	if r.isEmpty() || zsl.tail == nil || !r.gteMin(zsl.tail.member) || !r.lteMax(zsl.header.levels[0].forward.member) {
+		return nil
+	}
+
+	x := zsl.header
+
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !r.gteMin(x.levels[i].forward.member) {
+			x = x.levels[i].forward
+		}
+	}
+
+	x = x.levels[0].forward
+
+	if !r.lteMax(x.member) {
+		return nil
+	}
+
+	return x
+}
+
+func (zsl *skiplist) lastInLexRange(r LexRange) *skiplistNode {
+	if r.isEmpty() || zsl.tail == nil || !r.gteMin(zsl.tail.member) || !r.lteMax(zsl.header.levels[0].forward.member) {
+		return nil
+	}
+
+	x := zsl.header
+
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && r.lteMax(x.levels[i].forward.member) {
+			x = x.levels[i].forward
+		}
+	}
+
+	if !r.gteMin(x.member) {
+		return nil
+	}
+
+	return x
+}
@@ -0,0 +1,641 @@
+package datatypes
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ZAdd outcomes for a single member
+const (
+	ZAddSkipped   = iota // NX, XX, GT or LT prevented the update
+	ZAddAdded            // the member was new
+	ZAddUpdated          // the score of the member changed
+	ZAddUnchanged        // the member already had that score
+)
+
+type ZAddOptions struct {
+	NX   bool
+	XX   bool
+	GT   bool
+	LT   bool
+	Incr bool
+}
+
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// ScoreRange is a score interval as given to ZRANGE BYSCORE, e.g. (1 +inf
+type ScoreRange struct {
+	Min          float64
+	Max          float64
+	MinExclusive bool
+	MaxExclusive bool
+}
+
+// lexicographical range bounds, "-" and "+" are the infinitely small and large strings
+const (
+	lexValue = iota
+	lexNegativeInfinity
+	lexPositiveInfinity
+)
+
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	kind      int
+}
+
+// LexRange is a member interval as given to ZRANGE BYLEX, e.g. [a (c
+type LexRange struct {
+	Min LexBound
+	Max LexBound
+}
+
+// SortedSet keeps members ordered by score with a skiplist, and a map from
+// member to score for O(1) score lookups. Small sorted sets keep their members
+// and scores in order in a listpack instead, until they grow past
+// zset-max-listpack-entries or get a member longer than zset-max-listpack-value.
+type SortedSet struct {
+	DataType string
+	lp       *listpack // member score pairs
+	dict     map[string]float64
+	zsl      *skiplist
+	index    *ScanIndex // the members in ZSCAN order, along with dict
+}
+
+func NewSortedSet() *SortedSet {
+	return &SortedSet{
+		DataType: "zset",
+		lp:       &listpack{},
+	}
+}
+
+func (z *SortedSet) GetType() string {
+	return z.DataType
+}
+
+func (z *SortedSet) Encoding() string {
+	if z.lp != nil {
+		return "listpack"
+	}
+
+	return "skiplist"
+}
+
+func (z *SortedSet) Len() int {
+	if z.lp != nil {
+		return z.lp.len() / 2
+	}
+
+	return len(z.dict)
+}
+
+func (z *SortedSet) Score(member string) (float64, bool) {
+	if z.lp != nil {
+		offset := z.lp.find(member, 2)
+
+		if offset < 0 {
+			return 0, false
+		}
+
+		_, offset = z.lp.entry(offset)
+		score, _ := z.lp.entry(offset)
+
+		return parseListpackScore(score), true
+	}
+
+	score, ok := z.dict[member]
+	return score, ok
+}
+
+// adds or updates member following the ZADD options. With Incr the score is
+// added to the current one. Returns the resulting score and one of the ZAdd outcomes.
+func (z *SortedSet) Add(member string, score float64, options ZAddOptions) (float64, int, error) {
+	current, exists := z.Score(member)
+
+	if exists {
+		if options.NX {
+			return current, ZAddSkipped, nil
+		}
+
+		if options.Incr {
+			score += current
+
+			if math.IsNaN(score) {
+				return 0, ZAddSkipped, errors.New("ERR resulting score is not a number (NaN)")
+			}
+		}
+
+		if (options.LT && score >= current) || (options.GT && score <= current) {
+			return current, ZAddSkipped, nil
+		}
+
+		if score == current {
+			return current, ZAddUnchanged, nil
+		}
+
+		if z.lp != nil {
+			// the pair moves to its new position
+			z.remove(member, current)
+			z.insert(member, score)
+		} else {
+			z.zsl.updateScore(current, member, score)
+			z.dict[member] = score
+		}
+
+		return score, ZAddUpdated, nil
+	}
+
+	if options.XX {
+		return 0, ZAddSkipped, nil
+	}
+
+	z.insert(member, score)
+
+	return score, ZAddAdded, nil
+}
+
+// returns false when member was not part of the sorted set
+func (z *SortedSet) Remove(member string) bool {
+	score, ok := z.Score(member)
+
+	if !ok {
+		return false
+	}
+
+	z.remove(member, score)
+
+	return true
+}
+
+// returns the 0 based rank of member, counting from the highest score when reverse is set
+func (z *SortedSet) Rank(member string, reverse bool) (int, bool) {
+	if z.lp != nil {
+		for i, m := range z.listpackMembers() {
+			if m.Member != member {
+				continue
+			}
+
+			if reverse {
+				return z.Len() - 1 - i, true
+			}
+
+			return i, true
+		}
+
+		return 0, false
+	}
+
+	score, ok := z.dict[member]
+
+	if !ok {
+		return 0, false
+	}
+
+	rank := z.zsl.rank(score, member)
+
+	if reverse {
+		return z.zsl.length - rank, true
+	}
+
+	return rank - 1, true
+}
+
+// start and stop are inclusive 0 based ranks, negative ranks count from the end
+func (z *SortedSet) RangeByRank(start, stop int, reverse bool) []ScoredMember {
+	start, stop, ok := NormalizeRange(start, stop, z.Len())
+
+	if !ok {
+		return []ScoredMember{}
+	}
+
+	result := make([]ScoredMember, 0, stop-start+1)
+
+	if z.lp != nil {
+		members := z.listpackMembers()
+
+		for i := start; i <= stop; i++ {
+			if reverse {
+				result = append(result, members[len(members)-1-i])
+			} else {
+				result = append(result, members[i])
+			}
+		}
+
+		return result
+	}
+
+	var x *skiplistNode
+
+	if reverse {
+		x = z.zsl.byRank(z.zsl.length - start)
+	} else {
+		x = z.zsl.byRank(start + 1)
+	}
+
+	for i := start; i <= stop && x != nil; i++ {
+		result = append(result, ScoredMember{x.member, x.score})
+
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.levels[0].forward
+		}
+	}
+
+	return result
+}
+
+// returns the members within r, skipping offset of them and returning at most
+// count (a negative count returns every remaining member)
+func (z *SortedSet) RangeByScore(r ScoreRange, reverse bool, offset, count int) []ScoredMember {
+	if z.lp != nil {
+		return collectListpackMembers(z.listpackMembers(), reverse, offset, count, func(m ScoredMember) bool {
+			return r.gteMin(m.Score)
+		}, func(m ScoredMember) bool {
+			return r.lteMax(m.Score)
+		})
+	}
+
+	var x *skiplistNode
+
+	if reverse {
+		x = z.zsl.lastInScoreRange(r)
+	} else {
+		x = z.zsl.firstInScoreRange(r)
+	}
+
+	return z.collect(x, reverse, offset, count, func(node *skiplistNode) bool {
+		if reverse {
+			return r.gteMin(node.score)
+		}
+		return r.lteMax(node.score)
+	})
+}
+
+// like RangeByScore for a lexicographical range, meant for members sharing the same score
+func (z *SortedSet) RangeByLex(r LexRange, reverse bool, offset, count int) []ScoredMember {
+	if z.lp != nil {
+		return collectListpackMembers(z.listpackMembers(), reverse, offset, count, func(m ScoredMember) bool {
+			return r.gteMin(m.Member)
+		}, func(m ScoredMember) bool {
+			return r.lteMax(m.Member)
+		})
+	}
+
+	var x *skiplistNode
+
+	if reverse {
+		x = z.zsl.lastInLexRange(r)
+	} else {
+		x = z.zsl.firstInLexRange(r)
+	}
+
+	return z.collect(x, reverse, offset, count, func(node *skiplistNode) bool {
+		if reverse {
+			return r.gteMin(node.member)
+		}
+		return r.lteMax(node.member)
+	})
+}
+
+// number of members within r, computed from the ranks of both ends
+func (z *SortedSet) Count(r ScoreRange) int {
+	if z.lp != nil {
+		return len(z.RangeByScore(r, false, 0, -1))
+	}
+
+	first := z.zsl.firstInScoreRange(r)
+
+	if first == nil {
+		return 0
+	}
+
+	last := z.zsl.lastInScoreRange(r)
+
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+// removes and returns up to count members with the lowest (or highest when max is set) scores
+func (z *SortedSet) Pop(count int, max bool) []ScoredMember {
+	popped := []ScoredMember{}
+
+	for len(popped) < count && z.Len() > 0 {
+		rank := 0
+
+		if max {
+			rank = -1
+		}
+
+		member := z.RangeByRank(rank, rank, false)[0]
+		popped = append(popped, member)
+		z.remove(member.Member, member.Score)
+	}
+
+	return popped
+}
+
+func (z *SortedSet) Members() []string {
+	members := make([]string, 0, z.Len())
+
+	if z.lp != nil {
+		for _, m := range z.listpackMembers() {
+			members = append(members, m.Member)
+		}
+
+		return members
+	}
+
+	for member := range z.dict {
+		members = append(members, member)
+	}
+
+	return members
+}
+
+// returns the members from cursor on, at least count unless the iteration is
+// over, and the cursor to resume from, 0 once it is. like redis, the listpack
+// encoding returns every member at once.
+func (z *SortedSet) Scan(cursor uint64, count int) ([]string, uint64) {
+	if z.lp != nil {
+		return z.Members(), 0
+	}
+
+	return z.index.collect(cursor, count)
+}
+
+// adds a member that is not part of the sorted set
+func (z *SortedSet) insert(member string, score float64) {
+	if z.lp != nil {
+		t := thresholds()
+
+		if z.Len() >= t.ZSetMaxListpackEntries || len(member) > t.ZSetMaxListpackValue {
+			z.convertToSkiplist()
+		}
+	}
+
+	if z.lp == nil {
+		z.zsl.insert(score, member)
+		z.dict[member] = score
+		z.index.Add(member)
+		return
+	}
+
+	// the first pair ordered after the new one
+	offset := 0
+
+	for offset < z.lp.end() {
+		m, next := z.lp.entry(offset)
+		s, after := z.lp.entry(next)
+
+		if current := parseListpackScore(s); current > score || (current == score && string(m) > member) {
+			break
+		}
+
+		offset = after
+	}
+
+	z.lp.insert(offset, member, strconv.FormatFloat(score, 'g', -1, 64))
+}
+
+// removes a member of the sorted set, which has the given score
+func (z *SortedSet) remove(member string, score float64) {
+	if z.lp != nil {
+		z.lp.delete(z.lp.find(member, 2), 2)
+		return
+	}
+
+	z.zsl.delete(score, member)
+	delete(z.dict, member)
+	z.index.Remove(member)
+}
+
+// the members of a listpack encoded sorted set, in order
+func (z *SortedSet) listpackMembers() []ScoredMember {
+	entries := z.lp.entries()
+	members := make([]ScoredMember, 0, len(entries)/2)
+
+	for i := 0; i < len(entries); i += 2 {
+		score, _ := strconv.ParseFloat(entries[i+1], 64)
+		members = append(members, ScoredMember{entries[i], score})
+	}
+
+	return members
+}
+
+func (z *SortedSet) convertToSkiplist() {
+	members := z.listpackMembers()
+
+	z.lp = nil
+	z.dict = make(map[string]float64, len(members))
+	z.zsl = newSkiplist()
+	z.index = NewScanIndex()
+
+	for _, m := range members {
+		z.zsl.insert(m.Score, m.Member)
+		z.dict[m.Member] = m.Score
+		z.index.Add(m.Member)
+	}
+}
+
+func parseListpackScore(score []byte) float64 {
+	value, _ := strconv.ParseFloat(string(score), 64)
+	return value
+}
+
+// like collect for the ordered members of a listpack encoded sorted set, where
+// the range starts at the first member above min, or below max when reverse is set
+func collectListpackMembers(members []ScoredMember, reverse bool, offset, count int, gteMin, lteMax func(ScoredMember) bool) []ScoredMember {
+	result := []ScoredMember{}
+	inRange, i, step := lteMax, 0, 1
+
+	if reverse {
+		inRange, i, step = gteMin, len(members)-1, -1
+
+		for i >= 0 && !lteMax(members[i]) {
+			i--
+		}
+	} else {
+		for i < len(members) && !gteMin(members[i]) {
+			i++
+		}
+	}
+
+	for i += offset * step; i >= 0 && i < len(members) && count != 0 && inRange(members[i]); i += step {
+		result = append(result, members[i])
+		count--
+	}
+
+	return result
+}
+
+func (z *SortedSet) collect(x *skiplistNode, reverse bool, offset, count int, inRange func(*skiplistNode) bool) []ScoredMember {
+	result := []ScoredMember{}
+
+	for ; x != nil && offset > 0; offset-- {
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.levels[0].forward
+		}
+	}
+
+	for x != nil && count != 0 && inRange(x) {
+		result = append(result, ScoredMember{x.member, x.score})
+		count--
+
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.levels[0].forward
+		}
+	}
+
+	return result
+}
+
+func (r ScoreRange) gteMin(score float64) bool {
+	if r.MinExclusive {
+		return score > r.Min
+	}
+
+	return score >= r.Min
+}
+
+func (r ScoreRange) lteMax(score float64) bool {
+	if r.MaxExclusive {
+		return score < r.Max
+	}
+
+	return score <= r.Max
+}
+
+func (r ScoreRange) isEmpty() bool {
+	return r.Min > r.Max || (r.Min == r.Max && (r.MinExclusive || r.MaxExclusive))
+}
+
+func (r LexRange) gteMin(member string) bool {
+	switch r.Min.kind {
+	case lexNegativeInfinity:
+		return true
+	case lexPositiveInfinity:
+		return false
+	}
+
+	if r.Min.Exclusive {
+		return member > r.Min.Value
+	}
+
+	return member >= r.Min.Value
+}
+
+func (r LexRange) lteMax(member string) bool {
+	switch r.Max.kind {
+	case lexNegativeInfinity:
+		return false
+	case lexPositiveInfinity:
+		return true
+	}
+
+	if r.Max.Exclusive {
+		return member < r.Max.Value
+	}
+
+	return member <= r.Max.Value
+}
+
+func (r LexRange) isEmpty() bool {
+	if r.Min.kind == lexPositiveInfinity || r.Max.kind == lexNegativeInfinity {
+		return true
+	}
+
+	if r.Min.kind != lexValue || r.Max.kind != lexValue {
+		return false
+	}
+
+	return r.Min.Value > r.Max.Value || (r.Min.Value == r.Max.Value && (r.Min.Exclusive || r.Max.Exclusive))
+}
+
+// parses a score such as 1.5, -inf or (3 where ( makes the bound exclusive
+func ParseScoreBound(bound string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(bound, "(")
+
+	if exclusive {
+		bound = bound[1:]
+	}
+
+	score, err := ParseScore(bound)
+
+	if err != nil {
+		return 0, false, errors.New("ERR min or max is not a float")
+	}
+
+	return score, exclusive, nil
+}
+
+// parses a lexicographical bound: "-", "+", "[value" or "(value"
+func ParseLexBound(bound string) (LexBound, error) {
+	switch {
+	case bound == "-":
+		return LexBound{kind: lexNegativeInfinity}, nil
+	case bound == "+":
+		return LexBound{kind: lexPositiveInfinity}, nil
+	case strings.HasPrefix(bound, "["):
+		return LexBound{Value: bound[1:]}, nil
+	case strings.HasPrefix(bound, "("):
+		return LexBound{Value: bound[1:], Exclusive: true}, nil
+	default:
+		return LexBound{}, errors.New("ERR min or max not valid string range item")
+	}
+}
+
+// parses a score the way redis does, accepting inf and -inf but not NaN
+func ParseScore(score string) (float64, error) {
+	value, err := strconv.ParseFloat(score, 64)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if math.IsNaN(value) {
+		return 0, errors.New("score is not a number")
+	}
+
+	return value, nil
+}
+
+func FormatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	default:
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+}
+
+func (z *SortedSet) Copy() *SortedSet {
+	if z.lp != nil {
+		return &SortedSet{DataType: z.DataType, lp: z.lp.copy()}
+	}
+
+	c := &SortedSet{
+		DataType: z.DataType,
+		dict:     make(map[string]float64, len(z.dict)),
+		zsl:      newSkiplist(),
+		index:    NewScanIndex(),
+	}
+
+	for x := z.zsl.header.levels[0].forward; x != nil; x = x.levels[0].forward {
+		c.zsl.insert(x.score, x.member)
+		c.dict[x.member] = x.score
+		c.index.Add(x.member)
+	}
+
+	return c
+}
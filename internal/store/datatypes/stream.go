@@ -26,6 +26,10 @@ func (s *Stream) GetType() string {
 	return s.DataType
 }
 
+func (s *Stream) Encoding() string {
+	return "stream"
+}
+
 func (s *Stream) AddEntry(entryId string, pairs []string) (string, error) {
 
 	majorId, minorId, err := s.validateEntryId(entryId)
@@ -57,6 +61,32 @@ func (s *Stream) AddEntry(entryId string, pairs []string) (string, error) {
 	return entry.Id, nil
 }
 
+// returns the two parts of the id of the entry
+func (e Entry) IdParts() (int, int) {
+	return e.majorId, e.minorId
+}
+
+// appends an entry loaded from a snapshot, without waking the subscribers.
+// the id has to be greater than the one of the last entry.
+func (s *Stream) LoadEntry(majorId, minorId int, values map[string]string) error {
+	if len(s.Values) > 0 {
+		last := s.Values[len(s.Values)-1]
+
+		if majorId < last.majorId || majorId == last.majorId && minorId <= last.minorId {
+			return errors.New("stream entries out of order")
+		}
+	}
+
+	s.Values = append(s.Values, Entry{
+		Id:      fmt.Sprintf("%d-%d", majorId, minorId),
+		Values:  values,
+		majorId: majorId,
+		minorId: minorId,
+	})
+
+	return nil
+}
+
 func (s *Stream) GetRange(startId, endId string) ([]Entry, error) {
 
 	startMajorId, startMinorId, err := s.parseEntryIdForRange(startId, true)
@@ -266,3 +296,13 @@ func (s *Stream) generateMinorEntryId(majorId int) (int, int, error) {
 
 	return majorId, 0, nil
 }
+
+// Copy returns a copy of the stream without its subscribers. entries are
+// never modified once added, so they are shared.
+func (s *Stream) Copy() *Stream {
+	return &Stream{
+		DataType:    s.DataType,
+		Values:      append([]Entry{}, s.Values...),
+		Subscribers: make(map[string]chan string),
+	}
+}
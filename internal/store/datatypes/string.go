@@ -1,13 +1,219 @@
 package datatypes
 
-import "time"
+import (
+	"errors"
+	"strconv"
+)
 
+// String holds raw bytes so bitmap commands can update values in place
 type String struct {
 	DataType string
-	Value    string
-	Expiry   time.Time
+	Value    []byte
+	Raw      bool // set once the value is modified in place, which redis only does to raw strings
 }
 
+// strings up to this long are allocated along with their object header in redis
+const embstrSizeLimit = 44
+
 func (s *String) GetType() string {
 	return s.DataType
 }
+
+// int for values that read back as the same 64 bit integer, embstr for short
+// values and raw for the others and for values modified in place
+func (s *String) Encoding() string {
+	if s.Raw {
+		return "raw"
+	}
+
+	if len(s.Value) <= 20 {
+		if n, err := strconv.ParseInt(string(s.Value), 10, 64); err == nil && strconv.FormatInt(n, 10) == string(s.Value) {
+			return "int"
+		}
+	}
+
+	if len(s.Value) <= embstrSizeLimit {
+		return "embstr"
+	}
+
+	return "raw"
+}
+
+// appends value and returns the new length
+func (s *String) Append(value string) int {
+	s.Value = append(s.Value, value...)
+	s.Raw = true
+
+	return len(s.Value)
+}
+
+// start and end are inclusive offsets as given to GETRANGE, negative ones count from the end
+func (s *String) Range(start, end int) string {
+	start, end, ok := normalizeStringRange(start, end, len(s.Value))
+
+	if !ok {
+		return ""
+	}
+
+	return string(s.Value[start : end+1])
+}
+
+// overwrites the value from offset on, padding it with zero bytes when it is too short.
+// returns the new length.
+func (s *String) SetRange(offset int, value string) int {
+	if len(value) == 0 {
+		return len(s.Value)
+	}
+
+	s.grow(offset + len(value))
+	copy(s.Value[offset:], value)
+	s.Raw = true
+
+	return len(s.Value)
+}
+
+// pads the value with zero bytes up to length
+func (s *String) grow(length int) {
+	if length > len(s.Value) {
+		s.Value = append(s.Value, make([]byte, length-len(s.Value))...)
+	}
+}
+
+// the most bytes the LCS table may take, the default proto-max-bulk-len of redis
+const maxLCSTableSize = 512 * 1024 * 1024
+
+// LCSMatch is a range of the longest common subsequence found in both strings,
+// as reported by LCS IDX. A and B hold the inclusive start and end offsets.
+type LCSMatch struct {
+	A   [2]int
+	B   [2]int
+	Len int
+}
+
+// returns the longest common subsequence of a and b, and the ranges it is made of
+// starting from the end of the strings like redis does
+func LCS(a, b string) (string, []LCSMatch, error) {
+	alen, blen := len(a), len(b)
+
+	// the table is (alen+1) * (blen+1) uint32 cells, bounded like redis bounds
+	// its table by proto-max-bulk-len
+	if uint64(alen+1)*uint64(blen+1)*4 > maxLCSTableSize {
+		return "", nil, errors.New("ERR Insufficient memory, transient memory for LCS exceeds proto-max-bulk-len")
+	}
+
+	width := blen + 1
+	table := make([]uint32, (alen+1)*width)
+
+	for i := 1; i <= alen; i++ {
+		for j := 1; j <= blen; j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				table[i*width+j] = table[(i-1)*width+j-1] + 1
+			case table[(i-1)*width+j] > table[i*width+j-1]:
+				table[i*width+j] = table[(i-1)*width+j]
+			default:
+				table[i*width+j] = table[i*width+j-1]
+			}
+		}
+	}
+
+	idx := int(table[alen*width+blen])
+	result := make([]byte, idx)
+	matches := []LCSMatch{}
+
+	// walk the table back from the end, rebuilding the subsequence and its ranges.
+	// aStart == alen means no range is being tracked.
+	aStart, aEnd, bStart, bEnd := alen, 0, 0, 0
+	i, j := alen, blen
+
+	for i > 0 && j > 0 {
+		emit := false
+
+		if a[i-1] == b[j-1] {
+			result[idx-1] = a[i-1]
+
+			if aStart == alen {
+				aStart, aEnd = i-1, i-1
+				bStart, bEnd = j-1, j-1
+			} else if aStart == i && bStart == j {
+				aStart--
+				bStart--
+			} else {
+				emit = true
+			}
+
+			if aStart == 0 || bStart == 0 {
+				emit = true
+			}
+
+			idx--
+			i--
+			j--
+		} else {
+			if table[(i-1)*width+j] > table[i*width+j-1] {
+				i--
+			} else {
+				j--
+			}
+
+			if aStart != alen {
+				emit = true
+			}
+		}
+
+		if emit {
+			matches = append(matches, LCSMatch{
+				A:   [2]int{aStart, aEnd},
+				B:   [2]int{bStart, bEnd},
+				Len: aEnd - aStart + 1,
+			})
+
+			aStart = alen
+		}
+	}
+
+	return string(result), matches, nil
+}
+
+// converts the inclusive start and end offsets of GETRANGE, BITCOUNT and BITPOS into
+// valid offsets for a length. unlike list ranges, out of range ends are clamped.
+func normalizeStringRange(start, end, length int) (int, int, bool) {
+	if start < 0 && end < 0 && start > end {
+		return 0, 0, false
+	}
+
+	if start < 0 {
+		start += length
+	}
+
+	if end < 0 {
+		end += length
+	}
+
+	if start < 0 {
+		start = 0
+	}
+
+	if end < 0 {
+		end = 0
+	}
+
+	if end >= length {
+		end = length - 1
+	}
+
+	if length == 0 || start > end {
+		return 0, 0, false
+	}
+
+	return start, end, true
+}
+
+// Copy returns a deep copy of the string, used by COPY and PFMERGE
+func (s *String) Copy() *String {
+	return &String{
+		DataType: s.DataType,
+		Value:    append([]byte{}, s.Value...),
+		Raw:      s.Raw,
+	}
+}
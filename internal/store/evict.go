@@ -0,0 +1,281 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// maxmemory-policy values
+const (
+	NoEviction     = "noeviction"
+	AllKeysLRU     = "allkeys-lru"
+	AllKeysLFU     = "allkeys-lfu"
+	AllKeysRandom  = "allkeys-random"
+	VolatileLRU    = "volatile-lru"
+	VolatileLFU    = "volatile-lfu"
+	VolatileRandom = "volatile-random"
+	VolatileTTL    = "volatile-ttl"
+)
+
+var ErrOOM = errors.New("OOM command not allowed when used memory > 'maxmemory'.")
+
+// the number of candidates kept between evictions, like EVPOOL_SIZE in redis
+const evictionPoolSize = 16
+
+// a key that may be evicted, the higher idle the better
+type evictionCandidate struct {
+	idle uint64
+	key  string
+	db   *Store
+}
+
+// a key removed to bring the used memory back under maxmemory
+type EvictedKey struct {
+	DB  *Store
+	Key string
+}
+
+// returns whether policy is one of the maxmemory-policy values
+func ValidEvictionPolicy(policy string) bool {
+	switch policy {
+	case NoEviction, AllKeysLRU, AllKeysLFU, AllKeysRandom, VolatileLRU, VolatileLFU, VolatileRandom, VolatileTTL:
+		return true
+	default:
+		return false
+	}
+}
+
+// returns the estimated memory used by the keys of every database
+func (d *Databases) UsedMemory() int64 {
+	var used int64
+
+	for _, db := range d.dbs {
+		used += db.UsedMemory()
+	}
+
+	for {
+		peak := d.peak.Load()
+
+		if used <= peak || d.peak.CompareAndSwap(peak, used) {
+			return used
+		}
+	}
+}
+
+// returns the highest used memory seen by UsedMemory
+func (d *Databases) PeakMemory() int64 {
+	return d.peak.Load()
+}
+
+// returns how many keys were evicted since the server started
+func (d *Databases) EvictedKeys() int64 {
+	return d.evicted.Load()
+}
+
+// evicts keys chosen by policy until the used memory is no more than maxMemory.
+// like redis, the lru, lfu and ttl policies approximate the best key by looking
+// at samples keys per database and keeping the best candidates seen in a pool.
+// returns the evicted keys, and ErrOOM when the memory could not be freed.
+func (d *Databases) PerformEvictions(maxMemory int64, policy string, samples int) ([]EvictedKey, error) {
+	d.evictionMutex.Lock()
+	defer d.evictionMutex.Unlock()
+
+	evicted := []EvictedKey{}
+
+	for d.UsedMemory() > maxMemory {
+		if policy == NoEviction {
+			return evicted, ErrOOM
+		}
+
+		var candidate evictionCandidate
+		var ok bool
+
+		if policy == AllKeysRandom || policy == VolatileRandom {
+			candidate, ok = d.randomCandidate(policy == VolatileRandom)
+		} else {
+			candidate, ok = d.bestCandidate(policy, samples)
+		}
+
+		if !ok {
+			return evicted, ErrOOM
+		}
+
+		if candidate.db.evict(candidate.key, isVolatilePolicy(policy)) {
+			evicted = append(evicted, EvictedKey{candidate.db, candidate.key})
+			d.evicted.Add(1)
+		}
+	}
+
+	return evicted, nil
+}
+
+// refills the pool from every database and pops the best candidate that still exists
+func (d *Databases) bestCandidate(policy string, samples int) (evictionCandidate, bool) {
+	for _, db := range d.dbs {
+		db.populateEvictionPool(&d.evictionPool, policy, samples)
+	}
+
+	for len(d.evictionPool) > 0 {
+		last := len(d.evictionPool) - 1
+		candidate := d.evictionPool[last]
+		d.evictionPool = d.evictionPool[:last]
+
+		if candidate.db.holds(candidate.key, isVolatilePolicy(policy)) {
+			return candidate, true
+		}
+	}
+
+	return evictionCandidate{}, false
+}
+
+// picks a random key, visiting the databases in turn so none is drained first
+func (d *Databases) randomCandidate(volatile bool) (evictionCandidate, bool) {
+	for range d.dbs {
+		db := d.dbs[d.nextEvictionDB]
+		d.nextEvictionDB = (d.nextEvictionDB + 1) % len(d.dbs)
+
+		if key, ok := db.randomEvictionKey(volatile); ok {
+			return evictionCandidate{key: key, db: db}, true
+		}
+	}
+
+	return evictionCandidate{}, false
+}
+
+// samples keys and inserts the ones idler than the pool's worst into the pool,
+// which is kept sorted by idle and no larger than evictionPoolSize
+func (s *Store) populateEvictionPool(pool *[]evictionCandidate, policy string, samples int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	sampled := 0
+
+	// returns false once enough keys were sampled
+	sample := func(key string) bool {
+		if sampled == samples {
+			return false
+		}
+
+		sampled++
+
+		var idle uint64
+
+		switch policy {
+		case AllKeysLRU, VolatileLRU:
+			idle = uint64(s.data[key].idleTime())
+		case AllKeysLFU, VolatileLFU:
+			idle = 255 - uint64(s.data[key].frequency())
+		case VolatileTTL:
+			// the sooner it expires the better
+			idle = math.MaxUint64 - uint64(s.expires[key].UnixMilli())
+		}
+
+		insertEvictionCandidate(pool, evictionCandidate{idle, key, s})
+
+		return true
+	}
+
+	if isVolatilePolicy(policy) {
+		for key := range s.expires {
+			if !sample(key) {
+				return
+			}
+		}
+
+		return
+	}
+
+	for key := range s.data {
+		if !sample(key) {
+			return
+		}
+	}
+}
+
+func insertEvictionCandidate(pool *[]evictionCandidate, candidate evictionCandidate) {
+	p := *pool
+
+	for _, c := range p {
+		if c.key == candidate.key && c.db == candidate.db {
+			return
+		}
+	}
+
+	i := sort.Search(len(p), func(i int) bool {
+		return p[i].idle > candidate.idle
+	})
+
+	if len(p) == evictionPoolSize {
+		// the pool only keeps the idlest keys
+		if i == 0 {
+			return
+		}
+
+		copy(p, p[1:i])
+		p[i-1] = candidate
+		return
+	}
+
+	p = append(p, evictionCandidate{})
+	copy(p[i+1:], p[i:])
+	p[i] = candidate
+	*pool = p
+}
+
+// returns a random key, only among those with an expiry when volatile is set
+func (s *Store) randomEvictionKey(volatile bool) (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if volatile {
+		for key := range s.expires {
+			return key, true
+		}
+
+		return "", false
+	}
+
+	for key := range s.data {
+		return key, true
+	}
+
+	return "", false
+}
+
+// returns whether key is still there, with an expiry when volatile is set
+func (s *Store) holds(key string, volatile bool) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if volatile {
+		_, ok := s.expires[key]
+		return ok
+	}
+
+	_, ok := s.data[key]
+
+	return ok
+}
+
+// deletes key unless it went away, or lost its expiry when volatile is set, since it was picked
+func (s *Store) evict(key string, volatile bool) bool {
+	s.lock()
+	defer s.unlock()
+
+	if _, ok := s.data[key]; !ok {
+		return false
+	}
+
+	if _, ok := s.expires[key]; volatile && !ok {
+		return false
+	}
+
+	s.deleteKey(key)
+
+	return true
+}
+
+func isVolatilePolicy(policy string) bool {
+	return policy == VolatileLRU || policy == VolatileLFU || policy == VolatileRandom || policy == VolatileTTL
+}
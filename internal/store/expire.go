@@ -0,0 +1,116 @@
+package store
+
+import "time"
+
+// tuning of the active expire cycle at effort 1, scaled up with the effort like
+// ACTIVE_EXPIRE_CYCLE_* in redis
+const (
+	activeExpireKeysPerLoop     = 20 // keys sampled per loop
+	activeExpireSlowTimePerc    = 25 // max share of each tick spent expiring
+	activeExpireAcceptableStale = 10 // % of expired keys in a sample that ends the cycle
+)
+
+// Expire outcomes
+const (
+	ExpireSkipped = iota // the key does not exist or the condition prevented the update
+	ExpireSet
+	ExpireDeleted // the expiry was in the past, so the key was deleted
+)
+
+// sets the expiry of key when every condition (ExpireNX, ExpireXX, ExpireGT or
+// ExpireLT) is met. past expiries delete the key.
+func (s *Store) Expire(key string, expiry time.Time, conditions []string) int {
+	s.lock()
+	defer s.unlock()
+
+	if _, ok := s.lookup(key); !ok {
+		return ExpireSkipped
+	}
+
+	current, hasExpiry := s.expires[key]
+
+	for _, condition := range conditions {
+		if !expiryConditionMet(condition, current, hasExpiry, expiry) {
+			return ExpireSkipped
+		}
+	}
+
+	if !expiry.After(time.Now()) {
+		s.deleteKey(key)
+		return ExpireDeleted
+	}
+
+	s.expires[key] = expiry
+
+	return ExpireSet
+}
+
+// returns the expiry of key, zero when it does not expire. exists is false for missing keys.
+func (s *Store) GetExpiry(key string) (expiry time.Time, exists bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, ok := s.lookup(key); !ok {
+		return time.Time{}, false
+	}
+
+	return s.expires[key], true
+}
+
+// removes the expiry of key, returning false when it had none or does not exist
+func (s *Store) Persist(key string) bool {
+	s.lock()
+	defer s.unlock()
+
+	if _, ok := s.lookup(key); !ok {
+		return false
+	}
+
+	if _, ok := s.expires[key]; !ok {
+		return false
+	}
+
+	delete(s.expires, key)
+
+	return true
+}
+
+// deletes expired keys that are never accessed again. it samples keysPerLoop
+// keys with an expiry and keeps going while more than acceptableStale percent
+// of the sample had expired, until deadline. expired is called with the keys
+// deleted before the lock is released, so they are replicated before any later
+// write to them.
+func (s *Store) activeExpire(keysPerLoop, acceptableStale int, deadline time.Time, expired func(db *Store, keys []string)) {
+	s.lock()
+	defer s.unlock()
+
+	start := time.Now()
+	deleted := []string{}
+
+	for {
+		sampled, stale := 0, 0
+
+		// map iteration starts at a random position, which makes this a random sample
+		for key, expiry := range s.expires {
+			if sampled == keysPerLoop {
+				break
+			}
+
+			sampled++
+
+			if !expiry.After(start) {
+				s.deleteKey(key)
+				deleted = append(deleted, key)
+				stale++
+			}
+		}
+
+		if sampled == 0 || stale*100 <= sampled*acceptableStale || time.Now().After(deadline) {
+			break
+		}
+	}
+
+	if len(deleted) > 0 {
+		expired(s, deleted)
+	}
+}
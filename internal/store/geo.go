@@ -0,0 +1,115 @@
+package store
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+var errGeoMemberNotFound = errors.New("ERR could not decode requested zset member")
+
+// GeoQuery holds the parsed arguments of GEOSEARCH and GEOSEARCHSTORE. With
+// FromMember set the shape is centered on Member instead of its coordinates.
+// Count limits the results, stopping the search early with Any. Sort is 0,
+// SortAsc or SortDesc.
+type GeoQuery struct {
+	Shape      datatypes.GeoShape
+	FromMember bool
+	Member     string
+	Sort       int
+	Count      int
+	Any        bool
+}
+
+// orders of GEOSEARCH results, by distance from the center
+const (
+	SortAsc = iota + 1
+	SortDesc
+)
+
+// returns the members of the sorted set at key within the query shape
+func (s *Store) GeoSearch(key string, query GeoQuery) ([]datatypes.GeoPoint, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.geoSearch(key, query)
+}
+
+// stores the members found by GeoSearch in destination and returns how many there are.
+// they keep their geohash as score, or get their distance from the center with storeDist.
+func (s *Store) GeoSearchStore(destination, key string, query GeoQuery, storeDist bool) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	points, err := s.geoSearch(key, query)
+
+	if err != nil {
+		return 0, err
+	}
+
+	result := datatypes.NewSortedSet()
+
+	for _, point := range points {
+		score := point.Score
+
+		if storeDist {
+			score = point.Distance / query.Shape.Conversion
+		}
+
+		result.Add(point.Member, score, datatypes.ZAddOptions{})
+	}
+
+	s.storeSortedSet(destination, result)
+	s.handleReadyKeys()
+
+	return len(points), nil
+}
+
+// callers must hold the read lock
+func (s *Store) geoSearch(key string, query GeoQuery) ([]datatypes.GeoPoint, error) {
+	zset, err := s.getSortedSet(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if query.FromMember {
+		if zset == nil {
+			return nil, errGeoMemberNotFound
+		}
+
+		score, ok := zset.Score(query.Member)
+
+		if !ok {
+			return nil, errGeoMemberNotFound
+		}
+
+		query.Shape.Longitude, query.Shape.Latitude = datatypes.GeoDecode(score)
+	}
+
+	if zset == nil {
+		return []datatypes.GeoPoint{}, nil
+	}
+
+	limit := 0
+
+	if query.Any {
+		limit = query.Count
+	}
+
+	points := zset.GeoSearch(query.Shape, limit)
+
+	switch query.Sort {
+	case SortAsc:
+		sort.SliceStable(points, func(i, j int) bool { return points[i].Distance < points[j].Distance })
+	case SortDesc:
+		sort.SliceStable(points, func(i, j int) bool { return points[i].Distance > points[j].Distance })
+	}
+
+	if query.Count > 0 && len(points) > query.Count {
+		points = points[:query.Count]
+	}
+
+	return points, nil
+}
@@ -0,0 +1,577 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// returns the hash stored at key, or nil when the key does not exist
+func (s *Store) getHash(key string) (*datatypes.Hash, error) {
+	e, ok := s.lookup(key)
+
+	if !ok {
+		return nil, nil
+	}
+
+	hash, ok := e.(*datatypes.Hash)
+
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return hash, nil
+}
+
+// same as getHash but creates an empty hash when the key does not exist
+func (s *Store) getOrCreateHash(key string) (*datatypes.Hash, error) {
+	hash, err := s.getHash(key)
+
+	if err != nil || hash != nil {
+		return hash, err
+	}
+
+	hash = datatypes.NewHash()
+	s.setKey(key, hash)
+
+	return hash, nil
+}
+
+// sets the field value pairs and returns the number of fields that were added
+func (s *Store) HSet(key string, pairs []string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	hash, err := s.getOrCreateHash(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+
+	for i := 0; i < len(pairs); i += 2 {
+		if hash.Set(pairs[i], pairs[i+1]) {
+			added++
+		}
+	}
+
+	return added, nil
+}
+
+func (s *Store) HSetNX(key, field, value string) (bool, error) {
+	s.lock()
+	defer s.unlock()
+
+	hash, err := s.getOrCreateHash(key)
+
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := hash.Get(field); ok {
+		return false, nil
+	}
+
+	hash.Set(field, value)
+
+	return true, nil
+}
+
+func (s *Store) HGet(key, field string) (string, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return "", false, err
+	}
+
+	value, ok := hash.Get(field)
+
+	return value, ok, nil
+}
+
+// returns the values of the fields, found[i] is false when fields[i] does not exist
+func (s *Store) HMGet(key string, fields []string) (values []string, found []bool, err error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	values = make([]string, len(fields))
+	found = make([]bool, len(fields))
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return values, found, err
+	}
+
+	for i, field := range fields {
+		values[i], found[i] = hash.Get(field)
+	}
+
+	return values, found, nil
+}
+
+func (s *Store) HDel(key string, fields []string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return 0, err
+	}
+
+	deleted := 0
+
+	for _, field := range fields {
+		if hash.Delete(field) {
+			deleted++
+		}
+	}
+
+	s.deleteIfEmptyHash(key, hash)
+
+	return deleted, nil
+}
+
+func (s *Store) HLen(key string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return 0, err
+	}
+
+	return hash.Len(), nil
+}
+
+func (s *Store) HKeys(key string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return []string{}, err
+	}
+
+	return hash.Fields(), nil
+}
+
+func (s *Store) HVals(key string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return []string{}, err
+	}
+
+	values := []string{}
+
+	for _, field := range hash.Fields() {
+		value, _ := hash.Get(field)
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// returns the fields and values of the hash flattened as field1, value1, field2, ...
+func (s *Store) HGetAll(key string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return []string{}, err
+	}
+
+	return hashPairs(hash, hash.Fields()), nil
+}
+
+func (s *Store) HIncrBy(key, field string, increment int64) (int64, error) {
+	s.lock()
+	defer s.unlock()
+
+	hash, err := s.getOrCreateHash(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var current int64
+
+	if value, ok := hash.Get(field); ok {
+		current, err = strconv.ParseInt(value, 10, 64)
+
+		if err != nil {
+			return 0, errors.New("ERR hash value is not an integer")
+		}
+	}
+
+	if (increment > 0 && current > math.MaxInt64-increment) || (increment < 0 && current < math.MinInt64-increment) {
+		return 0, errors.New("ERR increment or decrement would overflow")
+	}
+
+	current += increment
+	hash.Update(field, strconv.FormatInt(current, 10))
+
+	return current, nil
+}
+
+// returns the new value formatted the way it is stored
+func (s *Store) HIncrByFloat(key, field string, increment float64) (string, error) {
+	s.lock()
+	defer s.unlock()
+
+	hash, err := s.getOrCreateHash(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	var current float64
+
+	if value, ok := hash.Get(field); ok {
+		current, err = strconv.ParseFloat(value, 64)
+
+		if err != nil {
+			return "", errors.New("ERR hash value is not a float")
+		}
+	}
+
+	current += increment
+
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return "", errors.New("ERR increment would produce NaN or Infinity")
+	}
+
+	value := strconv.FormatFloat(current, 'f', -1, 64)
+	hash.Update(field, value)
+
+	return value, nil
+}
+
+func (s *Store) HStrLen(key, field string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return 0, err
+	}
+
+	value, _ := hash.Get(field)
+
+	return len(value), nil
+}
+
+// returns random fields following the HRANDFIELD rules: a positive count returns
+// distinct fields, a negative count may return the same field multiple times.
+// with withValues the result is flattened as field1, value1, field2, ...
+func (s *Store) HRandField(key string, count int, withValues bool) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return []string{}, err
+	}
+
+	fields := hash.Fields()
+	picked := []string{}
+
+	if count >= 0 {
+		rand.Shuffle(len(fields), func(i, j int) {
+			fields[i], fields[j] = fields[j], fields[i]
+		})
+
+		if count < len(fields) {
+			fields = fields[:count]
+		}
+
+		picked = fields
+	} else {
+		for i := 0; i < -count; i++ {
+			picked = append(picked, fields[rand.Intn(len(fields))])
+		}
+	}
+
+	if !withValues {
+		return picked, nil
+	}
+
+	return hashPairs(hash, picked), nil
+}
+
+// returns the next cursor and the matching fields and values flattened as field1, value1, ...
+func (s *Store) HScan(key string, cursor uint64, count int, pattern string) (uint64, []string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil || hash == nil {
+		return 0, []string{}, err
+	}
+
+	fields, next := hash.Scan(cursor, count)
+	matching := []string{}
+
+	for _, field := range fields {
+		if pattern == "" || glob.Match(pattern, field) {
+			matching = append(matching, field)
+		}
+	}
+
+	return next, hashPairs(hash, matching), nil
+}
+
+// HExpire conditions, mirroring the NX, XX, GT and LT options
+const (
+	ExpireAlways = ""
+	ExpireNX     = "NX"
+	ExpireXX     = "XX"
+	ExpireGT     = "GT"
+	ExpireLT     = "LT"
+)
+
+// HExpire reply codes for each field
+const (
+	HashFieldMissing    = -2
+	HashFieldNoExpiry   = -1
+	HashFieldNotUpdated = 0
+	HashFieldExpirySet  = 1
+	HashFieldDeleted    = 2
+	HashFieldPersisted  = 1
+)
+
+// how many volatile hashes are checked on every background reclaim tick
+const hashFieldReclaimBatch = 20
+
+// sets the expiry of each field when condition allows it and returns a reply code per field.
+// an expiry that is not in the future deletes the field right away.
+func (s *Store) HExpire(key string, fields []string, expiry time.Time, condition string) ([]int, error) {
+	s.lock()
+	defer s.unlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]int, len(fields))
+
+	for i, field := range fields {
+		if hash == nil {
+			codes[i] = HashFieldMissing
+			continue
+		}
+
+		if _, ok := hash.Get(field); !ok {
+			codes[i] = HashFieldMissing
+			continue
+		}
+
+		current, hasExpiry := hash.Expiry(field)
+
+		if !expiryConditionMet(condition, current, hasExpiry, expiry) {
+			codes[i] = HashFieldNotUpdated
+			continue
+		}
+
+		if !expiry.After(time.Now()) {
+			hash.Delete(field)
+			codes[i] = HashFieldDeleted
+			continue
+		}
+
+		hash.SetExpiry(field, expiry)
+		s.volatileHashes[key] = struct{}{}
+		codes[i] = HashFieldExpirySet
+	}
+
+	if hash != nil {
+		s.deleteIfEmptyHash(key, hash)
+	}
+
+	return codes, nil
+}
+
+// returns the remaining time to live of each field in milliseconds, or HashFieldMissing / HashFieldNoExpiry
+func (s *Store) HTTL(key string, fields []string) ([]int64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ttls := make([]int64, len(fields))
+
+	for i, field := range fields {
+		if hash == nil {
+			ttls[i] = HashFieldMissing
+			continue
+		}
+
+		if _, ok := hash.Get(field); !ok {
+			ttls[i] = HashFieldMissing
+			continue
+		}
+
+		expiry, ok := hash.Expiry(field)
+
+		if !ok {
+			ttls[i] = HashFieldNoExpiry
+			continue
+		}
+
+		ttls[i] = time.Until(expiry).Milliseconds()
+	}
+
+	return ttls, nil
+}
+
+func (s *Store) HPersist(key string, fields []string) ([]int, error) {
+	s.lock()
+	defer s.unlock()
+
+	hash, err := s.getHash(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]int, len(fields))
+
+	for i, field := range fields {
+		if hash == nil {
+			codes[i] = HashFieldMissing
+			continue
+		}
+
+		if _, ok := hash.Get(field); !ok {
+			codes[i] = HashFieldMissing
+			continue
+		}
+
+		if !hash.Persist(field) {
+			codes[i] = HashFieldNoExpiry
+			continue
+		}
+
+		codes[i] = HashFieldPersisted
+	}
+
+	return codes, nil
+}
+
+// checks the NX, XX, GT and LT conditions against the current expiry, a missing
+// expiry is treated as an infinite time to live
+func expiryConditionMet(condition string, current time.Time, hasExpiry bool, expiry time.Time) bool {
+	switch condition {
+	case ExpireNX:
+		return !hasExpiry
+	case ExpireXX:
+		return hasExpiry
+	case ExpireGT:
+		return hasExpiry && expiry.After(current)
+	case ExpireLT:
+		return !hasExpiry || expiry.Before(current)
+	default:
+		return true
+	}
+}
+
+// the fields of the hash at Key reclaimed as they expired. Deleted is set when
+// they were its last ones, so the key was deleted with them.
+type ReclaimedFields struct {
+	Key     string
+	Fields  []string
+	Deleted bool
+}
+
+// expired fields are invisible to reads, but only go away once reclaimed here,
+// a batch of volatile hashes at a time, see Databases.ReclaimExpiredHashFields.
+// like for the keys of activeExpire, reclaimed is called with the fields removed
+// before the lock is released.
+func (s *Store) reclaimExpiredHashFields(reclaimed func(db *Store, hashes []ReclaimedFields)) {
+	s.lock()
+	defer s.unlock()
+
+	checked := 0
+	hashes := []ReclaimedFields{}
+
+	for key := range s.volatileHashes {
+		if checked == hashFieldReclaimBatch {
+			break
+		}
+
+		checked++
+
+		e, ok := s.data[key]
+
+		if !ok {
+			delete(s.volatileHashes, key)
+			continue
+		}
+
+		hash, ok := e.value.(*datatypes.Hash)
+
+		if !ok {
+			delete(s.volatileHashes, key)
+			continue
+		}
+
+		if fields := hash.DeleteExpired(); len(fields) > 0 {
+			s.dirty = append(s.dirty, key)
+			s.deleteIfEmptyHash(key, hash)
+			hashes = append(hashes, ReclaimedFields{Key: key, Fields: fields, Deleted: hash.Len() == 0})
+		}
+
+		if hash.VolatileLen() == 0 {
+			delete(s.volatileHashes, key)
+		}
+	}
+
+	if len(hashes) > 0 {
+		reclaimed(s, hashes)
+	}
+}
+
+func hashPairs(hash *datatypes.Hash, fields []string) []string {
+	pairs := make([]string, 0, len(fields)*2)
+
+	for _, field := range fields {
+		value, _ := hash.Get(field)
+		pairs = append(pairs, field, value)
+	}
+
+	return pairs
+}
+
+// redis never keeps empty hashes around
+func (s *Store) deleteIfEmptyHash(key string, hash *datatypes.Hash) {
+	if hash.Len() == 0 {
+		s.deleteKey(key)
+	}
+}
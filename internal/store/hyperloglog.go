@@ -0,0 +1,157 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+var errNoSuchKey = errors.New("ERR The specified key does not exist")
+
+// returns the HyperLogLog stored at key, or nil when the key does not exist.
+// strings that are not HyperLogLogs return datatypes.ErrInvalidHLL.
+func (s *Store) getHyperLogLog(key string) (*datatypes.String, error) {
+	str, err := s.getString(key)
+
+	if err != nil || str == nil {
+		return nil, err
+	}
+
+	if !str.IsHyperLogLog() {
+		return nil, datatypes.ErrInvalidHLL
+	}
+
+	return str, nil
+}
+
+// adds elements to the HyperLogLog at key, creating it when missing.
+// returns whether the estimated cardinality may have changed.
+func (s *Store) PFAdd(key string, elements []string) (bool, error) {
+	s.lock()
+	defer s.unlock()
+
+	hll, err := s.getHyperLogLog(key)
+
+	if err != nil {
+		return false, err
+	}
+
+	updated := false
+
+	if hll == nil {
+		hll = datatypes.NewHyperLogLog()
+		s.setKey(key, hll)
+		updated = true
+	}
+
+	for _, element := range elements {
+		added, err := hll.HLLAdd(element)
+
+		if err != nil {
+			return false, err
+		}
+
+		updated = updated || added
+	}
+
+	return updated, nil
+}
+
+// estimates the cardinality of the union of the HyperLogLogs at keys, missing
+// keys counting as empty. With a single key the cardinality is cached in the
+// value itself, which is why the write lock is taken.
+func (s *Store) PFCount(keys []string) (uint64, error) {
+	s.lock()
+	defer s.unlock()
+
+	hlls := make([]*datatypes.String, 0, len(keys))
+
+	for _, key := range keys {
+		hll, err := s.getHyperLogLog(key)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if hll != nil {
+			hlls = append(hlls, hll)
+		}
+	}
+
+	if len(keys) == 1 {
+		if len(hlls) == 0 {
+			return 0, nil
+		}
+
+		return hlls[0].HLLCount()
+	}
+
+	return datatypes.HLLCountUnion(hlls)
+}
+
+// merges the HyperLogLogs at keys into the one at destination, creating it when missing
+func (s *Store) PFMerge(destination string, keys []string) error {
+	s.lock()
+	defer s.unlock()
+
+	hll, err := s.getHyperLogLog(destination)
+
+	if err != nil {
+		return err
+	}
+
+	sources := make([]*datatypes.String, 0, len(keys))
+
+	for _, key := range keys {
+		source, err := s.getHyperLogLog(key)
+
+		if err != nil {
+			return err
+		}
+
+		if source != nil {
+			sources = append(sources, source)
+		}
+	}
+
+	if hll == nil {
+		merged := datatypes.NewHyperLogLog()
+
+		if err := merged.HLLMerge(sources); err != nil {
+			return err
+		}
+
+		s.setKey(destination, merged)
+
+		return nil
+	}
+
+	// merge into a copy so a corrupted source leaves destination untouched
+	merged := hll.Copy()
+
+	if err := merged.HLLMerge(sources); err != nil {
+		return err
+	}
+
+	hll.Value = merged.Value
+
+	return nil
+}
+
+// runs fn on the HyperLogLog at key for the PFDEBUG subcommands, which require the key to exist
+func (s *Store) PFDebug(key string, fn func(hll *datatypes.String) error) error {
+	s.lock()
+	defer s.unlock()
+
+	hll, err := s.getHyperLogLog(key)
+
+	if err != nil {
+		return err
+	}
+
+	if hll == nil {
+		return errNoSuchKey
+	}
+
+	return fn(hll)
+}
@@ -0,0 +1,329 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+var ErrBusyKey = errors.New("BUSYKEY Target key name already exists.")
+
+// Restore outcomes
+const (
+	Restored       = iota
+	RestoreSkipped // the expiry was in the past, so nothing was stored
+	RestoreDeleted // same, and the key that would have been replaced was deleted
+)
+
+var (
+	errNoSuchKeyToRename = errors.New("ERR no such key")
+	errSameObject        = errors.New("ERR source and destination objects are the same")
+)
+
+// deletes keys and returns how many existed
+func (s *Store) Del(keys []string) int {
+	s.lock()
+	defer s.unlock()
+
+	deleted := 0
+
+	for _, key := range keys {
+		if _, ok := s.delete(key); ok {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// returns how many of keys exist, a key given twice being counted twice
+func (s *Store) Exists(keys []string) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	count := 0
+
+	for _, key := range keys {
+		if _, ok := s.peek(key); ok {
+			count++
+		}
+	}
+
+	return count
+}
+
+// renames key to newKey, overwriting it unless onlyIfMissing is set.
+// returns false when newKey exists and onlyIfMissing prevented the rename.
+func (s *Store) Rename(key, newKey string, onlyIfMissing bool) (bool, error) {
+	s.lock()
+	defer s.unlock()
+
+	e, ok := s.lookup(key)
+
+	if !ok {
+		return false, errNoSuchKeyToRename
+	}
+
+	if key == newKey {
+		return !onlyIfMissing, nil
+	}
+
+	if _, exists := s.lookup(newKey); exists && onlyIfMissing {
+		return false, nil
+	}
+
+	expiry, hasExpiry := s.expires[key]
+
+	s.deleteKey(key)
+	s.add(newKey, e)
+
+	// the key keeps its expiry under the new name
+	if hasExpiry {
+		s.expires[newKey] = expiry
+	}
+
+	s.handleReadyKeys()
+
+	return true, nil
+}
+
+// copies the value at source to destination in dst, which may be s itself,
+// overwriting it only with replace. returns false when source is missing or
+// destination exists without replace.
+func (s *Store) Copy(source string, dst *Store, destination string, replace bool) (bool, error) {
+	if s == dst && source == destination {
+		return false, errSameObject
+	}
+
+	unlock := lockPair(s, dst)
+	defer unlock()
+
+	e, ok := s.lookup(source)
+
+	if !ok {
+		return false, nil
+	}
+
+	if _, exists := dst.lookup(destination); exists && !replace {
+		return false, nil
+	}
+
+	dst.add(destination, copyData(e))
+
+	if expiry, ok := s.expires[source]; ok {
+		dst.expires[destination] = expiry
+	}
+
+	dst.handleReadyKeys()
+
+	return true, nil
+}
+
+// stores value at key with expiry, zero when it does not expire, failing with
+// ErrBusyKey when key exists and replace is not set. idle and freq, unless
+// negative, set the access clocks of the key like RESTORE IDLETIME and FREQ.
+// returns one of the Restore outcomes.
+func (s *Store) Restore(key string, value Data, expiry time.Time, replace bool, idle time.Duration, freq int) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	_, exists := s.lookup(key)
+
+	if exists && !replace {
+		return RestoreSkipped, ErrBusyKey
+	}
+
+	// the restored value is a new object, with new clocks
+	s.deleteKey(key)
+
+	if !expiry.IsZero() && !expiry.After(time.Now()) {
+		if exists {
+			return RestoreDeleted, nil
+		}
+
+		return RestoreSkipped, nil
+	}
+
+	s.add(key, value)
+
+	if !expiry.IsZero() {
+		s.expires[key] = expiry
+	}
+
+	s.data[key].setClocks(idle, freq)
+	s.handleReadyKeys()
+
+	return Restored, nil
+}
+
+// returns how many of keys exist, recording an access to each of them
+func (s *Store) Touch(keys []string) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	count := 0
+
+	for _, key := range keys {
+		if _, ok := s.lookup(key); ok {
+			count++
+		}
+	}
+
+	return count
+}
+
+// returns a random key, ok is false when the keyspace is empty
+func (s *Store) RandomKey() (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// map iteration starts at a random position
+	for key := range s.data {
+		if _, ok := s.peek(key); ok {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// returns the next cursor and the keys of the keyspace matching pattern and, when
+// given, holding a value of dataType. the cursor walks the buckets of a ScanIndex,
+// so every key present for the whole iteration is returned.
+func (s *Store) Scan(cursor uint64, count int, pattern, dataType string) (uint64, []string) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	matching := []string{}
+
+	next := s.keys.Scan(cursor, count, func(key string) {
+		e, ok := s.peek(key)
+
+		if !ok {
+			return
+		}
+
+		if pattern != "" && !glob.Match(pattern, key) {
+			return
+		}
+
+		if dataType != "" && e.GetType() != dataType {
+			return
+		}
+
+		matching = append(matching, key)
+	})
+
+	return next, matching
+}
+
+// stores value at key with expiry, zero when it does not expire. used to load
+// keys from a snapshot.
+func (s *Store) Load(key string, value Data, expiry time.Time) {
+	s.lock()
+	defer s.unlock()
+
+	s.add(key, value)
+
+	if !expiry.IsZero() {
+		s.expires[key] = expiry
+	}
+
+	s.handleReadyKeys()
+}
+
+// calls fn with the value at key and its expiry, zero when it does not expire,
+// recording the access. returns the version of the key as fn saw it, see
+// DelIfVersion, and false when key does not exist. fn runs with the read lock
+// held and must not modify the value.
+func (s *Store) View(key string, fn func(value Data, expiry time.Time)) (uint64, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	e, ok := s.lookup(key)
+
+	if !ok {
+		return 0, false
+	}
+
+	fn(e, s.expires[key])
+
+	return s.data[key].version, true
+}
+
+// deletes key when it was not written since View returned version. returns
+// whether it was deleted, and whether it still existed.
+func (s *Store) DelIfVersion(key string, version uint64) (deleted, exists bool) {
+	s.lock()
+	defer s.unlock()
+
+	if _, ok := s.peek(key); !ok {
+		return false, false
+	}
+
+	if s.data[key].version != version {
+		return false, true
+	}
+
+	s.deleteKey(key)
+
+	return true, true
+}
+
+// calls fn with every key that has not expired, its value and its expiry (zero
+// when it does not expire). fn runs with the read lock held and must not modify
+// the store.
+func (s *Store) ForEach(fn func(key string, value Data, expiry time.Time)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for key := range s.data {
+		if e, ok := s.peek(key); ok {
+			fn(key, e, s.expires[key])
+		}
+	}
+}
+
+// removes key and returns its value, ok is false when it did not exist.
+// callers must hold the write lock.
+func (s *Store) delete(key string) (Data, bool) {
+	e, ok := s.lookup(key)
+
+	s.deleteKey(key)
+
+	return e, ok
+}
+
+// stores e at key, replacing whatever was there, and wakes up the clients
+// blocked on key. callers must hold the write lock and handle the ready keys.
+func (s *Store) add(key string, e Data) {
+	s.setKey(key, e)
+
+	if hash, ok := e.(*datatypes.Hash); ok && hash.VolatileLen() > 0 {
+		s.volatileHashes[key] = struct{}{}
+	}
+
+	s.signalKeyAsReady(key)
+}
+
+// returns a deep copy of the value
+func copyData(e Data) Data {
+	switch v := e.(type) {
+	case *datatypes.String:
+		return v.Copy()
+	case *datatypes.List:
+		return v.Copy()
+	case *datatypes.Hash:
+		return v.Copy()
+	case *datatypes.Set:
+		return v.Copy()
+	case *datatypes.SortedSet:
+		return v.Copy()
+	case *datatypes.Stream:
+		return v.Copy()
+	default:
+		return e
+	}
+}
@@ -0,0 +1,337 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// returns the list stored at key, or nil when the key does not exist
+func (s *Store) getList(key string) (*datatypes.List, error) {
+	e, ok := s.lookup(key)
+
+	if !ok {
+		return nil, nil
+	}
+
+	list, ok := e.(*datatypes.List)
+
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return list, nil
+}
+
+// pushes values on the head (left) or tail of the list and returns its new length.
+// with onlyIfExists nothing is created and 0 is returned for missing keys.
+func (s *Store) Push(key string, values []string, left, onlyIfExists bool) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	list, err := s.getList(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if list == nil {
+		if onlyIfExists {
+			return 0, nil
+		}
+
+		list = datatypes.NewList()
+		s.setKey(key, list)
+	}
+
+	if left {
+		list.PushLeft(values...)
+	} else {
+		list.PushRight(values...)
+	}
+
+	length := list.Len()
+
+	s.signalKeyAsReady(key)
+	s.handleReadyKeys()
+
+	return length, nil
+}
+
+// pops up to count elements from the head (left) or tail of the list.
+// a nil slice means the key does not exist.
+func (s *Store) Pop(key string, left bool, count int) ([]string, error) {
+	s.lock()
+	defer s.unlock()
+
+	values, _, err := s.popFrom(key, left, count)
+
+	return values, err
+}
+
+// pops up to count elements from the first non empty list among keys.
+// the result is the key followed by the popped values, nil when all lists are empty.
+func (s *Store) MPop(keys []string, left bool, count int) ([]string, error) {
+	s.lock()
+	defer s.unlock()
+
+	for _, key := range keys {
+		values, ok, err := s.popFrom(key, left, count)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			return append([]string{key}, values...), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// blocking version of MPop, waits until a value is pushed to one of keys or ctx is done
+func (s *Store) BPop(ctx context.Context, keys []string, left bool, count int) ([]string, error) {
+	return s.Block(ctx, keys, func(key string) ([]string, bool, error) {
+		values, ok, err := s.popFrom(key, left, count)
+
+		if err != nil || !ok {
+			return nil, false, err
+		}
+
+		return append([]string{key}, values...), true, nil
+	})
+}
+
+// blocking version of LMove, waits until a value is pushed to source or ctx is done
+func (s *Store) BLMove(ctx context.Context, source, destination string, fromLeft, toLeft bool) (string, bool, error) {
+	result, err := s.Block(ctx, []string{source}, func(key string) ([]string, bool, error) {
+		value, ok, err := s.move(source, destination, fromLeft, toLeft)
+
+		if err != nil || !ok {
+			return nil, false, err
+		}
+
+		return []string{value}, true, nil
+	})
+
+	if err != nil || result == nil {
+		return "", false, err
+	}
+
+	return result[0], true, nil
+}
+
+// pops without locking, callers must hold the write lock.
+// the bool is false when the key does not exist.
+func (s *Store) popFrom(key string, left bool, count int) ([]string, bool, error) {
+	list, err := s.getList(key)
+
+	if err != nil || list == nil {
+		return nil, false, err
+	}
+
+	values := []string{}
+
+	for len(values) < count {
+		var value string
+		var ok bool
+
+		if left {
+			value, ok = list.PopLeft()
+		} else {
+			value, ok = list.PopRight()
+		}
+
+		if !ok {
+			break
+		}
+
+		values = append(values, value)
+	}
+
+	s.deleteIfEmptyList(key, list)
+
+	return values, true, nil
+}
+
+func (s *Store) LRange(key string, start, stop int) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list, err := s.getList(key)
+
+	if err != nil || list == nil {
+		return []string{}, err
+	}
+
+	return list.Range(start, stop), nil
+}
+
+func (s *Store) LLen(key string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list, err := s.getList(key)
+
+	if err != nil || list == nil {
+		return 0, err
+	}
+
+	return list.Len(), nil
+}
+
+func (s *Store) LIndex(key string, index int) (string, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list, err := s.getList(key)
+
+	if err != nil || list == nil {
+		return "", false, err
+	}
+
+	value, ok := list.Index(index)
+
+	return value, ok, nil
+}
+
+func (s *Store) LSet(key string, index int, value string) error {
+	s.lock()
+	defer s.unlock()
+
+	list, err := s.getList(key)
+
+	if err != nil {
+		return err
+	}
+
+	if list == nil {
+		return errors.New("ERR no such key")
+	}
+
+	if !list.Set(index, value) {
+		return errors.New("ERR index out of range")
+	}
+
+	return nil
+}
+
+// returns the new length of the list, -1 when pivot is not found and 0 when the key does not exist
+func (s *Store) LInsert(key string, before bool, pivot, value string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	list, err := s.getList(key)
+
+	if err != nil || list == nil {
+		return 0, err
+	}
+
+	return list.Insert(pivot, value, before), nil
+}
+
+func (s *Store) LRem(key string, count int, value string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	list, err := s.getList(key)
+
+	if err != nil || list == nil {
+		return 0, err
+	}
+
+	removed := list.Remove(value, count)
+	s.deleteIfEmptyList(key, list)
+
+	return removed, nil
+}
+
+func (s *Store) LTrim(key string, start, stop int) error {
+	s.lock()
+	defer s.unlock()
+
+	list, err := s.getList(key)
+
+	if err != nil || list == nil {
+		return err
+	}
+
+	list.Trim(start, stop)
+	s.deleteIfEmptyList(key, list)
+
+	return nil
+}
+
+func (s *Store) LPos(key, value string, rank, count, maxLen int) ([]int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list, err := s.getList(key)
+
+	if err != nil || list == nil {
+		return []int{}, err
+	}
+
+	return list.Positions(value, rank, count, maxLen), nil
+}
+
+// atomically pops an element from source and pushes it on destination.
+// the bool is false when source does not exist.
+func (s *Store) LMove(source, destination string, fromLeft, toLeft bool) (string, bool, error) {
+	s.lock()
+	defer s.unlock()
+
+	value, ok, err := s.move(source, destination, fromLeft, toLeft)
+
+	s.handleReadyKeys()
+
+	return value, ok, err
+}
+
+// LMOVE without locking, callers must hold the write lock
+func (s *Store) move(source, destination string, fromLeft, toLeft bool) (string, bool, error) {
+	src, err := s.getList(source)
+
+	if err != nil || src == nil {
+		return "", false, err
+	}
+
+	dst, err := s.getList(destination)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	var value string
+
+	if fromLeft {
+		value, _ = src.PopLeft()
+	} else {
+		value, _ = src.PopRight()
+	}
+
+	if dst == nil {
+		dst = datatypes.NewList()
+		s.setKey(destination, dst)
+	}
+
+	if toLeft {
+		dst.PushLeft(value)
+	} else {
+		dst.PushRight(value)
+	}
+
+	s.deleteIfEmptyList(source, src)
+	s.signalKeyAsReady(destination)
+
+	return value, true, nil
+}
+
+// redis never keeps empty lists around
+func (s *Store) deleteIfEmptyList(key string, list *datatypes.List) {
+	if list.Len() == 0 {
+		s.deleteKey(key)
+	}
+}
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// a value along with what redis keeps next to it in the object header
+type entry struct {
+	value   Data
+	size    int           // estimated memory usage of the key, see estimateSize
+	version uint64        // changes on every write to the key, see unlock
+	lru     atomic.Int64  // unix time of the last access, in milliseconds
+	lfu     atomic.Uint32 // lfu counter in the low 8 bits, minutes of its last decrement above
+}
+
+// the last version given to an entry. shared by every database so an entry
+// moved to another one by SWAPDB can not take the version of another entry.
+var lastVersion atomic.Uint64
+
+// how many elements of a value are looked at to estimate its size
+const sizeSamples = 5
+
+// what a key costs on top of its value: the map slot, the key string and the entry
+const keyOverhead = 16 + 16 + 48
+
+// the lfu counter of new keys, so they are not evicted before they get a chance
+// to be accessed again, like LFU_INIT_VAL in redis
+const lfuInitVal = 5
+
+// lfu-log-factor and lfu-decay-time, shared by every database
+var (
+	lfuLogFactor atomic.Int64
+	lfuDecayTime atomic.Int64
+)
+
+func init() {
+	lfuLogFactor.Store(10)
+	lfuDecayTime.Store(1)
+}
+
+// sets how fast the lfu counters grow with accesses (higher is slower) and every
+// how many minutes an idle key has its counter decremented
+func SetLFUParams(logFactor, decayTime int) {
+	lfuLogFactor.Store(int64(logFactor))
+	lfuDecayTime.Store(int64(decayTime))
+}
+
+func newEntry(value Data) *entry {
+	e := &entry{value: value}
+	e.lru.Store(lruClock())
+	e.lfu.Store(lfuMinutes()<<8 | lfuInitVal)
+
+	return e
+}
+
+// updates the access clocks. it runs with the read lock only, so every update is atomic.
+func (e *entry) touch() {
+	e.lru.Store(lruClock())
+
+	for {
+		old := e.lfu.Load()
+		counter := lfuLogIncr(lfuDecr(old))
+
+		if e.lfu.CompareAndSwap(old, lfuMinutes()<<8|uint32(counter)) {
+			return
+		}
+	}
+}
+
+// sets the access clocks as if the last access was idle ago and the lfu counter
+// was freq, leaving the ones given as negative alone
+func (e *entry) setClocks(idle time.Duration, freq int) {
+	if idle >= 0 {
+		e.lru.Store(lruClock() - idle.Milliseconds())
+	}
+
+	if freq >= 0 {
+		e.lfu.Store(lfuMinutes()<<8 | uint32(freq))
+	}
+}
+
+// time since the last access
+func (e *entry) idleTime() time.Duration {
+	idle := lruClock() - e.lru.Load()
+
+	return time.Duration(idle) * time.Millisecond
+}
+
+// the lfu counter, decremented for the time the key was idle
+func (e *entry) frequency() int {
+	return lfuDecr(e.lfu.Load())
+}
+
+func lruClock() int64 {
+	return time.Now().UnixMilli()
+}
+
+func lfuMinutes() uint32 {
+	return uint32(time.Now().Unix()/60) & math.MaxUint16
+}
+
+// the counter in lfu, decremented once per lfu-decay-time minutes since the last decrement
+func lfuDecr(lfu uint32) int {
+	counter := int(lfu & 0xff)
+	decayTime := lfuDecayTime.Load()
+
+	if decayTime <= 0 {
+		return counter
+	}
+
+	// minutes wrap around every 45 days
+	elapsed := (lfuMinutes() - lfu>>8) & math.MaxUint16
+	periods := int(int64(elapsed) / decayTime)
+
+	if periods > counter {
+		return 0
+	}
+
+	return counter - periods
+}
+
+// increments counter with a probability that shrinks as it grows, so the 8 bits
+// can count up to millions of accesses
+func lfuLogIncr(counter int) int {
+	if counter == 255 {
+		return counter
+	}
+
+	base := counter - lfuInitVal
+
+	if base < 0 {
+		base = 0
+	}
+
+	if rand.Float64() < 1/float64(int64(base)*lfuLogFactor.Load()+1) {
+		counter++
+	}
+
+	return counter
+}
+
+// estimates the memory used by key and its value, looking at samples elements
+// of the value. the same estimate feeds used memory, eviction and MEMORY USAGE.
+func estimateSize(key string, value Data, volatile bool, samples int) int {
+	size := keyOverhead + len(key)
+
+	if volatile {
+		size += keyOverhead
+	}
+
+	switch v := value.(type) {
+	case *datatypes.String:
+		size += v.MemoryUsage(samples)
+	case *datatypes.List:
+		size += v.MemoryUsage(samples)
+	case *datatypes.Hash:
+		size += v.MemoryUsage(samples)
+	case *datatypes.Set:
+		size += v.MemoryUsage(samples)
+	case *datatypes.SortedSet:
+		size += v.MemoryUsage(samples)
+	case *datatypes.Stream:
+		size += v.MemoryUsage(samples)
+	}
+
+	return size
+}
+
+// takes the write lock. keys looked up while holding it may be modified, so
+// unlock estimates their size again.
+func (s *Store) lock() {
+	s.mutex.Lock()
+	s.writing = true
+}
+
+// updates the size of the keys accessed since lock and releases the write lock
+func (s *Store) unlock() {
+	for _, key := range s.dirty {
+		if e, ok := s.data[key]; ok {
+			_, volatile := s.expires[key]
+			size := estimateSize(key, e.value, volatile, sizeSamples)
+
+			s.used.Add(int64(size - e.size))
+			e.size = size
+			e.version = lastVersion.Add(1)
+		}
+	}
+
+	s.dirty = s.dirty[:0]
+	s.writing = false
+	s.mutex.Unlock()
+}
+
+// returns the estimated memory used by the keys of the database
+func (s *Store) UsedMemory() int64 {
+	return s.used.Load()
+}
+
+// estimates the memory used by key, looking at samples elements of the value,
+// or all of them when samples is 0. ok is false when key does not exist.
+func (s *Store) MemoryUsage(key string, samples int) (int, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, ok := s.peek(key)
+
+	if !ok {
+		return 0, false
+	}
+
+	_, volatile := s.expires[key]
+
+	return estimateSize(key, value, volatile, samples), true
+}
+
+// returns the part of the used memory spent on the key tables rather than on
+// the values, for the keys and for their expiries
+func (s *Store) MemoryOverhead() (keys, expires int64) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return int64(len(s.data) * keyOverhead), int64(len(s.expires) * keyOverhead)
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// the integers redis shares between keys instead of allocating them, like OBJ_SHARED_INTEGERS
+const sharedIntegers = 10000
+
+// what OBJECT reports about a key
+type ObjectInfo struct {
+	Encoding  string
+	IdleTime  time.Duration // since the last access
+	Frequency int           // the lfu counter
+	Shared    bool          // whether redis would share the value with other keys
+}
+
+// returns the encoding and the access clocks of key, without counting as an
+// access. ok is false when key does not exist.
+func (s *Store) Object(key string) (ObjectInfo, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, ok := s.peek(key)
+
+	if !ok {
+		return ObjectInfo{}, false
+	}
+
+	e := s.data[key]
+	info := ObjectInfo{
+		Encoding:  value.Encoding(),
+		IdleTime:  e.idleTime(),
+		Frequency: e.frequency(),
+	}
+
+	if str, ok := value.(*datatypes.String); ok && info.Encoding == "int" {
+		n, _ := strconv.ParseInt(string(str.Value), 10, 64)
+		info.Shared = n >= 0 && n < sharedIntegers
+	}
+
+	return info, true
+}
@@ -0,0 +1,395 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// set algebra operations supported by SetOperation and SetOperationStore
+const (
+	SetInter = "INTER"
+	SetUnion = "UNION"
+	SetDiff  = "DIFF"
+)
+
+// returns the set stored at key, or nil when the key does not exist
+func (s *Store) getSet(key string) (*datatypes.Set, error) {
+	e, ok := s.lookup(key)
+
+	if !ok {
+		return nil, nil
+	}
+
+	set, ok := e.(*datatypes.Set)
+
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return set, nil
+}
+
+// returns the sets stored at keys, missing keys are returned as nil sets
+func (s *Store) getSets(keys []string) ([]*datatypes.Set, error) {
+	sets := make([]*datatypes.Set, len(keys))
+
+	for i, key := range keys {
+		set, err := s.getSet(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sets[i] = set
+	}
+
+	return sets, nil
+}
+
+// returns the number of members that were added
+func (s *Store) SAdd(key string, members []string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	set, err := s.getSet(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if set == nil {
+		set = datatypes.NewSet()
+		s.setKey(key, set)
+	}
+
+	added := 0
+
+	for _, member := range members {
+		if set.Add(member) {
+			added++
+		}
+	}
+
+	return added, nil
+}
+
+// returns the number of members that were removed
+func (s *Store) SRem(key string, members []string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	set, err := s.getSet(key)
+
+	if err != nil || set == nil {
+		return 0, err
+	}
+
+	removed := 0
+
+	for _, member := range members {
+		if set.Remove(member) {
+			removed++
+		}
+	}
+
+	s.deleteIfEmptySet(key, set)
+
+	return removed, nil
+}
+
+// reports for each member whether it belongs to the set
+func (s *Store) SMIsMember(key string, members []string) ([]bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	set, err := s.getSet(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]bool, len(members))
+
+	if set == nil {
+		return found, nil
+	}
+
+	for i, member := range members {
+		found[i] = set.Contains(member)
+	}
+
+	return found, nil
+}
+
+func (s *Store) SMembers(key string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	set, err := s.getSet(key)
+
+	if err != nil || set == nil {
+		return []string{}, err
+	}
+
+	return set.Members(), nil
+}
+
+func (s *Store) SCard(key string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	set, err := s.getSet(key)
+
+	if err != nil || set == nil {
+		return 0, err
+	}
+
+	return set.Len(), nil
+}
+
+// removes and returns up to count random members, nil when the key does not exist
+func (s *Store) SPop(key string, count int) ([]string, error) {
+	s.lock()
+	defer s.unlock()
+
+	set, err := s.getSet(key)
+
+	if err != nil || set == nil {
+		return nil, err
+	}
+
+	members := set.RandomMembers(count)
+
+	for _, member := range members {
+		set.Remove(member)
+	}
+
+	s.deleteIfEmptySet(key, set)
+
+	return members, nil
+}
+
+// returns random members following the SRANDMEMBER rules: a positive count returns
+// distinct members, a negative count may return the same member multiple times.
+func (s *Store) SRandMember(key string, count int) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	set, err := s.getSet(key)
+
+	if err != nil || set == nil {
+		return []string{}, err
+	}
+
+	if count < 0 {
+		return set.RandomMembersWithRepetition(-count), nil
+	}
+
+	return set.RandomMembers(count), nil
+}
+
+// moves member from source to destination, returns false when it is not in source
+func (s *Store) SMove(source, destination, member string) (bool, error) {
+	s.lock()
+	defer s.unlock()
+
+	src, err := s.getSet(source)
+
+	if err != nil {
+		return false, err
+	}
+
+	dst, err := s.getSet(destination)
+
+	if err != nil {
+		return false, err
+	}
+
+	if src == nil || !src.Contains(member) {
+		return false, nil
+	}
+
+	if source == destination {
+		return true, nil
+	}
+
+	src.Remove(member)
+	s.deleteIfEmptySet(source, src)
+
+	if dst == nil {
+		dst = datatypes.NewSet()
+		s.setKey(destination, dst)
+	}
+
+	dst.Add(member)
+
+	return true, nil
+}
+
+// returns the members resulting from applying op (SetInter, SetUnion or SetDiff) on the sets at keys
+func (s *Store) SetOperation(op string, keys []string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	sets, err := s.getSets(keys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return setOperation(op, sets), nil
+}
+
+// stores the result of SetOperation in destination and returns its cardinality
+func (s *Store) SetOperationStore(op, destination string, keys []string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	sets, err := s.getSets(keys)
+
+	if err != nil {
+		return 0, err
+	}
+
+	members := setOperation(op, sets)
+
+	if len(members) == 0 {
+		s.deleteKey(destination)
+		return 0, nil
+	}
+
+	set := datatypes.NewSet()
+
+	for _, member := range members {
+		set.Add(member)
+	}
+
+	s.setKey(destination, set)
+
+	return set.Len(), nil
+}
+
+// returns the cardinality of the intersection, stopping at limit when it is not 0
+func (s *Store) SInterCard(keys []string, limit int) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	sets, err := s.getSets(keys)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(intersect(sets, limit)), nil
+}
+
+// returns the next cursor and the matching members
+func (s *Store) SScan(key string, cursor uint64, count int, pattern string) (uint64, []string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	set, err := s.getSet(key)
+
+	if err != nil || set == nil {
+		return 0, []string{}, err
+	}
+
+	members, next := set.Scan(cursor, count)
+	matching := []string{}
+
+	for _, member := range members {
+		if pattern == "" || glob.Match(pattern, member) {
+			matching = append(matching, member)
+		}
+	}
+
+	return next, matching, nil
+}
+
+func setOperation(op string, sets []*datatypes.Set) []string {
+	switch op {
+	case SetInter:
+		return intersect(sets, 0)
+	case SetUnion:
+		seen := make(map[string]struct{})
+		members := []string{}
+
+		for _, set := range sets {
+			if set == nil {
+				continue
+			}
+
+			for _, member := range set.Members() {
+				if _, ok := seen[member]; !ok {
+					seen[member] = struct{}{}
+					members = append(members, member)
+				}
+			}
+		}
+
+		return members
+	default:
+		members := []string{}
+
+		if sets[0] == nil {
+			return members
+		}
+
+	outer:
+		for _, member := range sets[0].Members() {
+			for _, other := range sets[1:] {
+				if other != nil && other.Contains(member) {
+					continue outer
+				}
+			}
+
+			members = append(members, member)
+		}
+
+		return members
+	}
+}
+
+// intersects starting from the smallest set so the fewest lookups are done.
+// limit stops the intersection once that many members are found, 0 means no limit.
+func intersect(sets []*datatypes.Set, limit int) []string {
+	members := []string{}
+
+	for _, set := range sets {
+		if set == nil {
+			return members
+		}
+	}
+
+	sorted := append([]*datatypes.Set{}, sets...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Len() < sorted[j].Len()
+	})
+
+outer:
+	for _, member := range sorted[0].Members() {
+		for _, other := range sorted[1:] {
+			if !other.Contains(member) {
+				continue outer
+			}
+		}
+
+		members = append(members, member)
+
+		if limit != 0 && len(members) == limit {
+			break
+		}
+	}
+
+	return members
+}
+
+// redis never keeps empty sets around
+func (s *Store) deleteIfEmptySet(key string, set *datatypes.Set) {
+	if set.Len() == 0 {
+		s.deleteKey(key)
+	}
+}
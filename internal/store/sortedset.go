@@ -0,0 +1,599 @@
+package store
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// how ZRANGE interprets its start and stop arguments
+const (
+	ZRangeByRank = iota
+	ZRangeByScore
+	ZRangeByLex
+)
+
+// how ZUNION and ZINTER combine the scores of a member found in several inputs
+const (
+	AggregateSum = "SUM"
+	AggregateMin = "MIN"
+	AggregateMax = "MAX"
+)
+
+// ZRangeQuery holds the parsed arguments of ZRANGE and ZRANGESTORE.
+// Start and Stop are used by rank, Score and Lex by the other kinds.
+// Offset and Count implement LIMIT, a negative Count meaning no limit.
+type ZRangeQuery struct {
+	By      int
+	Start   int
+	Stop    int
+	Score   datatypes.ScoreRange
+	Lex     datatypes.LexRange
+	Reverse bool
+	Offset  int
+	Count   int
+}
+
+func (q ZRangeQuery) apply(zset *datatypes.SortedSet) []datatypes.ScoredMember {
+	switch q.By {
+	case ZRangeByScore:
+		return zset.RangeByScore(q.Score, q.Reverse, q.Offset, q.Count)
+	case ZRangeByLex:
+		return zset.RangeByLex(q.Lex, q.Reverse, q.Offset, q.Count)
+	default:
+		return zset.RangeByRank(q.Start, q.Stop, q.Reverse)
+	}
+}
+
+// returns the sorted set stored at key, or nil when the key does not exist
+func (s *Store) getSortedSet(key string) (*datatypes.SortedSet, error) {
+	e, ok := s.lookup(key)
+
+	if !ok {
+		return nil, nil
+	}
+
+	zset, ok := e.(*datatypes.SortedSet)
+
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return zset, nil
+}
+
+// adds or updates members and returns how many were added and how many had their score changed
+func (s *Store) ZAdd(key string, members []datatypes.ScoredMember, options datatypes.ZAddOptions) (added, updated int, err error) {
+	s.lock()
+	defer s.unlock()
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if zset == nil {
+		if options.XX {
+			return 0, 0, nil
+		}
+
+		zset = datatypes.NewSortedSet()
+		s.setKey(key, zset)
+	}
+
+	for _, member := range members {
+		_, result, err := zset.Add(member.Member, member.Score, options)
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		switch result {
+		case datatypes.ZAddAdded:
+			added++
+		case datatypes.ZAddUpdated:
+			updated++
+		}
+	}
+
+	s.signalKeyAsReady(key)
+	s.handleReadyKeys()
+
+	return added, updated, nil
+}
+
+// increments the score of member, used by ZINCRBY and ZADD INCR.
+// the bool is false when one of the ZADD options prevented the update.
+func (s *Store) ZIncrBy(key, member string, increment float64, options datatypes.ZAddOptions) (float64, bool, error) {
+	s.lock()
+	defer s.unlock()
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	if zset == nil {
+		if options.XX {
+			return 0, false, nil
+		}
+
+		zset = datatypes.NewSortedSet()
+		s.setKey(key, zset)
+	}
+
+	options.Incr = true
+	score, result, err := zset.Add(member, increment, options)
+
+	s.deleteIfEmptySortedSet(key, zset)
+
+	if err != nil || result == datatypes.ZAddSkipped {
+		return 0, false, err
+	}
+
+	s.signalKeyAsReady(key)
+	s.handleReadyKeys()
+
+	return score, true, nil
+}
+
+func (s *Store) ZRem(key string, members []string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil || zset == nil {
+		return 0, err
+	}
+
+	removed := 0
+
+	for _, member := range members {
+		if zset.Remove(member) {
+			removed++
+		}
+	}
+
+	s.deleteIfEmptySortedSet(key, zset)
+
+	return removed, nil
+}
+
+// returns the score of each member, found[i] is false when members[i] does not exist
+func (s *Store) ZMScore(key string, members []string) (scores []float64, found []bool, err error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	scores = make([]float64, len(members))
+	found = make([]bool, len(members))
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil || zset == nil {
+		return scores, found, err
+	}
+
+	for i, member := range members {
+		scores[i], found[i] = zset.Score(member)
+	}
+
+	return scores, found, nil
+}
+
+func (s *Store) ZCard(key string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil || zset == nil {
+		return 0, err
+	}
+
+	return zset.Len(), nil
+}
+
+func (s *Store) ZCount(key string, r datatypes.ScoreRange) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil || zset == nil {
+		return 0, err
+	}
+
+	return zset.Count(r), nil
+}
+
+// returns the 0 based rank and the score of member, ok is false when it does not exist
+func (s *Store) ZRank(key, member string, reverse bool) (rank int, score float64, ok bool, err error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil || zset == nil {
+		return 0, 0, false, err
+	}
+
+	rank, ok = zset.Rank(member, reverse)
+	score, _ = zset.Score(member)
+
+	return rank, score, ok, nil
+}
+
+func (s *Store) ZRange(key string, query ZRangeQuery) ([]datatypes.ScoredMember, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil || zset == nil {
+		return []datatypes.ScoredMember{}, err
+	}
+
+	return query.apply(zset), nil
+}
+
+// stores the result of ZRange in destination and returns its cardinality
+func (s *Store) ZRangeStore(destination, source string, query ZRangeQuery) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	zset, err := s.getSortedSet(source)
+
+	if err != nil {
+		return 0, err
+	}
+
+	result := datatypes.NewSortedSet()
+
+	if zset != nil {
+		for _, member := range query.apply(zset) {
+			result.Add(member.Member, member.Score, datatypes.ZAddOptions{})
+		}
+	}
+
+	length := result.Len()
+
+	s.storeSortedSet(destination, result)
+	s.handleReadyKeys()
+
+	return length, nil
+}
+
+// ZUNION, ZINTER and ZDIFF of the sorted sets (or sets) stored at keys, ordered by score.
+// weights holds one multiplication factor per key, aggregate is ignored by ZDIFF.
+func (s *Store) ZSetOperation(op string, keys []string, weights []float64, aggregate string) ([]datatypes.ScoredMember, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	inputs, err := s.getZSetInputs(keys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := zsetOperation(op, inputs, weights, aggregate)
+
+	return result.RangeByRank(0, -1, false), nil
+}
+
+// stores the result of ZSetOperation in destination and returns its cardinality
+func (s *Store) ZSetOperationStore(op, destination string, keys []string, weights []float64, aggregate string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	inputs, err := s.getZSetInputs(keys)
+
+	if err != nil {
+		return 0, err
+	}
+
+	result := zsetOperation(op, inputs, weights, aggregate)
+
+	// blocked clients may pop from destination right away, so the length is taken first
+	length := result.Len()
+
+	s.storeSortedSet(destination, result)
+	s.handleReadyKeys()
+
+	return length, nil
+}
+
+// cardinality of the intersection of keys, stopping early once limit (when not 0) is reached
+func (s *Store) ZInterCard(keys []string, limit int) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	inputs, err := s.getZSetInputs(keys)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(zintersect(inputs, limit)), nil
+}
+
+// removes and returns up to count members with the lowest (or highest when max is set) scores
+func (s *Store) ZPop(key string, count int, max bool) ([]datatypes.ScoredMember, error) {
+	s.lock()
+	defer s.unlock()
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil || zset == nil {
+		return []datatypes.ScoredMember{}, err
+	}
+
+	popped := zset.Pop(count, max)
+	s.deleteIfEmptySortedSet(key, zset)
+
+	return popped, nil
+}
+
+// pops up to count members from the first non empty sorted set among keys. the result is
+// the key followed by the popped members and their scores, nil when all sorted sets are empty.
+func (s *Store) ZMPop(keys []string, count int, max bool) ([]string, error) {
+	s.lock()
+	defer s.unlock()
+
+	for _, key := range keys {
+		values, ok, err := s.zpopFrom(key, count, max)
+
+		if err != nil || ok {
+			return values, err
+		}
+	}
+
+	return nil, nil
+}
+
+// blocking version of ZMPop, waits until a member is added to one of keys or ctx is done
+func (s *Store) BZPop(ctx context.Context, keys []string, count int, max bool) ([]string, error) {
+	return s.Block(ctx, keys, func(key string) ([]string, bool, error) {
+		return s.zpopFrom(key, count, max)
+	})
+}
+
+// pops without locking, callers must hold the write lock. the result is the key
+// followed by the popped members and their scores, the bool is false when the key does not exist.
+func (s *Store) zpopFrom(key string, count int, max bool) ([]string, bool, error) {
+	zset, err := s.getSortedSet(key)
+
+	if err != nil || zset == nil {
+		return nil, false, err
+	}
+
+	popped := zset.Pop(count, max)
+	s.deleteIfEmptySortedSet(key, zset)
+
+	values := make([]string, 0, len(popped)*2+1)
+	values = append(values, key)
+
+	for _, member := range popped {
+		values = append(values, member.Member, datatypes.FormatScore(member.Score))
+	}
+
+	return values, true, nil
+}
+
+// returns the next cursor and the matching members with their scores
+func (s *Store) ZScan(key string, cursor uint64, count int, pattern string) (uint64, []datatypes.ScoredMember, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	zset, err := s.getSortedSet(key)
+
+	if err != nil || zset == nil {
+		return 0, []datatypes.ScoredMember{}, err
+	}
+
+	members, next := zset.Scan(cursor, count)
+	matching := []datatypes.ScoredMember{}
+
+	for _, member := range members {
+		if pattern == "" || glob.Match(pattern, member) {
+			score, _ := zset.Score(member)
+			matching = append(matching, datatypes.ScoredMember{Member: member, Score: score})
+		}
+	}
+
+	return next, matching, nil
+}
+
+// replaces whatever is stored at key with zset, an empty zset deletes the key.
+// callers must hold the write lock and handle the ready keys.
+func (s *Store) storeSortedSet(key string, zset *datatypes.SortedSet) {
+	if zset.Len() == 0 {
+		s.deleteKey(key)
+		return
+	}
+
+	s.setKey(key, zset)
+	s.signalKeyAsReady(key)
+}
+
+// an input of ZUNION, ZINTER and ZDIFF. plain sets are accepted as well,
+// every one of their members scoring 1
+type zsetInput interface {
+	Len() int
+	Score(member string) (float64, bool)
+	Members() []string
+}
+
+type setInput struct {
+	*datatypes.Set
+}
+
+func (s setInput) Score(member string) (float64, bool) {
+	return 1, s.Contains(member)
+}
+
+// returns one input per key, nil for the keys that do not exist
+func (s *Store) getZSetInputs(keys []string) ([]zsetInput, error) {
+	inputs := make([]zsetInput, len(keys))
+
+	for i, key := range keys {
+		e, ok := s.lookup(key)
+
+		if !ok {
+			continue
+		}
+
+		switch value := e.(type) {
+		case *datatypes.SortedSet:
+			inputs[i] = value
+		case *datatypes.Set:
+			inputs[i] = setInput{value}
+		default:
+			return nil, ErrWrongType
+		}
+	}
+
+	return inputs, nil
+}
+
+func zsetOperation(op string, inputs []zsetInput, weights []float64, aggregate string) *datatypes.SortedSet {
+	result := datatypes.NewSortedSet()
+
+	switch op {
+	case SetUnion:
+		scores := make(map[string]float64)
+
+		for i, input := range inputs {
+			if input == nil {
+				continue
+			}
+
+			for _, member := range input.Members() {
+				score, _ := input.Score(member)
+				score = weightedScore(score, weights[i])
+
+				if current, ok := scores[member]; ok {
+					score = aggregateScores(current, score, aggregate)
+				}
+
+				scores[member] = score
+			}
+		}
+
+		for member, score := range scores {
+			result.Add(member, score, datatypes.ZAddOptions{})
+		}
+	case SetInter:
+		for _, member := range zintersect(inputs, 0) {
+			var score float64
+
+			for i, input := range inputs {
+				other, _ := input.Score(member)
+				other = weightedScore(other, weights[i])
+
+				if i == 0 {
+					score = other
+				} else {
+					score = aggregateScores(score, other, aggregate)
+				}
+			}
+
+			result.Add(member, score, datatypes.ZAddOptions{})
+		}
+	case SetDiff:
+		if inputs[0] == nil {
+			return result
+		}
+
+	outer:
+		for _, member := range inputs[0].Members() {
+			for _, other := range inputs[1:] {
+				if other == nil {
+					continue
+				}
+
+				if _, ok := other.Score(member); ok {
+					continue outer
+				}
+			}
+
+			score, _ := inputs[0].Score(member)
+			result.Add(member, score, datatypes.ZAddOptions{})
+		}
+	}
+
+	return result
+}
+
+// members found in every input, iterating the smallest one
+func zintersect(inputs []zsetInput, limit int) []string {
+	members := []string{}
+
+	for _, input := range inputs {
+		if input == nil {
+			return members
+		}
+	}
+
+	sorted := append([]zsetInput{}, inputs...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Len() < sorted[j].Len()
+	})
+
+outer:
+	for _, member := range sorted[0].Members() {
+		for _, other := range sorted[1:] {
+			if _, ok := other.Score(member); !ok {
+				continue outer
+			}
+		}
+
+		members = append(members, member)
+
+		if limit != 0 && len(members) == limit {
+			break
+		}
+	}
+
+	return members
+}
+
+// like redis, 0 * inf counts as 0 rather than NaN
+func weightedScore(score, weight float64) float64 {
+	score *= weight
+
+	if math.IsNaN(score) {
+		return 0
+	}
+
+	return score
+}
+
+func aggregateScores(a, b float64, aggregate string) float64 {
+	switch aggregate {
+	case AggregateMin:
+		return math.Min(a, b)
+	case AggregateMax:
+		return math.Max(a, b)
+	default:
+		// inf + -inf counts as 0 as well
+		if sum := a + b; !math.IsNaN(sum) {
+			return sum
+		}
+
+		return 0
+	}
+}
+
+// redis never keeps empty sorted sets around
+func (s *Store) deleteIfEmptySortedSet(key string, zset *datatypes.SortedSet) {
+	if zset.Len() == 0 {
+		s.deleteKey(key)
+	}
+}
@@ -6,45 +6,81 @@ import (
 	"fmt"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
 	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
 )
 
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+var errNoSuchStream = errors.New("ERR no such stream key")
+
 type Data interface {
 	GetType() string
+	Encoding() string // the OBJECT ENCODING of the value
 }
 
+// a logical database, see Databases
 type Store struct {
-	data  map[string]Data
-	mutex *sync.RWMutex
+	index          int // the number selected with SELECT
+	data           map[string]*entry
+	keys           *datatypes.ScanIndex // the keys of data in SCAN order
+	expires        map[string]time.Time // keys with an expiry, and when they expire
+	mutex          *sync.RWMutex
+	blocked        map[string][]*waiter
+	readyKeys      []string
+	volatileHashes map[string]struct{} // hashes with at least one field that expires
+	used           atomic.Int64        // estimated memory used by the keys, see unlock
+	writing        bool                // whether the write lock is held, see lock
+	dirty          []string            // keys looked up since lock
 }
 
-func New() *Store {
+func New(index int) *Store {
 	return &Store{
-		data:  make(map[string]Data),
-		mutex: &sync.RWMutex{},
+		index:          index,
+		data:           make(map[string]*entry),
+		keys:           datatypes.NewScanIndex(),
+		expires:        make(map[string]time.Time),
+		mutex:          &sync.RWMutex{},
+		blocked:        make(map[string][]*waiter),
+		volatileHashes: make(map[string]struct{}),
 	}
 }
 
+// returns the number of the database
+func (s *Store) Index() int {
+	return s.index
+}
+
 func (s *Store) Set(key, value string, expiry time.Time) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	s.lock()
+	defer s.unlock()
 
-	s.data[key] = &datatypes.String{
+	s.setKey(key, &datatypes.String{
 		DataType: "string",
-		Value:    value,
-		Expiry:   expiry,
+		Value:    []byte(value),
+	})
+
+	if !expiry.IsZero() {
+		s.expires[key] = expiry
 	}
 }
 
 func (s *Store) XAdd(streamKey, entryId string, entries []string) (string, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	s.lock()
+	defer s.unlock()
 
-	stream, ok := s.data[streamKey].(*datatypes.Stream)
+	stream, err := s.getStream(streamKey)
 
-	if !ok {
+	if err != nil {
+		return "", err
+	}
+
+	created := stream == nil
+
+	if created {
 		stream = &datatypes.Stream{
 			DataType:    "stream",
 			Values:      make([]datatypes.Entry, 0),
@@ -57,7 +93,10 @@ func (s *Store) XAdd(streamKey, entryId string, entries []string) (string, error
 		return "", err
 	}
 
-	s.data[streamKey] = stream
+	if created {
+		s.setKey(streamKey, stream)
+	}
+
 	return id, nil
 }
 
@@ -66,10 +105,14 @@ func (s *Store) XRange(streamKey, startId, endId string) ([]datatypes.Entry, err
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	stream, ok := s.data[streamKey].(*datatypes.Stream)
+	stream, err := s.getStream(streamKey)
 
-	if !ok {
-		return nil, errors.New("ERR no such stream key")
+	if err != nil {
+		return nil, err
+	}
+
+	if stream == nil {
+		return nil, errNoSuchStream
 	}
 
 	entries, err := stream.GetRange(startId, endId)
@@ -79,10 +122,16 @@ func (s *Store) XRange(streamKey, startId, endId string) ([]datatypes.Entry, err
 
 func (s *Store) XRead(streamKey, entryId string, count, block int, ctx context.Context, ctxCancel context.CancelFunc) ([]datatypes.Entry, error) {
 
-	stream, ok := s.data[streamKey].(*datatypes.Stream)
+	s.mutex.RLock()
+	stream, err := s.getStream(streamKey)
+	s.mutex.RUnlock()
 
-	if !ok {
-		return nil, errors.New("ERR no such stream key")
+	if err != nil {
+		return nil, err
+	}
+
+	if stream == nil {
+		return nil, errNoSuchStream
 	}
 	randomSubscriberKey := randomString()
 	stream.Subscribers[randomSubscriberKey] = make(chan string)
@@ -146,61 +195,127 @@ func (s *Store) XRead(streamKey, entryId string, count, block int, ctx context.C
 
 }
 
-func (s *Store) Get(key string) string {
+// returns the string stored at key, ok is false when the key does not exist
+func (s *Store) Get(key string) (string, bool, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	e, ok := s.data[key]
+	str, err := s.getString(key)
 
-	if !ok {
-		return ""
+	if err != nil || str == nil {
+		return "", false, err
 	}
 
-	entry, ok := e.(*datatypes.String)
+	return string(str.Value), true, nil
+}
+
+func (s *Store) GetDataType(key string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	e, ok := s.lookup(key)
 
 	if !ok {
-		return ""
+		return "none"
+	}
+
+	return e.GetType()
+}
+
+func (s *Store) GetKeysWithPattern(pattern string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := []string{}
+
+	for key := range s.data {
+		if _, ok := s.peek(key); ok && glob.Match(pattern, key) {
+			keys = append(keys, key)
+		}
 	}
 
-	if entry.Expiry.IsZero() {
-		return entry.Value
+	return keys
+}
+
+// returns the stream stored at key, or nil when the key does not exist
+func (s *Store) getStream(key string) (*datatypes.Stream, error) {
+	e, ok := s.lookup(key)
+
+	if !ok {
+		return nil, nil
 	}
 
-	if time.Now().After(entry.Expiry) {
-		delete(s.data, key)
-		return ""
+	stream, ok := e.(*datatypes.Stream)
+
+	if !ok {
+		return nil, ErrWrongType
 	}
 
-	return entry.Value
+	return stream, nil
 }
 
-func (s *Store) GetDataType(key string) string {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+// returns the value stored at key, treating expired keys and hashes whose
+// fields all expired as missing, and records the access. callers must hold at
+// least a read lock.
+func (s *Store) lookup(key string) (Data, bool) {
+	e, ok := s.peek(key)
 
+	if !ok {
+		return nil, false
+	}
+
+	s.data[key].touch()
+
+	if s.writing {
+		s.dirty = append(s.dirty, key)
+	}
+
+	return e, true
+}
+
+// like lookup, but leaves the access clocks alone, for commands that walk the
+// keyspace like KEYS and SCAN. callers must hold at least a read lock.
+func (s *Store) peek(key string) (Data, bool) {
 	e, ok := s.data[key]
 
 	if !ok {
-		return "none"
+		return nil, false
 	}
 
-	return e.GetType()
-}
+	if expiry, ok := s.expires[key]; ok && time.Now().After(expiry) {
+		return nil, false
+	}
 
-func (s *Store) GetKeysWithPattern(pattern string) []string {
+	if hash, ok := e.value.(*datatypes.Hash); ok && hash.VolatileLen() > 0 && hash.Len() == 0 {
+		return nil, false
+	}
+
+	return e.value, true
+}
 
-	// TODO: Implement other patters
-	if pattern != "*" {
-		return []string{}
+// stores value at key and removes its expiry, as creating or overwriting a key
+// does. an overwritten key keeps its access clocks. callers must hold the write lock.
+func (s *Store) setKey(key string, value Data) {
+	if e, ok := s.data[key]; ok {
+		e.value = value
+	} else {
+		s.data[key] = newEntry(value)
+		s.keys.Add(key)
 	}
 
-	keys := []string{}
+	delete(s.expires, key)
+	s.dirty = append(s.dirty, key)
+}
 
-	for key := range s.data {
-		keys = append(keys, key)
+// removes key along with its expiry, callers must hold the write lock
+func (s *Store) deleteKey(key string) {
+	if e, ok := s.data[key]; ok {
+		s.used.Add(int64(-e.size))
+		s.keys.Remove(key)
 	}
 
-	return keys
+	delete(s.data, key)
+	delete(s.expires, key)
 }
 
 func randomString() string {
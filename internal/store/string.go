@@ -0,0 +1,346 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/store/datatypes"
+)
+
+// conditions of SET NX and XX
+const (
+	SetAlways = iota
+	SetIfNotExists
+	SetIfExists
+)
+
+// the largest string APPEND and SETRANGE may produce, the default proto-max-bulk-len of redis
+const maxStringLength = 512 * 1024 * 1024
+
+var errStringTooLong = errors.New("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+
+// SetOptions holds the options of SET. A zero Expiry means the key does not expire,
+// unless KeepTTL is set and the previous key had an expiry.
+type SetOptions struct {
+	Condition int
+	Expiry    time.Time
+	KeepTTL   bool
+	Get       bool
+}
+
+// returns the string stored at key, or nil when the key does not exist
+func (s *Store) getString(key string) (*datatypes.String, error) {
+	e, ok := s.lookup(key)
+
+	if !ok {
+		return nil, nil
+	}
+
+	str, ok := e.(*datatypes.String)
+
+	if !ok {
+		return nil, ErrWrongType
+	}
+
+	return str, nil
+}
+
+// adds increment to the integer stored at key, a missing key counting as 0.
+// the value is updated in place so its expiry is kept.
+func (s *Store) IncrBy(key string, increment int64) (int64, error) {
+	s.lock()
+	defer s.unlock()
+
+	str, err := s.getString(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var current int64
+
+	if str != nil {
+		current, err = strconv.ParseInt(string(str.Value), 10, 64)
+
+		if err != nil {
+			return 0, errors.New("ERR value is not an integer or out of range")
+		}
+	}
+
+	if (increment > 0 && current > math.MaxInt64-increment) || (increment < 0 && current < math.MinInt64-increment) {
+		return 0, errors.New("ERR increment or decrement would overflow")
+	}
+
+	current += increment
+	s.setStringValue(key, str, strconv.FormatInt(current, 10))
+
+	return current, nil
+}
+
+// like IncrBy for floats, returns the new value formatted the way it is stored
+func (s *Store) IncrByFloat(key string, increment float64) (string, error) {
+	s.lock()
+	defer s.unlock()
+
+	str, err := s.getString(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	var current float64
+
+	if str != nil {
+		current, err = strconv.ParseFloat(string(str.Value), 64)
+
+		if err != nil || math.IsNaN(current) {
+			return "", errors.New("ERR value is not a valid float")
+		}
+	}
+
+	current += increment
+
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return "", errors.New("ERR increment would produce NaN or Infinity")
+	}
+
+	value := strconv.FormatFloat(current, 'f', -1, 64)
+	s.setStringValue(key, str, value)
+
+	return value, nil
+}
+
+// replaces the value of str, creating it at key when nil. callers must hold the write lock.
+func (s *Store) setStringValue(key string, str *datatypes.String, value string) {
+	if str == nil {
+		s.setKey(key, &datatypes.String{
+			DataType: "string",
+			Value:    []byte(value),
+		})
+		return
+	}
+
+	str.Value = []byte(value)
+	str.Raw = false
+}
+
+// sets key following options, overwriting a value of any type. previous is the string stored
+// before (existed is false when there was none) and ok is false when NX or XX prevented the write.
+// with Get, a value of another type is left untouched and reported as an error.
+func (s *Store) SetWithOptions(key, value string, options SetOptions) (previous string, existed, ok bool, err error) {
+	s.lock()
+	defer s.unlock()
+
+	e, exists := s.lookup(key)
+	str, isString := e.(*datatypes.String)
+
+	if exists && !isString && options.Get {
+		return "", false, false, ErrWrongType
+	}
+
+	if isString {
+		previous, existed = string(str.Value), true
+	}
+
+	if (options.Condition == SetIfNotExists && exists) || (options.Condition == SetIfExists && !exists) {
+		return previous, existed, false, nil
+	}
+
+	expiry, hasExpiry := s.expires[key]
+
+	s.setKey(key, &datatypes.String{
+		DataType: "string",
+		Value:    []byte(value),
+	})
+
+	if options.KeepTTL && exists && hasExpiry {
+		s.expires[key] = expiry
+	} else if !options.Expiry.IsZero() {
+		s.expires[key] = options.Expiry
+	}
+
+	return previous, existed, true, nil
+}
+
+// returns the string stored at key and updates its expiry: persist removes it,
+// a non zero expiry replaces it and otherwise it is left as is
+func (s *Store) GetEx(key string, expiry time.Time, persist bool) (string, bool, error) {
+	s.lock()
+	defer s.unlock()
+
+	str, err := s.getString(key)
+
+	if err != nil || str == nil {
+		return "", false, err
+	}
+
+	if persist {
+		delete(s.expires, key)
+	} else if !expiry.IsZero() {
+		s.expires[key] = expiry
+	}
+
+	return string(str.Value), true, nil
+}
+
+// returns the string stored at key and deletes the key
+func (s *Store) GetDel(key string) (string, bool, error) {
+	s.lock()
+	defer s.unlock()
+
+	str, err := s.getString(key)
+
+	if err != nil || str == nil {
+		return "", false, err
+	}
+
+	s.deleteKey(key)
+
+	return string(str.Value), true, nil
+}
+
+// returns the value of every key, found[i] is false when keys[i] is missing or not a string
+func (s *Store) MGet(keys []string) (values []string, found []bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	values = make([]string, len(keys))
+	found = make([]bool, len(keys))
+
+	for i, key := range keys {
+		if str, err := s.getString(key); err == nil && str != nil {
+			values[i], found[i] = string(str.Value), true
+		}
+	}
+
+	return values, found
+}
+
+// sets every key, value pair at once. with onlyIfNoneExist nothing is set when
+// any of the keys exists and false is returned.
+func (s *Store) MSet(pairs []string, onlyIfNoneExist bool) bool {
+	s.lock()
+	defer s.unlock()
+
+	if onlyIfNoneExist {
+		for i := 0; i < len(pairs); i += 2 {
+			if _, exists := s.lookup(pairs[i]); exists {
+				return false
+			}
+		}
+	}
+
+	for i := 0; i < len(pairs); i += 2 {
+		s.setKey(pairs[i], &datatypes.String{
+			DataType: "string",
+			Value:    []byte(pairs[i+1]),
+		})
+	}
+
+	return true
+}
+
+// appends value to the string at key, creating it when missing, and returns the new length
+func (s *Store) Append(key, value string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	str, err := s.getString(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if str == nil {
+		s.setStringValue(key, nil, value)
+		return len(value), nil
+	}
+
+	if len(str.Value)+len(value) > maxStringLength {
+		return 0, errStringTooLong
+	}
+
+	return str.Append(value), nil
+}
+
+func (s *Store) StrLen(key string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	str, err := s.getString(key)
+
+	if err != nil || str == nil {
+		return 0, err
+	}
+
+	return len(str.Value), nil
+}
+
+func (s *Store) GetRange(key string, start, end int) (string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	str, err := s.getString(key)
+
+	if err != nil || str == nil {
+		return "", err
+	}
+
+	return str.Range(start, end), nil
+}
+
+// overwrites the string at key from offset on and returns its new length.
+// an empty value never creates the key.
+func (s *Store) SetRange(key string, offset int, value string) (int, error) {
+	s.lock()
+	defer s.unlock()
+
+	str, err := s.getString(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(value) == 0 {
+		if str == nil {
+			return 0, nil
+		}
+
+		return len(str.Value), nil
+	}
+
+	if offset+len(value) > maxStringLength {
+		return 0, errStringTooLong
+	}
+
+	if str == nil {
+		str = &datatypes.String{DataType: "string"}
+		s.setKey(key, str)
+	}
+
+	return str.SetRange(offset, value), nil
+}
+
+// longest common subsequence of the strings stored at key1 and key2, missing keys being empty
+func (s *Store) LCS(key1, key2 string) (string, []datatypes.LCSMatch, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var values [2]string
+
+	for i, key := range []string{key1, key2} {
+		str, err := s.getString(key)
+
+		if err != nil {
+			return "", nil, errors.New("ERR The specified keys must contain string values")
+		}
+
+		if str != nil {
+			values[i] = string(str.Value)
+		}
+	}
+
+	return datatypes.LCS(values[0], values[1])
+}